@@ -22,6 +22,254 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type ImageFormat int32
+
+const (
+	ImageFormat_IMAGE_FORMAT_UNSPECIFIED ImageFormat = 0 // JPEG для JPEG, иначе PNG
+	ImageFormat_IMAGE_FORMAT_JPEG        ImageFormat = 1
+	ImageFormat_IMAGE_FORMAT_PNG         ImageFormat = 2
+	ImageFormat_IMAGE_FORMAT_GIF         ImageFormat = 3
+)
+
+// Enum value maps for ImageFormat.
+var (
+	ImageFormat_name = map[int32]string{
+		0: "IMAGE_FORMAT_UNSPECIFIED",
+		1: "IMAGE_FORMAT_JPEG",
+		2: "IMAGE_FORMAT_PNG",
+		3: "IMAGE_FORMAT_GIF",
+	}
+	ImageFormat_value = map[string]int32{
+		"IMAGE_FORMAT_UNSPECIFIED": 0,
+		"IMAGE_FORMAT_JPEG":        1,
+		"IMAGE_FORMAT_PNG":         2,
+		"IMAGE_FORMAT_GIF":         3,
+	}
+)
+
+func (x ImageFormat) Enum() *ImageFormat {
+	p := new(ImageFormat)
+	*p = x
+	return p
+}
+
+func (x ImageFormat) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ImageFormat) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_file_service_proto_enumTypes[0].Descriptor()
+}
+
+func (ImageFormat) Type() protoreflect.EnumType {
+	return &file_api_proto_file_service_proto_enumTypes[0]
+}
+
+func (x ImageFormat) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ImageFormat.Descriptor instead.
+func (ImageFormat) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{0}
+}
+
+type SortField int32
+
+const (
+	SortField_SORT_FIELD_CREATED_AT SortField = 0
+	SortField_SORT_FIELD_NAME       SortField = 1
+	SortField_SORT_FIELD_SIZE       SortField = 2
+	SortField_SORT_FIELD_UPDATED_AT SortField = 3
+)
+
+// Enum value maps for SortField.
+var (
+	SortField_name = map[int32]string{
+		0: "SORT_FIELD_CREATED_AT",
+		1: "SORT_FIELD_NAME",
+		2: "SORT_FIELD_SIZE",
+		3: "SORT_FIELD_UPDATED_AT",
+	}
+	SortField_value = map[string]int32{
+		"SORT_FIELD_CREATED_AT": 0,
+		"SORT_FIELD_NAME":       1,
+		"SORT_FIELD_SIZE":       2,
+		"SORT_FIELD_UPDATED_AT": 3,
+	}
+)
+
+func (x SortField) Enum() *SortField {
+	p := new(SortField)
+	*p = x
+	return p
+}
+
+func (x SortField) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SortField) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_file_service_proto_enumTypes[1].Descriptor()
+}
+
+func (SortField) Type() protoreflect.EnumType {
+	return &file_api_proto_file_service_proto_enumTypes[1]
+}
+
+func (x SortField) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SortField.Descriptor instead.
+func (SortField) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{1}
+}
+
+type SortDirection int32
+
+const (
+	SortDirection_SORT_DIRECTION_DESC SortDirection = 0
+	SortDirection_SORT_DIRECTION_ASC  SortDirection = 1
+)
+
+// Enum value maps for SortDirection.
+var (
+	SortDirection_name = map[int32]string{
+		0: "SORT_DIRECTION_DESC",
+		1: "SORT_DIRECTION_ASC",
+	}
+	SortDirection_value = map[string]int32{
+		"SORT_DIRECTION_DESC": 0,
+		"SORT_DIRECTION_ASC":  1,
+	}
+)
+
+func (x SortDirection) Enum() *SortDirection {
+	p := new(SortDirection)
+	*p = x
+	return p
+}
+
+func (x SortDirection) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SortDirection) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_file_service_proto_enumTypes[2].Descriptor()
+}
+
+func (SortDirection) Type() protoreflect.EnumType {
+	return &file_api_proto_file_service_proto_enumTypes[2]
+}
+
+func (x SortDirection) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SortDirection.Descriptor instead.
+func (SortDirection) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{2}
+}
+
+type StripMetadata int32
+
+const (
+	StripMetadata_STRIP_METADATA_DEFAULT  StripMetadata = 0 // Политика сервера
+	StripMetadata_STRIP_METADATA_LOCATION StripMetadata = 1 // Координаты GPS из EXIF и XMP
+	StripMetadata_STRIP_METADATA_ALL      StripMetadata = 2 // Все, кроме ориентации и цветового профиля
+)
+
+// Enum value maps for StripMetadata.
+var (
+	StripMetadata_name = map[int32]string{
+		0: "STRIP_METADATA_DEFAULT",
+		1: "STRIP_METADATA_LOCATION",
+		2: "STRIP_METADATA_ALL",
+	}
+	StripMetadata_value = map[string]int32{
+		"STRIP_METADATA_DEFAULT":  0,
+		"STRIP_METADATA_LOCATION": 1,
+		"STRIP_METADATA_ALL":      2,
+	}
+)
+
+func (x StripMetadata) Enum() *StripMetadata {
+	p := new(StripMetadata)
+	*p = x
+	return p
+}
+
+func (x StripMetadata) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (StripMetadata) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_file_service_proto_enumTypes[3].Descriptor()
+}
+
+func (StripMetadata) Type() protoreflect.EnumType {
+	return &file_api_proto_file_service_proto_enumTypes[3]
+}
+
+func (x StripMetadata) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use StripMetadata.Descriptor instead.
+func (StripMetadata) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{3}
+}
+
+type ConflictPolicy int32
+
+const (
+	ConflictPolicy_CONFLICT_POLICY_OVERWRITE      ConflictPolicy = 0
+	ConflictPolicy_CONFLICT_POLICY_FAIL_IF_EXISTS ConflictPolicy = 1 // ALREADY_EXISTS
+	ConflictPolicy_CONFLICT_POLICY_RENAME         ConflictPolicy = 2 // Сохранить как "photo (1).jpg"
+)
+
+// Enum value maps for ConflictPolicy.
+var (
+	ConflictPolicy_name = map[int32]string{
+		0: "CONFLICT_POLICY_OVERWRITE",
+		1: "CONFLICT_POLICY_FAIL_IF_EXISTS",
+		2: "CONFLICT_POLICY_RENAME",
+	}
+	ConflictPolicy_value = map[string]int32{
+		"CONFLICT_POLICY_OVERWRITE":      0,
+		"CONFLICT_POLICY_FAIL_IF_EXISTS": 1,
+		"CONFLICT_POLICY_RENAME":         2,
+	}
+)
+
+func (x ConflictPolicy) Enum() *ConflictPolicy {
+	p := new(ConflictPolicy)
+	*p = x
+	return p
+}
+
+func (x ConflictPolicy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ConflictPolicy) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_file_service_proto_enumTypes[4].Descriptor()
+}
+
+func (ConflictPolicy) Type() protoreflect.EnumType {
+	return &file_api_proto_file_service_proto_enumTypes[4]
+}
+
+func (x ConflictPolicy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ConflictPolicy.Descriptor instead.
+func (ConflictPolicy) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{4}
+}
+
 type UploadFileRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Types that are valid to be assigned to Data:
@@ -105,12 +353,17 @@ func (*UploadFileRequest_Metadata) isUploadFileRequest_Data() {}
 func (*UploadFileRequest_Chunk) isUploadFileRequest_Data() {}
 
 type UploadFileResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
-	Size          uint64                 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
-	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Filename  string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"` // Итоговое имя, может отличаться при CONFLICT_POLICY_RENAME
+	Size      uint64                 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	CreatedAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Sha256    string                 `protobuf:"bytes,4,opt,name=sha256,proto3" json:"sha256,omitempty"` // hex
+	Etag      string                 `protobuf:"bytes,5,opt,name=etag,proto3" json:"etag,omitempty"`
+	// Что удалено из файла при загрузке: exif_gps, exif, xmp, iptc, comment,
+	// app_segment, mpf, text, time, trailing_data
+	StrippedMetadata []string `protobuf:"bytes,6,rep,name=stripped_metadata,json=strippedMetadata,proto3" json:"stripped_metadata,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
 func (x *UploadFileResponse) Reset() {
@@ -164,11 +417,40 @@ func (x *UploadFileResponse) GetCreatedAt() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *UploadFileResponse) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *UploadFileResponse) GetEtag() string {
+	if x != nil {
+		return x.Etag
+	}
+	return ""
+}
+
+func (x *UploadFileResponse) GetStrippedMetadata() []string {
+	if x != nil {
+		return x.StrippedMetadata
+	}
+	return nil
+}
+
 type DownloadFileRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Filename string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Offset   uint64                 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`   // Смещение от начала файла
+	Length   uint64                 `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"`   // Количество байт, 0 - до конца файла
+	Version  uint64                 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"` // Номер версии, 0 - текущее содержимое
+	// Условная загрузка: если у клиента уже есть актуальная копия, сервер
+	// отправляет только метаданные с not_modified = true. При заданном
+	// if_none_match поле if_modified_since не учитывается
+	IfNoneMatch     string                 `protobuf:"bytes,5,opt,name=if_none_match,json=ifNoneMatch,proto3" json:"if_none_match,omitempty"` // ETag копии клиента или "*"
+	IfModifiedSince *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=if_modified_since,json=ifModifiedSince,proto3" json:"if_modified_since,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *DownloadFileRequest) Reset() {
@@ -208,6 +490,41 @@ func (x *DownloadFileRequest) GetFilename() string {
 	return ""
 }
 
+func (x *DownloadFileRequest) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *DownloadFileRequest) GetLength() uint64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *DownloadFileRequest) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *DownloadFileRequest) GetIfNoneMatch() string {
+	if x != nil {
+		return x.IfNoneMatch
+	}
+	return ""
+}
+
+func (x *DownloadFileRequest) GetIfModifiedSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.IfModifiedSince
+	}
+	return nil
+}
+
 type DownloadFileResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Types that are valid to be assigned to Content:
@@ -290,26 +607,29 @@ func (*DownloadFileResponse_Metadata) isDownloadFileResponse_Content() {}
 
 func (*DownloadFileResponse_Chunk) isDownloadFileResponse_Content() {}
 
-type ListFilesRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
+type GetThumbnailRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Filename string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	// Ограничение большей стороны в пикселях, одно из настроенных на сервере
+	Size          uint32 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ListFilesRequest) Reset() {
-	*x = ListFilesRequest{}
+func (x *GetThumbnailRequest) Reset() {
+	*x = GetThumbnailRequest{}
 	mi := &file_api_proto_file_service_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListFilesRequest) String() string {
+func (x *GetThumbnailRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListFilesRequest) ProtoMessage() {}
+func (*GetThumbnailRequest) ProtoMessage() {}
 
-func (x *ListFilesRequest) ProtoReflect() protoreflect.Message {
+func (x *GetThumbnailRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_file_service_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -321,32 +641,50 @@ func (x *ListFilesRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListFilesRequest.ProtoReflect.Descriptor instead.
-func (*ListFilesRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use GetThumbnailRequest.ProtoReflect.Descriptor instead.
+func (*GetThumbnailRequest) Descriptor() ([]byte, []int) {
 	return file_api_proto_file_service_proto_rawDescGZIP(), []int{4}
 }
 
-type ListFilesResponse struct {
+func (x *GetThumbnailRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *GetThumbnailRequest) GetSize() uint32 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type ThumbnailMetadata struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Files         []*FileInfo            `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Size          uint32                 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	ContentType   string                 `protobuf:"bytes,3,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Length        uint64                 `protobuf:"varint,4,opt,name=length,proto3" json:"length,omitempty"` // Размер превью в байтах
+	Etag          string                 `protobuf:"bytes,5,opt,name=etag,proto3" json:"etag,omitempty"`      // ETag исходного файла
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ListFilesResponse) Reset() {
-	*x = ListFilesResponse{}
+func (x *ThumbnailMetadata) Reset() {
+	*x = ThumbnailMetadata{}
 	mi := &file_api_proto_file_service_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListFilesResponse) String() string {
+func (x *ThumbnailMetadata) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListFilesResponse) ProtoMessage() {}
+func (*ThumbnailMetadata) ProtoMessage() {}
 
-func (x *ListFilesResponse) ProtoReflect() protoreflect.Message {
+func (x *ThumbnailMetadata) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_file_service_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -358,41 +696,71 @@ func (x *ListFilesResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListFilesResponse.ProtoReflect.Descriptor instead.
-func (*ListFilesResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use ThumbnailMetadata.ProtoReflect.Descriptor instead.
+func (*ThumbnailMetadata) Descriptor() ([]byte, []int) {
 	return file_api_proto_file_service_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *ListFilesResponse) GetFiles() []*FileInfo {
+func (x *ThumbnailMetadata) GetFilename() string {
 	if x != nil {
-		return x.Files
+		return x.Filename
 	}
-	return nil
+	return ""
 }
 
-type FileInfo struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
-	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
-	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+func (x *ThumbnailMetadata) GetSize() uint32 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *ThumbnailMetadata) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *ThumbnailMetadata) GetLength() uint64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *ThumbnailMetadata) GetEtag() string {
+	if x != nil {
+		return x.Etag
+	}
+	return ""
+}
+
+type GetThumbnailResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Content:
+	//
+	//	*GetThumbnailResponse_Metadata
+	//	*GetThumbnailResponse_Chunk
+	Content       isGetThumbnailResponse_Content `protobuf_oneof:"content"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FileInfo) Reset() {
-	*x = FileInfo{}
+func (x *GetThumbnailResponse) Reset() {
+	*x = GetThumbnailResponse{}
 	mi := &file_api_proto_file_service_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FileInfo) String() string {
+func (x *GetThumbnailResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FileInfo) ProtoMessage() {}
+func (*GetThumbnailResponse) ProtoMessage() {}
 
-func (x *FileInfo) ProtoReflect() protoreflect.Message {
+func (x *GetThumbnailResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_file_service_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -404,56 +772,2287 @@ func (x *FileInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FileInfo.ProtoReflect.Descriptor instead.
-func (*FileInfo) Descriptor() ([]byte, []int) {
+// Deprecated: Use GetThumbnailResponse.ProtoReflect.Descriptor instead.
+func (*GetThumbnailResponse) Descriptor() ([]byte, []int) {
 	return file_api_proto_file_service_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *FileInfo) GetFilename() string {
+func (x *GetThumbnailResponse) GetContent() isGetThumbnailResponse_Content {
 	if x != nil {
-		return x.Filename
+		return x.Content
 	}
-	return ""
+	return nil
 }
 
-func (x *FileInfo) GetCreatedAt() *timestamppb.Timestamp {
+func (x *GetThumbnailResponse) GetMetadata() *ThumbnailMetadata {
 	if x != nil {
-		return x.CreatedAt
+		if x, ok := x.Content.(*GetThumbnailResponse_Metadata); ok {
+			return x.Metadata
+		}
 	}
 	return nil
 }
 
-func (x *FileInfo) GetUpdatedAt() *timestamppb.Timestamp {
+func (x *GetThumbnailResponse) GetChunk() []byte {
 	if x != nil {
-		return x.UpdatedAt
+		if x, ok := x.Content.(*GetThumbnailResponse_Chunk); ok {
+			return x.Chunk
+		}
 	}
 	return nil
 }
 
-type FileMetadata struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
-	Size          uint64                 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
-	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type isGetThumbnailResponse_Content interface {
+	isGetThumbnailResponse_Content()
 }
 
-func (x *FileMetadata) Reset() {
-	*x = FileMetadata{}
-	mi := &file_api_proto_file_service_proto_msgTypes[7]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+type GetThumbnailResponse_Metadata struct {
+	Metadata *ThumbnailMetadata `protobuf:"bytes,1,opt,name=metadata,proto3,oneof"`
 }
 
-func (x *FileMetadata) String() string {
+type GetThumbnailResponse_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"`
+}
+
+func (*GetThumbnailResponse_Metadata) isGetThumbnailResponse_Content() {}
+
+func (*GetThumbnailResponse_Chunk) isGetThumbnailResponse_Content() {}
+
+type TransformImageRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Filename string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	// Ограничение ширины и высоты в пикселях, 0 - по другой стороне с
+	// сохранением пропорций. Изображение только уменьшается
+	Width  uint32 `protobuf:"varint,2,opt,name=width,proto3" json:"width,omitempty"`
+	Height uint32 `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+	// Обрезка по центру до соотношения сторон aspect_width:aspect_height
+	// перед уменьшением, 0 - без обрезки
+	AspectWidth   uint32      `protobuf:"varint,4,opt,name=aspect_width,json=aspectWidth,proto3" json:"aspect_width,omitempty"`
+	AspectHeight  uint32      `protobuf:"varint,5,opt,name=aspect_height,json=aspectHeight,proto3" json:"aspect_height,omitempty"`
+	Format        ImageFormat `protobuf:"varint,6,opt,name=format,proto3,enum=file_service.ImageFormat" json:"format,omitempty"`
+	Quality       uint32      `protobuf:"varint,7,opt,name=quality,proto3" json:"quality,omitempty"` // Качество JPEG 1-100, 0 - по умолчанию
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransformImageRequest) Reset() {
+	*x = TransformImageRequest{}
+	mi := &file_api_proto_file_service_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransformImageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransformImageRequest) ProtoMessage() {}
+
+func (x *TransformImageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransformImageRequest.ProtoReflect.Descriptor instead.
+func (*TransformImageRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *TransformImageRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *TransformImageRequest) GetWidth() uint32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *TransformImageRequest) GetHeight() uint32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *TransformImageRequest) GetAspectWidth() uint32 {
+	if x != nil {
+		return x.AspectWidth
+	}
+	return 0
+}
+
+func (x *TransformImageRequest) GetAspectHeight() uint32 {
+	if x != nil {
+		return x.AspectHeight
+	}
+	return 0
+}
+
+func (x *TransformImageRequest) GetFormat() ImageFormat {
+	if x != nil {
+		return x.Format
+	}
+	return ImageFormat_IMAGE_FORMAT_UNSPECIFIED
+}
+
+func (x *TransformImageRequest) GetQuality() uint32 {
+	if x != nil {
+		return x.Quality
+	}
+	return 0
+}
+
+type RenditionMetadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Width         uint32                 `protobuf:"varint,2,opt,name=width,proto3" json:"width,omitempty"`
+	Height        uint32                 `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+	ContentType   string                 `protobuf:"bytes,4,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Length        uint64                 `protobuf:"varint,5,opt,name=length,proto3" json:"length,omitempty"` // Размер результата в байтах
+	Etag          string                 `protobuf:"bytes,6,opt,name=etag,proto3" json:"etag,omitempty"`      // ETag исходного файла
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenditionMetadata) Reset() {
+	*x = RenditionMetadata{}
+	mi := &file_api_proto_file_service_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenditionMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenditionMetadata) ProtoMessage() {}
+
+func (x *RenditionMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenditionMetadata.ProtoReflect.Descriptor instead.
+func (*RenditionMetadata) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *RenditionMetadata) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *RenditionMetadata) GetWidth() uint32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *RenditionMetadata) GetHeight() uint32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *RenditionMetadata) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *RenditionMetadata) GetLength() uint64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *RenditionMetadata) GetEtag() string {
+	if x != nil {
+		return x.Etag
+	}
+	return ""
+}
+
+type TransformImageResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Content:
+	//
+	//	*TransformImageResponse_Metadata
+	//	*TransformImageResponse_Chunk
+	Content       isTransformImageResponse_Content `protobuf_oneof:"content"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransformImageResponse) Reset() {
+	*x = TransformImageResponse{}
+	mi := &file_api_proto_file_service_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransformImageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransformImageResponse) ProtoMessage() {}
+
+func (x *TransformImageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransformImageResponse.ProtoReflect.Descriptor instead.
+func (*TransformImageResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *TransformImageResponse) GetContent() isTransformImageResponse_Content {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+func (x *TransformImageResponse) GetMetadata() *RenditionMetadata {
+	if x != nil {
+		if x, ok := x.Content.(*TransformImageResponse_Metadata); ok {
+			return x.Metadata
+		}
+	}
+	return nil
+}
+
+func (x *TransformImageResponse) GetChunk() []byte {
+	if x != nil {
+		if x, ok := x.Content.(*TransformImageResponse_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+type isTransformImageResponse_Content interface {
+	isTransformImageResponse_Content()
+}
+
+type TransformImageResponse_Metadata struct {
+	Metadata *RenditionMetadata `protobuf:"bytes,1,opt,name=metadata,proto3,oneof"`
+}
+
+type TransformImageResponse_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"`
+}
+
+func (*TransformImageResponse_Metadata) isTransformImageResponse_Content() {}
+
+func (*TransformImageResponse_Chunk) isTransformImageResponse_Content() {}
+
+type ListFilesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageSize      uint32                 `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`   // 0 - размер по умолчанию (100), максимум 1000
+	PageToken     string                 `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"` // next_page_token из предыдущего ответа
+	NamePrefix    string                 `protobuf:"bytes,3,opt,name=name_prefix,json=namePrefix,proto3" json:"name_prefix,omitempty"`
+	NamePattern   string                 `protobuf:"bytes,4,opt,name=name_pattern,json=namePattern,proto3" json:"name_pattern,omitempty"` // Glob, например "*.jpg"
+	MinSize       uint64                 `protobuf:"varint,5,opt,name=min_size,json=minSize,proto3" json:"min_size,omitempty"`
+	MaxSize       uint64                 `protobuf:"varint,6,opt,name=max_size,json=maxSize,proto3" json:"max_size,omitempty"` // 0 - без ограничения
+	CreatedAfter  *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_after,json=createdAfter,proto3" json:"created_after,omitempty"`
+	CreatedBefore *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_before,json=createdBefore,proto3" json:"created_before,omitempty"`
+	SortBy        SortField              `protobuf:"varint,9,opt,name=sort_by,json=sortBy,proto3,enum=file_service.SortField" json:"sort_by,omitempty"`
+	SortDirection SortDirection          `protobuf:"varint,10,opt,name=sort_direction,json=sortDirection,proto3,enum=file_service.SortDirection" json:"sort_direction,omitempty"`
+	Folder        string                 `protobuf:"bytes,11,opt,name=folder,proto3" json:"folder,omitempty"`                              // Папка вида "project/2024", пусто - корень
+	Recursive     bool                   `protobuf:"varint,12,opt,name=recursive,proto3" json:"recursive,omitempty"`                       // Включать файлы из вложенных папок
+	ContentType   string                 `protobuf:"bytes,13,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"` // Префикс типа содержимого, например "image/"
+	Uploader      string                 `protobuf:"bytes,14,opt,name=uploader,proto3" json:"uploader,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFilesRequest) Reset() {
+	*x = ListFilesRequest{}
+	mi := &file_api_proto_file_service_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFilesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFilesRequest) ProtoMessage() {}
+
+func (x *ListFilesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFilesRequest.ProtoReflect.Descriptor instead.
+func (*ListFilesRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListFilesRequest) GetPageSize() uint32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListFilesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListFilesRequest) GetNamePrefix() string {
+	if x != nil {
+		return x.NamePrefix
+	}
+	return ""
+}
+
+func (x *ListFilesRequest) GetNamePattern() string {
+	if x != nil {
+		return x.NamePattern
+	}
+	return ""
+}
+
+func (x *ListFilesRequest) GetMinSize() uint64 {
+	if x != nil {
+		return x.MinSize
+	}
+	return 0
+}
+
+func (x *ListFilesRequest) GetMaxSize() uint64 {
+	if x != nil {
+		return x.MaxSize
+	}
+	return 0
+}
+
+func (x *ListFilesRequest) GetCreatedAfter() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAfter
+	}
+	return nil
+}
+
+func (x *ListFilesRequest) GetCreatedBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedBefore
+	}
+	return nil
+}
+
+func (x *ListFilesRequest) GetSortBy() SortField {
+	if x != nil {
+		return x.SortBy
+	}
+	return SortField_SORT_FIELD_CREATED_AT
+}
+
+func (x *ListFilesRequest) GetSortDirection() SortDirection {
+	if x != nil {
+		return x.SortDirection
+	}
+	return SortDirection_SORT_DIRECTION_DESC
+}
+
+func (x *ListFilesRequest) GetFolder() string {
+	if x != nil {
+		return x.Folder
+	}
+	return ""
+}
+
+func (x *ListFilesRequest) GetRecursive() bool {
+	if x != nil {
+		return x.Recursive
+	}
+	return false
+}
+
+func (x *ListFilesRequest) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *ListFilesRequest) GetUploader() string {
+	if x != nil {
+		return x.Uploader
+	}
+	return ""
+}
+
+type ListFilesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Files         []*FileInfo            `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"` // Пустой на последней странице
+	// Вложенные папки при нерекурсивном запросе, только на первой странице
+	Folders []string `protobuf:"bytes,3,rep,name=folders,proto3" json:"folders,omitempty"`
+	// Объем всех файлов под фильтром (не только страницы): сумма размеров и
+	// место, которое они занимают в хранилище. При дедупликации (storage.type
+	// "cas") физический объем меньше логического
+	LogicalBytes  uint64 `protobuf:"varint,4,opt,name=logical_bytes,json=logicalBytes,proto3" json:"logical_bytes,omitempty"`
+	PhysicalBytes uint64 `protobuf:"varint,5,opt,name=physical_bytes,json=physicalBytes,proto3" json:"physical_bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFilesResponse) Reset() {
+	*x = ListFilesResponse{}
+	mi := &file_api_proto_file_service_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFilesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFilesResponse) ProtoMessage() {}
+
+func (x *ListFilesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFilesResponse.ProtoReflect.Descriptor instead.
+func (*ListFilesResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListFilesResponse) GetFiles() []*FileInfo {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *ListFilesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+func (x *ListFilesResponse) GetFolders() []string {
+	if x != nil {
+		return x.Folders
+	}
+	return nil
+}
+
+func (x *ListFilesResponse) GetLogicalBytes() uint64 {
+	if x != nil {
+		return x.LogicalBytes
+	}
+	return 0
+}
+
+func (x *ListFilesResponse) GetPhysicalBytes() uint64 {
+	if x != nil {
+		return x.PhysicalBytes
+	}
+	return 0
+}
+
+type StreamFilesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BatchSize     uint32                 `protobuf:"varint,1,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"` // 0 - размер по умолчанию (100), максимум 1000
+	NamePrefix    string                 `protobuf:"bytes,2,opt,name=name_prefix,json=namePrefix,proto3" json:"name_prefix,omitempty"`
+	NamePattern   string                 `protobuf:"bytes,3,opt,name=name_pattern,json=namePattern,proto3" json:"name_pattern,omitempty"`
+	MinSize       uint64                 `protobuf:"varint,4,opt,name=min_size,json=minSize,proto3" json:"min_size,omitempty"`
+	MaxSize       uint64                 `protobuf:"varint,5,opt,name=max_size,json=maxSize,proto3" json:"max_size,omitempty"`
+	CreatedAfter  *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_after,json=createdAfter,proto3" json:"created_after,omitempty"`
+	CreatedBefore *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_before,json=createdBefore,proto3" json:"created_before,omitempty"`
+	Folder        string                 `protobuf:"bytes,8,opt,name=folder,proto3" json:"folder,omitempty"`
+	Recursive     bool                   `protobuf:"varint,9,opt,name=recursive,proto3" json:"recursive,omitempty"`
+	ContentType   string                 `protobuf:"bytes,10,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Uploader      string                 `protobuf:"bytes,11,opt,name=uploader,proto3" json:"uploader,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamFilesRequest) Reset() {
+	*x = StreamFilesRequest{}
+	mi := &file_api_proto_file_service_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamFilesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamFilesRequest) ProtoMessage() {}
+
+func (x *StreamFilesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamFilesRequest.ProtoReflect.Descriptor instead.
+func (*StreamFilesRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *StreamFilesRequest) GetBatchSize() uint32 {
+	if x != nil {
+		return x.BatchSize
+	}
+	return 0
+}
+
+func (x *StreamFilesRequest) GetNamePrefix() string {
+	if x != nil {
+		return x.NamePrefix
+	}
+	return ""
+}
+
+func (x *StreamFilesRequest) GetNamePattern() string {
+	if x != nil {
+		return x.NamePattern
+	}
+	return ""
+}
+
+func (x *StreamFilesRequest) GetMinSize() uint64 {
+	if x != nil {
+		return x.MinSize
+	}
+	return 0
+}
+
+func (x *StreamFilesRequest) GetMaxSize() uint64 {
+	if x != nil {
+		return x.MaxSize
+	}
+	return 0
+}
+
+func (x *StreamFilesRequest) GetCreatedAfter() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAfter
+	}
+	return nil
+}
+
+func (x *StreamFilesRequest) GetCreatedBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedBefore
+	}
+	return nil
+}
+
+func (x *StreamFilesRequest) GetFolder() string {
+	if x != nil {
+		return x.Folder
+	}
+	return ""
+}
+
+func (x *StreamFilesRequest) GetRecursive() bool {
+	if x != nil {
+		return x.Recursive
+	}
+	return false
+}
+
+func (x *StreamFilesRequest) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *StreamFilesRequest) GetUploader() string {
+	if x != nil {
+		return x.Uploader
+	}
+	return ""
+}
+
+type StreamFilesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Files         []*FileInfo            `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamFilesResponse) Reset() {
+	*x = StreamFilesResponse{}
+	mi := &file_api_proto_file_service_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamFilesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamFilesResponse) ProtoMessage() {}
+
+func (x *StreamFilesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamFilesResponse.ProtoReflect.Descriptor instead.
+func (*StreamFilesResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *StreamFilesResponse) GetFiles() []*FileInfo {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+type DeleteFileRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Filename string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	// Условия на текущую версию файла, при невыполнении - FAILED_PRECONDITION
+	IfMatch       string `protobuf:"bytes,2,opt,name=if_match,json=ifMatch,proto3" json:"if_match,omitempty"`               // ETag или "*"
+	IfNoneMatch   string `protobuf:"bytes,3,opt,name=if_none_match,json=ifNoneMatch,proto3" json:"if_none_match,omitempty"` // ETag или "*"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteFileRequest) Reset() {
+	*x = DeleteFileRequest{}
+	mi := &file_api_proto_file_service_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteFileRequest) ProtoMessage() {}
+
+func (x *DeleteFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteFileRequest.ProtoReflect.Descriptor instead.
+func (*DeleteFileRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *DeleteFileRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *DeleteFileRequest) GetIfMatch() string {
+	if x != nil {
+		return x.IfMatch
+	}
+	return ""
+}
+
+func (x *DeleteFileRequest) GetIfNoneMatch() string {
+	if x != nil {
+		return x.IfNoneMatch
+	}
+	return ""
+}
+
+type DeleteFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TrashId       string                 `protobuf:"bytes,1,opt,name=trash_id,json=trashId,proto3" json:"trash_id,omitempty"` // Идентификатор файла в корзине
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteFileResponse) Reset() {
+	*x = DeleteFileResponse{}
+	mi := &file_api_proto_file_service_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteFileResponse) ProtoMessage() {}
+
+func (x *DeleteFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteFileResponse.ProtoReflect.Descriptor instead.
+func (*DeleteFileResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DeleteFileResponse) GetTrashId() string {
+	if x != nil {
+		return x.TrashId
+	}
+	return ""
+}
+
+type CreateUploadSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Size          uint64                 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`    // Ожидаемый размер файла, 0 - неизвестен
+	Sha256        string                 `protobuf:"bytes,3,opt,name=sha256,proto3" json:"sha256,omitempty"` // Ожидаемый SHA-256 в hex, проверяется при завершении
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateUploadSessionRequest) Reset() {
+	*x = CreateUploadSessionRequest{}
+	mi := &file_api_proto_file_service_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateUploadSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateUploadSessionRequest) ProtoMessage() {}
+
+func (x *CreateUploadSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateUploadSessionRequest.ProtoReflect.Descriptor instead.
+func (*CreateUploadSessionRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CreateUploadSessionRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *CreateUploadSessionRequest) GetSize() uint64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *CreateUploadSessionRequest) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+type UploadSessionChunkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Offset        uint64                 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Chunk         []byte                 `protobuf:"bytes,3,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadSessionChunkRequest) Reset() {
+	*x = UploadSessionChunkRequest{}
+	mi := &file_api_proto_file_service_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadSessionChunkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadSessionChunkRequest) ProtoMessage() {}
+
+func (x *UploadSessionChunkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadSessionChunkRequest.ProtoReflect.Descriptor instead.
+func (*UploadSessionChunkRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *UploadSessionChunkRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *UploadSessionChunkRequest) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *UploadSessionChunkRequest) GetChunk() []byte {
+	if x != nil {
+		return x.Chunk
+	}
+	return nil
+}
+
+type GetUploadSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUploadSessionRequest) Reset() {
+	*x = GetUploadSessionRequest{}
+	mi := &file_api_proto_file_service_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUploadSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUploadSessionRequest) ProtoMessage() {}
+
+func (x *GetUploadSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUploadSessionRequest.ProtoReflect.Descriptor instead.
+func (*GetUploadSessionRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *GetUploadSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type FinalizeUploadSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FinalizeUploadSessionRequest) Reset() {
+	*x = FinalizeUploadSessionRequest{}
+	mi := &file_api_proto_file_service_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FinalizeUploadSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FinalizeUploadSessionRequest) ProtoMessage() {}
+
+func (x *FinalizeUploadSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FinalizeUploadSessionRequest.ProtoReflect.Descriptor instead.
+func (*FinalizeUploadSessionRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *FinalizeUploadSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type UploadSession struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	Size          uint64                 `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	CommittedSize uint64                 `protobuf:"varint,4,opt,name=committed_size,json=committedSize,proto3" json:"committed_size,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadSession) Reset() {
+	*x = UploadSession{}
+	mi := &file_api_proto_file_service_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadSession) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadSession) ProtoMessage() {}
+
+func (x *UploadSession) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadSession.ProtoReflect.Descriptor instead.
+func (*UploadSession) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *UploadSession) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *UploadSession) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *UploadSession) GetSize() uint64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *UploadSession) GetCommittedSize() uint64 {
+	if x != nil {
+		return x.CommittedSize
+	}
+	return 0
+}
+
+func (x *UploadSession) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *UploadSession) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type RenameFileRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Source      string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Destination string                 `protobuf:"bytes,2,opt,name=destination,proto3" json:"destination,omitempty"`
+	// Заменить существующий файл назначения. Без флага возвращается ALREADY_EXISTS
+	Overwrite     bool `protobuf:"varint,3,opt,name=overwrite,proto3" json:"overwrite,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenameFileRequest) Reset() {
+	*x = RenameFileRequest{}
+	mi := &file_api_proto_file_service_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameFileRequest) ProtoMessage() {}
+
+func (x *RenameFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameFileRequest.ProtoReflect.Descriptor instead.
+func (*RenameFileRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *RenameFileRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *RenameFileRequest) GetDestination() string {
+	if x != nil {
+		return x.Destination
+	}
+	return ""
+}
+
+func (x *RenameFileRequest) GetOverwrite() bool {
+	if x != nil {
+		return x.Overwrite
+	}
+	return false
+}
+
+type RenameFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	File          *FileInfo              `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenameFileResponse) Reset() {
+	*x = RenameFileResponse{}
+	mi := &file_api_proto_file_service_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameFileResponse) ProtoMessage() {}
+
+func (x *RenameFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameFileResponse.ProtoReflect.Descriptor instead.
+func (*RenameFileResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *RenameFileResponse) GetFile() *FileInfo {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+type CopyFileRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Source      string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Destination string                 `protobuf:"bytes,2,opt,name=destination,proto3" json:"destination,omitempty"`
+	// Заменить существующий файл назначения. Без флага возвращается ALREADY_EXISTS
+	Overwrite     bool `protobuf:"varint,3,opt,name=overwrite,proto3" json:"overwrite,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CopyFileRequest) Reset() {
+	*x = CopyFileRequest{}
+	mi := &file_api_proto_file_service_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CopyFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CopyFileRequest) ProtoMessage() {}
+
+func (x *CopyFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CopyFileRequest.ProtoReflect.Descriptor instead.
+func (*CopyFileRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *CopyFileRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *CopyFileRequest) GetDestination() string {
+	if x != nil {
+		return x.Destination
+	}
+	return ""
+}
+
+func (x *CopyFileRequest) GetOverwrite() bool {
+	if x != nil {
+		return x.Overwrite
+	}
+	return false
+}
+
+type CopyFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	File          *FileInfo              `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CopyFileResponse) Reset() {
+	*x = CopyFileResponse{}
+	mi := &file_api_proto_file_service_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CopyFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CopyFileResponse) ProtoMessage() {}
+
+func (x *CopyFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CopyFileResponse.ProtoReflect.Descriptor instead.
+func (*CopyFileResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *CopyFileResponse) GetFile() *FileInfo {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+type ListFileVersionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFileVersionsRequest) Reset() {
+	*x = ListFileVersionsRequest{}
+	mi := &file_api_proto_file_service_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFileVersionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFileVersionsRequest) ProtoMessage() {}
+
+func (x *ListFileVersionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFileVersionsRequest.ProtoReflect.Descriptor instead.
+func (*ListFileVersionsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ListFileVersionsRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+// Версии идут от старых к новым
+type ListFileVersionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Versions      []*FileVersion         `protobuf:"bytes,1,rep,name=versions,proto3" json:"versions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFileVersionsResponse) Reset() {
+	*x = ListFileVersionsResponse{}
+	mi := &file_api_proto_file_service_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFileVersionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFileVersionsResponse) ProtoMessage() {}
+
+func (x *ListFileVersionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFileVersionsResponse.ProtoReflect.Descriptor instead.
+func (*ListFileVersionsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ListFileVersionsResponse) GetVersions() []*FileVersion {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+type RestoreFileVersionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Version       uint64                 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreFileVersionRequest) Reset() {
+	*x = RestoreFileVersionRequest{}
+	mi := &file_api_proto_file_service_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreFileVersionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreFileVersionRequest) ProtoMessage() {}
+
+func (x *RestoreFileVersionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreFileVersionRequest.ProtoReflect.Descriptor instead.
+func (*RestoreFileVersionRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *RestoreFileVersionRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *RestoreFileVersionRequest) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type RestoreFileVersionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	File          *FileInfo              `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreFileVersionResponse) Reset() {
+	*x = RestoreFileVersionResponse{}
+	mi := &file_api_proto_file_service_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreFileVersionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreFileVersionResponse) ProtoMessage() {}
+
+func (x *RestoreFileVersionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreFileVersionResponse.ProtoReflect.Descriptor instead.
+func (*RestoreFileVersionResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *RestoreFileVersionResponse) GetFile() *FileInfo {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+type PruneFileVersionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Keep          uint32                 `protobuf:"varint,2,opt,name=keep,proto3" json:"keep,omitempty"` // Сколько последних версий оставить, 0 - удалить все
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PruneFileVersionsRequest) Reset() {
+	*x = PruneFileVersionsRequest{}
+	mi := &file_api_proto_file_service_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PruneFileVersionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PruneFileVersionsRequest) ProtoMessage() {}
+
+func (x *PruneFileVersionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PruneFileVersionsRequest.ProtoReflect.Descriptor instead.
+func (*PruneFileVersionsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *PruneFileVersionsRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *PruneFileVersionsRequest) GetKeep() uint32 {
+	if x != nil {
+		return x.Keep
+	}
+	return 0
+}
+
+type PruneFileVersionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Removed       uint32                 `protobuf:"varint,1,opt,name=removed,proto3" json:"removed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PruneFileVersionsResponse) Reset() {
+	*x = PruneFileVersionsResponse{}
+	mi := &file_api_proto_file_service_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PruneFileVersionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PruneFileVersionsResponse) ProtoMessage() {}
+
+func (x *PruneFileVersionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PruneFileVersionsResponse.ProtoReflect.Descriptor instead.
+func (*PruneFileVersionsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *PruneFileVersionsResponse) GetRemoved() uint32 {
+	if x != nil {
+		return x.Removed
+	}
+	return 0
+}
+
+type ListTrashRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTrashRequest) Reset() {
+	*x = ListTrashRequest{}
+	mi := &file_api_proto_file_service_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTrashRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTrashRequest) ProtoMessage() {}
+
+func (x *ListTrashRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTrashRequest.ProtoReflect.Descriptor instead.
+func (*ListTrashRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{31}
+}
+
+// Недавно удаленные файлы идут первыми
+type ListTrashResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*TrashItem           `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTrashResponse) Reset() {
+	*x = ListTrashResponse{}
+	mi := &file_api_proto_file_service_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTrashResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTrashResponse) ProtoMessage() {}
+
+func (x *ListTrashResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTrashResponse.ProtoReflect.Descriptor instead.
+func (*ListTrashResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *ListTrashResponse) GetItems() []*TrashItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type RestoreFileRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	TrashId string                 `protobuf:"bytes,1,opt,name=trash_id,json=trashId,proto3" json:"trash_id,omitempty"`
+	// Имя восстановленного файла, пустое - исходное имя.
+	// Существующий файл не заменяется, возвращается ALREADY_EXISTS
+	Destination   string `protobuf:"bytes,2,opt,name=destination,proto3" json:"destination,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreFileRequest) Reset() {
+	*x = RestoreFileRequest{}
+	mi := &file_api_proto_file_service_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreFileRequest) ProtoMessage() {}
+
+func (x *RestoreFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreFileRequest.ProtoReflect.Descriptor instead.
+func (*RestoreFileRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *RestoreFileRequest) GetTrashId() string {
+	if x != nil {
+		return x.TrashId
+	}
+	return ""
+}
+
+func (x *RestoreFileRequest) GetDestination() string {
+	if x != nil {
+		return x.Destination
+	}
+	return ""
+}
+
+type RestoreFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	File          *FileInfo              `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreFileResponse) Reset() {
+	*x = RestoreFileResponse{}
+	mi := &file_api_proto_file_service_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreFileResponse) ProtoMessage() {}
+
+func (x *RestoreFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreFileResponse.ProtoReflect.Descriptor instead.
+func (*RestoreFileResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *RestoreFileResponse) GetFile() *FileInfo {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+type EmptyTrashRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EmptyTrashRequest) Reset() {
+	*x = EmptyTrashRequest{}
+	mi := &file_api_proto_file_service_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EmptyTrashRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EmptyTrashRequest) ProtoMessage() {}
+
+func (x *EmptyTrashRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EmptyTrashRequest.ProtoReflect.Descriptor instead.
+func (*EmptyTrashRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{35}
+}
+
+type EmptyTrashResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Removed       uint32                 `protobuf:"varint,1,opt,name=removed,proto3" json:"removed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EmptyTrashResponse) Reset() {
+	*x = EmptyTrashResponse{}
+	mi := &file_api_proto_file_service_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EmptyTrashResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EmptyTrashResponse) ProtoMessage() {}
+
+func (x *EmptyTrashResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EmptyTrashResponse.ProtoReflect.Descriptor instead.
+func (*EmptyTrashResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *EmptyTrashResponse) GetRemoved() uint32 {
+	if x != nil {
+		return x.Removed
+	}
+	return 0
+}
+
+type TrashItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TrashId       string                 `protobuf:"bytes,1,opt,name=trash_id,json=trashId,proto3" json:"trash_id,omitempty"`
+	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"` // Имя, под которым файл был удален
+	Size          uint64                 `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	Sha256        string                 `protobuf:"bytes,4,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	ContentType   string                 `protobuf:"bytes,5,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	DeletedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=deleted_at,json=deletedAt,proto3" json:"deleted_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TrashItem) Reset() {
+	*x = TrashItem{}
+	mi := &file_api_proto_file_service_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TrashItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrashItem) ProtoMessage() {}
+
+func (x *TrashItem) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrashItem.ProtoReflect.Descriptor instead.
+func (*TrashItem) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *TrashItem) GetTrashId() string {
+	if x != nil {
+		return x.TrashId
+	}
+	return ""
+}
+
+func (x *TrashItem) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *TrashItem) GetSize() uint64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *TrashItem) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *TrashItem) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *TrashItem) GetDeletedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DeletedAt
+	}
+	return nil
+}
+
+type FileVersion struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       uint64                 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Size          uint64                 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Sha256        string                 `protobuf:"bytes,3,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	ContentType   string                 `protobuf:"bytes,4,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Uploader      string                 `protobuf:"bytes,5,opt,name=uploader,proto3" json:"uploader,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`    // Когда содержимое было загружено
+	ArchivedAt    *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=archived_at,json=archivedAt,proto3" json:"archived_at,omitempty"` // Когда его заменила новая версия
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileVersion) Reset() {
+	*x = FileVersion{}
+	mi := &file_api_proto_file_service_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileVersion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileVersion) ProtoMessage() {}
+
+func (x *FileVersion) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileVersion.ProtoReflect.Descriptor instead.
+func (*FileVersion) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *FileVersion) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *FileVersion) GetSize() uint64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileVersion) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *FileVersion) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *FileVersion) GetUploader() string {
+	if x != nil {
+		return x.Uploader
+	}
+	return ""
+}
+
+func (x *FileVersion) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *FileVersion) GetArchivedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ArchivedAt
+	}
+	return nil
+}
+
+type FileInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	Sha256        string                 `protobuf:"bytes,4,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	Size          uint64                 `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
+	ContentType   string                 `protobuf:"bytes,6,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Uploader      string                 `protobuf:"bytes,7,opt,name=uploader,proto3" json:"uploader,omitempty"`
+	Etag          string                 `protobuf:"bytes,8,opt,name=etag,proto3" json:"etag,omitempty"`   // Версия содержимого для if_match / if_none_match
+	Image         *ImageInfo             `protobuf:"bytes,9,opt,name=image,proto3" json:"image,omitempty"` // Не задано, если файл не изображение
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileInfo) Reset() {
+	*x = FileInfo{}
+	mi := &file_api_proto_file_service_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileInfo) ProtoMessage() {}
+
+func (x *FileInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileInfo.ProtoReflect.Descriptor instead.
+func (*FileInfo) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *FileInfo) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *FileInfo) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *FileInfo) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *FileInfo) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *FileInfo) GetSize() uint64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileInfo) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *FileInfo) GetUploader() string {
+	if x != nil {
+		return x.Uploader
+	}
+	return ""
+}
+
+func (x *FileInfo) GetEtag() string {
+	if x != nil {
+		return x.Etag
+	}
+	return ""
+}
+
+func (x *FileInfo) GetImage() *ImageInfo {
+	if x != nil {
+		return x.Image
+	}
+	return nil
+}
+
+// Свойства изображения, извлеченные при загрузке
+type ImageInfo struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Width      uint32                 `protobuf:"varint,1,opt,name=width,proto3" json:"width,omitempty"`
+	Height     uint32                 `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	Format     string                 `protobuf:"bytes,3,opt,name=format,proto3" json:"format,omitempty"`                           // jpeg, png, gif или webp
+	ColorModel string                 `protobuf:"bytes,4,opt,name=color_model,json=colorModel,proto3" json:"color_model,omitempty"` // ycbcr, rgba, gray, cmyk, paletted и т.д.
+	// Поля EXIF, не заданы, если их нет в файле
+	TakenAt       *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=taken_at,json=takenAt,proto3" json:"taken_at,omitempty"`
+	Orientation   uint32                 `protobuf:"varint,6,opt,name=orientation,proto3" json:"orientation,omitempty"` // 1-8
+	CameraModel   string                 `protobuf:"bytes,7,opt,name=camera_model,json=cameraModel,proto3" json:"camera_model,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImageInfo) Reset() {
+	*x = ImageInfo{}
+	mi := &file_api_proto_file_service_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImageInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImageInfo) ProtoMessage() {}
+
+func (x *ImageInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_file_service_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImageInfo.ProtoReflect.Descriptor instead.
+func (*ImageInfo) Descriptor() ([]byte, []int) {
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *ImageInfo) GetWidth() uint32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *ImageInfo) GetHeight() uint32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *ImageInfo) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *ImageInfo) GetColorModel() string {
+	if x != nil {
+		return x.ColorModel
+	}
+	return ""
+}
+
+func (x *ImageInfo) GetTakenAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TakenAt
+	}
+	return nil
+}
+
+func (x *ImageInfo) GetOrientation() uint32 {
+	if x != nil {
+		return x.Orientation
+	}
+	return 0
+}
+
+func (x *ImageInfo) GetCameraModel() string {
+	if x != nil {
+		return x.CameraModel
+	}
+	return ""
+}
+
+type FileMetadata struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Filename  string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Size      uint64                 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	CreatedAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// Диапазон, который отдает DownloadFile
+	Offset uint64 `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	Length uint64 `protobuf:"varint,5,opt,name=length,proto3" json:"length,omitempty"`
+	// SHA-256 содержимого в hex. При загрузке - ожидаемое значение,
+	// при несовпадении сервер отклоняет файл с DataLoss
+	Sha256      string `protobuf:"bytes,6,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	ContentType string `protobuf:"bytes,7,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	// Если не задан, сервер записывает адрес клиента
+	Uploader  string                 `protobuf:"bytes,8,opt,name=uploader,proto3" json:"uploader,omitempty"`
+	UpdatedAt *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	// Номер отданной версии, 0 - текущее содержимое
+	Version uint64 `protobuf:"varint,10,opt,name=version,proto3" json:"version,omitempty"`
+	// Что делать при загрузке, если файл с таким именем уже есть
+	ConflictPolicy ConflictPolicy `protobuf:"varint,11,opt,name=conflict_policy,json=conflictPolicy,proto3,enum=file_service.ConflictPolicy" json:"conflict_policy,omitempty"`
+	// Версия содержимого, меняется при каждой перезаписи
+	Etag string `protobuf:"bytes,12,opt,name=etag,proto3" json:"etag,omitempty"`
+	// Условия загрузки на текущую версию файла, при невыполнении -
+	// FAILED_PRECONDITION. "*" в if_match - файл должен существовать,
+	// в if_none_match - файл не должен существовать
+	IfMatch     string `protobuf:"bytes,13,opt,name=if_match,json=ifMatch,proto3" json:"if_match,omitempty"`
+	IfNoneMatch string `protobuf:"bytes,14,opt,name=if_none_match,json=ifNoneMatch,proto3" json:"if_none_match,omitempty"`
+	// DownloadFile: копия клиента актуальна, содержимое не передается
+	NotModified bool `protobuf:"varint,15,opt,name=not_modified,json=notModified,proto3" json:"not_modified,omitempty"`
+	// DownloadFile: свойства изображения, не задано для других файлов
+	Image *ImageInfo `protobuf:"bytes,16,opt,name=image,proto3" json:"image,omitempty"`
+	// Какие метаданные удалить из JPEG и PNG перед сохранением. Политика
+	// сервера действует всегда, запрос может только ужесточить ее
+	StripMetadata StripMetadata `protobuf:"varint,17,opt,name=strip_metadata,json=stripMetadata,proto3,enum=file_service.StripMetadata" json:"strip_metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileMetadata) Reset() {
+	*x = FileMetadata{}
+	mi := &file_api_proto_file_service_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileMetadata) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
 func (*FileMetadata) ProtoMessage() {}
 
 func (x *FileMetadata) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_file_service_proto_msgTypes[7]
+	mi := &file_api_proto_file_service_proto_msgTypes[41]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -466,7 +3065,7 @@ func (x *FileMetadata) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FileMetadata.ProtoReflect.Descriptor instead.
 func (*FileMetadata) Descriptor() ([]byte, []int) {
-	return file_api_proto_file_service_proto_rawDescGZIP(), []int{7}
+	return file_api_proto_file_service_proto_rawDescGZIP(), []int{41}
 }
 
 func (x *FileMetadata) GetFilename() string {
@@ -490,6 +3089,104 @@ func (x *FileMetadata) GetCreatedAt() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *FileMetadata) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *FileMetadata) GetLength() uint64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *FileMetadata) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *FileMetadata) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *FileMetadata) GetUploader() string {
+	if x != nil {
+		return x.Uploader
+	}
+	return ""
+}
+
+func (x *FileMetadata) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *FileMetadata) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *FileMetadata) GetConflictPolicy() ConflictPolicy {
+	if x != nil {
+		return x.ConflictPolicy
+	}
+	return ConflictPolicy_CONFLICT_POLICY_OVERWRITE
+}
+
+func (x *FileMetadata) GetEtag() string {
+	if x != nil {
+		return x.Etag
+	}
+	return ""
+}
+
+func (x *FileMetadata) GetIfMatch() string {
+	if x != nil {
+		return x.IfMatch
+	}
+	return ""
+}
+
+func (x *FileMetadata) GetIfNoneMatch() string {
+	if x != nil {
+		return x.IfNoneMatch
+	}
+	return ""
+}
+
+func (x *FileMetadata) GetNotModified() bool {
+	if x != nil {
+		return x.NotModified
+	}
+	return false
+}
+
+func (x *FileMetadata) GetImage() *ImageInfo {
+	if x != nil {
+		return x.Image
+	}
+	return nil
+}
+
+func (x *FileMetadata) GetStripMetadata() StripMetadata {
+	if x != nil {
+		return x.StripMetadata
+	}
+	return StripMetadata_STRIP_METADATA_DEFAULT
+}
+
 var File_api_proto_file_service_proto protoreflect.FileDescriptor
 
 const file_api_proto_file_service_proto_rawDesc = "" +
@@ -498,37 +3195,272 @@ const file_api_proto_file_service_proto_rawDesc = "" +
 	"\x11UploadFileRequest\x128\n" +
 	"\bmetadata\x18\x01 \x01(\v2\x1a.file_service.FileMetadataH\x00R\bmetadata\x12\x16\n" +
 	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\x06\n" +
-	"\x04data\"\x7f\n" +
+	"\x04data\"\xd8\x01\n" +
 	"\x12UploadFileResponse\x12\x1a\n" +
 	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x12\n" +
 	"\x04size\x18\x02 \x01(\x04R\x04size\x129\n" +
 	"\n" +
-	"created_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"1\n" +
+	"created_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x16\n" +
+	"\x06sha256\x18\x04 \x01(\tR\x06sha256\x12\x12\n" +
+	"\x04etag\x18\x05 \x01(\tR\x04etag\x12+\n" +
+	"\x11stripped_metadata\x18\x06 \x03(\tR\x10strippedMetadata\"\xe7\x01\n" +
 	"\x13DownloadFileRequest\x12\x1a\n" +
-	"\bfilename\x18\x01 \x01(\tR\bfilename\"s\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x04R\x06offset\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\x04R\x06length\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\x04R\aversion\x12\"\n" +
+	"\rif_none_match\x18\x05 \x01(\tR\vifNoneMatch\x12F\n" +
+	"\x11if_modified_since\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x0fifModifiedSince\"s\n" +
 	"\x14DownloadFileResponse\x128\n" +
 	"\bmetadata\x18\x01 \x01(\v2\x1a.file_service.FileMetadataH\x00R\bmetadata\x12\x16\n" +
 	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\t\n" +
-	"\acontent\"\x12\n" +
-	"\x10ListFilesRequest\"A\n" +
+	"\acontent\"E\n" +
+	"\x13GetThumbnailRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\rR\x04size\"\x92\x01\n" +
+	"\x11ThumbnailMetadata\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\rR\x04size\x12!\n" +
+	"\fcontent_type\x18\x03 \x01(\tR\vcontentType\x12\x16\n" +
+	"\x06length\x18\x04 \x01(\x04R\x06length\x12\x12\n" +
+	"\x04etag\x18\x05 \x01(\tR\x04etag\"x\n" +
+	"\x14GetThumbnailResponse\x12=\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x1f.file_service.ThumbnailMetadataH\x00R\bmetadata\x12\x16\n" +
+	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\t\n" +
+	"\acontent\"\xf6\x01\n" +
+	"\x15TransformImageRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x14\n" +
+	"\x05width\x18\x02 \x01(\rR\x05width\x12\x16\n" +
+	"\x06height\x18\x03 \x01(\rR\x06height\x12!\n" +
+	"\faspect_width\x18\x04 \x01(\rR\vaspectWidth\x12#\n" +
+	"\raspect_height\x18\x05 \x01(\rR\faspectHeight\x121\n" +
+	"\x06format\x18\x06 \x01(\x0e2\x19.file_service.ImageFormatR\x06format\x12\x18\n" +
+	"\aquality\x18\a \x01(\rR\aquality\"\xac\x01\n" +
+	"\x11RenditionMetadata\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x14\n" +
+	"\x05width\x18\x02 \x01(\rR\x05width\x12\x16\n" +
+	"\x06height\x18\x03 \x01(\rR\x06height\x12!\n" +
+	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\x12\x16\n" +
+	"\x06length\x18\x05 \x01(\x04R\x06length\x12\x12\n" +
+	"\x04etag\x18\x06 \x01(\tR\x04etag\"z\n" +
+	"\x16TransformImageResponse\x12=\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x1f.file_service.RenditionMetadataH\x00R\bmetadata\x12\x16\n" +
+	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\t\n" +
+	"\acontent\"\xb7\x04\n" +
+	"\x10ListFilesRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\rR\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\x12\x1f\n" +
+	"\vname_prefix\x18\x03 \x01(\tR\n" +
+	"namePrefix\x12!\n" +
+	"\fname_pattern\x18\x04 \x01(\tR\vnamePattern\x12\x19\n" +
+	"\bmin_size\x18\x05 \x01(\x04R\aminSize\x12\x19\n" +
+	"\bmax_size\x18\x06 \x01(\x04R\amaxSize\x12?\n" +
+	"\rcreated_after\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\fcreatedAfter\x12A\n" +
+	"\x0ecreated_before\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\rcreatedBefore\x120\n" +
+	"\asort_by\x18\t \x01(\x0e2\x17.file_service.SortFieldR\x06sortBy\x12B\n" +
+	"\x0esort_direction\x18\n" +
+	" \x01(\x0e2\x1b.file_service.SortDirectionR\rsortDirection\x12\x16\n" +
+	"\x06folder\x18\v \x01(\tR\x06folder\x12\x1c\n" +
+	"\trecursive\x18\f \x01(\bR\trecursive\x12!\n" +
+	"\fcontent_type\x18\r \x01(\tR\vcontentType\x12\x1a\n" +
+	"\buploader\x18\x0e \x01(\tR\buploader\"\xcf\x01\n" +
 	"\x11ListFilesResponse\x12,\n" +
-	"\x05files\x18\x01 \x03(\v2\x16.file_service.FileInfoR\x05files\"\x9c\x01\n" +
+	"\x05files\x18\x01 \x03(\v2\x16.file_service.FileInfoR\x05files\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\x12\x18\n" +
+	"\afolders\x18\x03 \x03(\tR\afolders\x12#\n" +
+	"\rlogical_bytes\x18\x04 \x01(\x04R\flogicalBytes\x12%\n" +
+	"\x0ephysical_bytes\x18\x05 \x01(\x04R\rphysicalBytes\"\xa6\x03\n" +
+	"\x12StreamFilesRequest\x12\x1d\n" +
+	"\n" +
+	"batch_size\x18\x01 \x01(\rR\tbatchSize\x12\x1f\n" +
+	"\vname_prefix\x18\x02 \x01(\tR\n" +
+	"namePrefix\x12!\n" +
+	"\fname_pattern\x18\x03 \x01(\tR\vnamePattern\x12\x19\n" +
+	"\bmin_size\x18\x04 \x01(\x04R\aminSize\x12\x19\n" +
+	"\bmax_size\x18\x05 \x01(\x04R\amaxSize\x12?\n" +
+	"\rcreated_after\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\fcreatedAfter\x12A\n" +
+	"\x0ecreated_before\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\rcreatedBefore\x12\x16\n" +
+	"\x06folder\x18\b \x01(\tR\x06folder\x12\x1c\n" +
+	"\trecursive\x18\t \x01(\bR\trecursive\x12!\n" +
+	"\fcontent_type\x18\n" +
+	" \x01(\tR\vcontentType\x12\x1a\n" +
+	"\buploader\x18\v \x01(\tR\buploader\"C\n" +
+	"\x13StreamFilesResponse\x12,\n" +
+	"\x05files\x18\x01 \x03(\v2\x16.file_service.FileInfoR\x05files\"n\n" +
+	"\x11DeleteFileRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x19\n" +
+	"\bif_match\x18\x02 \x01(\tR\aifMatch\x12\"\n" +
+	"\rif_none_match\x18\x03 \x01(\tR\vifNoneMatch\"/\n" +
+	"\x12DeleteFileResponse\x12\x19\n" +
+	"\btrash_id\x18\x01 \x01(\tR\atrashId\"d\n" +
+	"\x1aCreateUploadSessionRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x04R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x03 \x01(\tR\x06sha256\"h\n" +
+	"\x19UploadSessionChunkRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x04R\x06offset\x12\x14\n" +
+	"\x05chunk\x18\x03 \x01(\fR\x05chunk\"8\n" +
+	"\x17GetUploadSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"=\n" +
+	"\x1cFinalizeUploadSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\xfb\x01\n" +
+	"\rUploadSession\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x04R\x04size\x12%\n" +
+	"\x0ecommitted_size\x18\x04 \x01(\x04R\rcommittedSize\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"k\n" +
+	"\x11RenameFileRequest\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x12 \n" +
+	"\vdestination\x18\x02 \x01(\tR\vdestination\x12\x1c\n" +
+	"\toverwrite\x18\x03 \x01(\bR\toverwrite\"@\n" +
+	"\x12RenameFileResponse\x12*\n" +
+	"\x04file\x18\x01 \x01(\v2\x16.file_service.FileInfoR\x04file\"i\n" +
+	"\x0fCopyFileRequest\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x12 \n" +
+	"\vdestination\x18\x02 \x01(\tR\vdestination\x12\x1c\n" +
+	"\toverwrite\x18\x03 \x01(\bR\toverwrite\">\n" +
+	"\x10CopyFileResponse\x12*\n" +
+	"\x04file\x18\x01 \x01(\v2\x16.file_service.FileInfoR\x04file\"5\n" +
+	"\x17ListFileVersionsRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\"Q\n" +
+	"\x18ListFileVersionsResponse\x125\n" +
+	"\bversions\x18\x01 \x03(\v2\x19.file_service.FileVersionR\bversions\"Q\n" +
+	"\x19RestoreFileVersionRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x04R\aversion\"H\n" +
+	"\x1aRestoreFileVersionResponse\x12*\n" +
+	"\x04file\x18\x01 \x01(\v2\x16.file_service.FileInfoR\x04file\"J\n" +
+	"\x18PruneFileVersionsRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x12\n" +
+	"\x04keep\x18\x02 \x01(\rR\x04keep\"5\n" +
+	"\x19PruneFileVersionsResponse\x12\x18\n" +
+	"\aremoved\x18\x01 \x01(\rR\aremoved\"\x12\n" +
+	"\x10ListTrashRequest\"B\n" +
+	"\x11ListTrashResponse\x12-\n" +
+	"\x05items\x18\x01 \x03(\v2\x17.file_service.TrashItemR\x05items\"Q\n" +
+	"\x12RestoreFileRequest\x12\x19\n" +
+	"\btrash_id\x18\x01 \x01(\tR\atrashId\x12 \n" +
+	"\vdestination\x18\x02 \x01(\tR\vdestination\"A\n" +
+	"\x13RestoreFileResponse\x12*\n" +
+	"\x04file\x18\x01 \x01(\v2\x16.file_service.FileInfoR\x04file\"\x13\n" +
+	"\x11EmptyTrashRequest\".\n" +
+	"\x12EmptyTrashResponse\x12\x18\n" +
+	"\aremoved\x18\x01 \x01(\rR\aremoved\"\xcc\x01\n" +
+	"\tTrashItem\x12\x19\n" +
+	"\btrash_id\x18\x01 \x01(\tR\atrashId\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x04R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x04 \x01(\tR\x06sha256\x12!\n" +
+	"\fcontent_type\x18\x05 \x01(\tR\vcontentType\x129\n" +
+	"\n" +
+	"deleted_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tdeletedAt\"\x8a\x02\n" +
+	"\vFileVersion\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x04R\aversion\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x04R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x03 \x01(\tR\x06sha256\x12!\n" +
+	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\x12\x1a\n" +
+	"\buploader\x18\x05 \x01(\tR\buploader\x129\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12;\n" +
+	"\varchived_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"archivedAt\"\xca\x02\n" +
 	"\bFileInfo\x12\x1a\n" +
 	"\bfilename\x18\x01 \x01(\tR\bfilename\x129\n" +
 	"\n" +
 	"created_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
 	"\n" +
-	"updated_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"y\n" +
+	"updated_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x16\n" +
+	"\x06sha256\x18\x04 \x01(\tR\x06sha256\x12\x12\n" +
+	"\x04size\x18\x05 \x01(\x04R\x04size\x12!\n" +
+	"\fcontent_type\x18\x06 \x01(\tR\vcontentType\x12\x1a\n" +
+	"\buploader\x18\a \x01(\tR\buploader\x12\x12\n" +
+	"\x04etag\x18\b \x01(\tR\x04etag\x12-\n" +
+	"\x05image\x18\t \x01(\v2\x17.file_service.ImageInfoR\x05image\"\xee\x01\n" +
+	"\tImageInfo\x12\x14\n" +
+	"\x05width\x18\x01 \x01(\rR\x05width\x12\x16\n" +
+	"\x06height\x18\x02 \x01(\rR\x06height\x12\x16\n" +
+	"\x06format\x18\x03 \x01(\tR\x06format\x12\x1f\n" +
+	"\vcolor_model\x18\x04 \x01(\tR\n" +
+	"colorModel\x125\n" +
+	"\btaken_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\atakenAt\x12 \n" +
+	"\vorientation\x18\x06 \x01(\rR\vorientation\x12!\n" +
+	"\fcamera_model\x18\a \x01(\tR\vcameraModel\"\x85\x05\n" +
 	"\fFileMetadata\x12\x1a\n" +
 	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x12\n" +
 	"\x04size\x18\x02 \x01(\x04R\x04size\x129\n" +
 	"\n" +
-	"created_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt2\x87\x02\n" +
+	"created_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x04R\x06offset\x12\x16\n" +
+	"\x06length\x18\x05 \x01(\x04R\x06length\x12\x16\n" +
+	"\x06sha256\x18\x06 \x01(\tR\x06sha256\x12!\n" +
+	"\fcontent_type\x18\a \x01(\tR\vcontentType\x12\x1a\n" +
+	"\buploader\x18\b \x01(\tR\buploader\x129\n" +
+	"\n" +
+	"updated_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x18\n" +
+	"\aversion\x18\n" +
+	" \x01(\x04R\aversion\x12E\n" +
+	"\x0fconflict_policy\x18\v \x01(\x0e2\x1c.file_service.ConflictPolicyR\x0econflictPolicy\x12\x12\n" +
+	"\x04etag\x18\f \x01(\tR\x04etag\x12\x19\n" +
+	"\bif_match\x18\r \x01(\tR\aifMatch\x12\"\n" +
+	"\rif_none_match\x18\x0e \x01(\tR\vifNoneMatch\x12!\n" +
+	"\fnot_modified\x18\x0f \x01(\bR\vnotModified\x12-\n" +
+	"\x05image\x18\x10 \x01(\v2\x17.file_service.ImageInfoR\x05image\x12B\n" +
+	"\x0estrip_metadata\x18\x11 \x01(\x0e2\x1b.file_service.StripMetadataR\rstripMetadata*n\n" +
+	"\vImageFormat\x12\x1c\n" +
+	"\x18IMAGE_FORMAT_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11IMAGE_FORMAT_JPEG\x10\x01\x12\x14\n" +
+	"\x10IMAGE_FORMAT_PNG\x10\x02\x12\x14\n" +
+	"\x10IMAGE_FORMAT_GIF\x10\x03*k\n" +
+	"\tSortField\x12\x19\n" +
+	"\x15SORT_FIELD_CREATED_AT\x10\x00\x12\x13\n" +
+	"\x0fSORT_FIELD_NAME\x10\x01\x12\x13\n" +
+	"\x0fSORT_FIELD_SIZE\x10\x02\x12\x19\n" +
+	"\x15SORT_FIELD_UPDATED_AT\x10\x03*@\n" +
+	"\rSortDirection\x12\x17\n" +
+	"\x13SORT_DIRECTION_DESC\x10\x00\x12\x16\n" +
+	"\x12SORT_DIRECTION_ASC\x10\x01*`\n" +
+	"\rStripMetadata\x12\x1a\n" +
+	"\x16STRIP_METADATA_DEFAULT\x10\x00\x12\x1b\n" +
+	"\x17STRIP_METADATA_LOCATION\x10\x01\x12\x16\n" +
+	"\x12STRIP_METADATA_ALL\x10\x02*o\n" +
+	"\x0eConflictPolicy\x12\x1d\n" +
+	"\x19CONFLICT_POLICY_OVERWRITE\x10\x00\x12\"\n" +
+	"\x1eCONFLICT_POLICY_FAIL_IF_EXISTS\x10\x01\x12\x1a\n" +
+	"\x16CONFLICT_POLICY_RENAME\x10\x022\xa0\r\n" +
 	"\vFileService\x12Q\n" +
 	"\n" +
 	"UploadFile\x12\x1f.file_service.UploadFileRequest\x1a .file_service.UploadFileResponse(\x01\x12W\n" +
 	"\fDownloadFile\x12!.file_service.DownloadFileRequest\x1a\".file_service.DownloadFileResponse0\x01\x12L\n" +
-	"\tListFiles\x12\x1e.file_service.ListFilesRequest\x1a\x1f.file_service.ListFilesResponseB1Z/github.com/keenoobi/grpc-file-manager/api/protob\x06proto3"
+	"\tListFiles\x12\x1e.file_service.ListFilesRequest\x1a\x1f.file_service.ListFilesResponse\x12T\n" +
+	"\vStreamFiles\x12 .file_service.StreamFilesRequest\x1a!.file_service.StreamFilesResponse0\x01\x12O\n" +
+	"\n" +
+	"DeleteFile\x12\x1f.file_service.DeleteFileRequest\x1a .file_service.DeleteFileResponse\x12O\n" +
+	"\n" +
+	"RenameFile\x12\x1f.file_service.RenameFileRequest\x1a .file_service.RenameFileResponse\x12I\n" +
+	"\bCopyFile\x12\x1d.file_service.CopyFileRequest\x1a\x1e.file_service.CopyFileResponse\x12a\n" +
+	"\x10ListFileVersions\x12%.file_service.ListFileVersionsRequest\x1a&.file_service.ListFileVersionsResponse\x12g\n" +
+	"\x12RestoreFileVersion\x12'.file_service.RestoreFileVersionRequest\x1a(.file_service.RestoreFileVersionResponse\x12d\n" +
+	"\x11PruneFileVersions\x12&.file_service.PruneFileVersionsRequest\x1a'.file_service.PruneFileVersionsResponse\x12L\n" +
+	"\tListTrash\x12\x1e.file_service.ListTrashRequest\x1a\x1f.file_service.ListTrashResponse\x12R\n" +
+	"\vRestoreFile\x12 .file_service.RestoreFileRequest\x1a!.file_service.RestoreFileResponse\x12O\n" +
+	"\n" +
+	"EmptyTrash\x12\x1f.file_service.EmptyTrashRequest\x1a .file_service.EmptyTrashResponse\x12\\\n" +
+	"\x13CreateUploadSession\x12(.file_service.CreateUploadSessionRequest\x1a\x1b.file_service.UploadSession\x12Z\n" +
+	"\x12UploadSessionChunk\x12'.file_service.UploadSessionChunkRequest\x1a\x1b.file_service.UploadSession\x12V\n" +
+	"\x10GetUploadSession\x12%.file_service.GetUploadSessionRequest\x1a\x1b.file_service.UploadSession\x12e\n" +
+	"\x15FinalizeUploadSession\x12*.file_service.FinalizeUploadSessionRequest\x1a .file_service.UploadFileResponse\x12W\n" +
+	"\fGetThumbnail\x12!.file_service.GetThumbnailRequest\x1a\".file_service.GetThumbnailResponse0\x01\x12]\n" +
+	"\x0eTransformImage\x12#.file_service.TransformImageRequest\x1a$.file_service.TransformImageResponse0\x01B1Z/github.com/keenoobi/grpc-file-manager/api/protob\x06proto3"
 
 var (
 	file_api_proto_file_service_proto_rawDescOnce sync.Once
@@ -542,37 +3474,137 @@ func file_api_proto_file_service_proto_rawDescGZIP() []byte {
 	return file_api_proto_file_service_proto_rawDescData
 }
 
-var file_api_proto_file_service_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_api_proto_file_service_proto_enumTypes = make([]protoimpl.EnumInfo, 5)
+var file_api_proto_file_service_proto_msgTypes = make([]protoimpl.MessageInfo, 42)
 var file_api_proto_file_service_proto_goTypes = []any{
-	(*UploadFileRequest)(nil),     // 0: file_service.UploadFileRequest
-	(*UploadFileResponse)(nil),    // 1: file_service.UploadFileResponse
-	(*DownloadFileRequest)(nil),   // 2: file_service.DownloadFileRequest
-	(*DownloadFileResponse)(nil),  // 3: file_service.DownloadFileResponse
-	(*ListFilesRequest)(nil),      // 4: file_service.ListFilesRequest
-	(*ListFilesResponse)(nil),     // 5: file_service.ListFilesResponse
-	(*FileInfo)(nil),              // 6: file_service.FileInfo
-	(*FileMetadata)(nil),          // 7: file_service.FileMetadata
-	(*timestamppb.Timestamp)(nil), // 8: google.protobuf.Timestamp
+	(ImageFormat)(0),                     // 0: file_service.ImageFormat
+	(SortField)(0),                       // 1: file_service.SortField
+	(SortDirection)(0),                   // 2: file_service.SortDirection
+	(StripMetadata)(0),                   // 3: file_service.StripMetadata
+	(ConflictPolicy)(0),                  // 4: file_service.ConflictPolicy
+	(*UploadFileRequest)(nil),            // 5: file_service.UploadFileRequest
+	(*UploadFileResponse)(nil),           // 6: file_service.UploadFileResponse
+	(*DownloadFileRequest)(nil),          // 7: file_service.DownloadFileRequest
+	(*DownloadFileResponse)(nil),         // 8: file_service.DownloadFileResponse
+	(*GetThumbnailRequest)(nil),          // 9: file_service.GetThumbnailRequest
+	(*ThumbnailMetadata)(nil),            // 10: file_service.ThumbnailMetadata
+	(*GetThumbnailResponse)(nil),         // 11: file_service.GetThumbnailResponse
+	(*TransformImageRequest)(nil),        // 12: file_service.TransformImageRequest
+	(*RenditionMetadata)(nil),            // 13: file_service.RenditionMetadata
+	(*TransformImageResponse)(nil),       // 14: file_service.TransformImageResponse
+	(*ListFilesRequest)(nil),             // 15: file_service.ListFilesRequest
+	(*ListFilesResponse)(nil),            // 16: file_service.ListFilesResponse
+	(*StreamFilesRequest)(nil),           // 17: file_service.StreamFilesRequest
+	(*StreamFilesResponse)(nil),          // 18: file_service.StreamFilesResponse
+	(*DeleteFileRequest)(nil),            // 19: file_service.DeleteFileRequest
+	(*DeleteFileResponse)(nil),           // 20: file_service.DeleteFileResponse
+	(*CreateUploadSessionRequest)(nil),   // 21: file_service.CreateUploadSessionRequest
+	(*UploadSessionChunkRequest)(nil),    // 22: file_service.UploadSessionChunkRequest
+	(*GetUploadSessionRequest)(nil),      // 23: file_service.GetUploadSessionRequest
+	(*FinalizeUploadSessionRequest)(nil), // 24: file_service.FinalizeUploadSessionRequest
+	(*UploadSession)(nil),                // 25: file_service.UploadSession
+	(*RenameFileRequest)(nil),            // 26: file_service.RenameFileRequest
+	(*RenameFileResponse)(nil),           // 27: file_service.RenameFileResponse
+	(*CopyFileRequest)(nil),              // 28: file_service.CopyFileRequest
+	(*CopyFileResponse)(nil),             // 29: file_service.CopyFileResponse
+	(*ListFileVersionsRequest)(nil),      // 30: file_service.ListFileVersionsRequest
+	(*ListFileVersionsResponse)(nil),     // 31: file_service.ListFileVersionsResponse
+	(*RestoreFileVersionRequest)(nil),    // 32: file_service.RestoreFileVersionRequest
+	(*RestoreFileVersionResponse)(nil),   // 33: file_service.RestoreFileVersionResponse
+	(*PruneFileVersionsRequest)(nil),     // 34: file_service.PruneFileVersionsRequest
+	(*PruneFileVersionsResponse)(nil),    // 35: file_service.PruneFileVersionsResponse
+	(*ListTrashRequest)(nil),             // 36: file_service.ListTrashRequest
+	(*ListTrashResponse)(nil),            // 37: file_service.ListTrashResponse
+	(*RestoreFileRequest)(nil),           // 38: file_service.RestoreFileRequest
+	(*RestoreFileResponse)(nil),          // 39: file_service.RestoreFileResponse
+	(*EmptyTrashRequest)(nil),            // 40: file_service.EmptyTrashRequest
+	(*EmptyTrashResponse)(nil),           // 41: file_service.EmptyTrashResponse
+	(*TrashItem)(nil),                    // 42: file_service.TrashItem
+	(*FileVersion)(nil),                  // 43: file_service.FileVersion
+	(*FileInfo)(nil),                     // 44: file_service.FileInfo
+	(*ImageInfo)(nil),                    // 45: file_service.ImageInfo
+	(*FileMetadata)(nil),                 // 46: file_service.FileMetadata
+	(*timestamppb.Timestamp)(nil),        // 47: google.protobuf.Timestamp
 }
 var file_api_proto_file_service_proto_depIdxs = []int32{
-	7,  // 0: file_service.UploadFileRequest.metadata:type_name -> file_service.FileMetadata
-	8,  // 1: file_service.UploadFileResponse.created_at:type_name -> google.protobuf.Timestamp
-	7,  // 2: file_service.DownloadFileResponse.metadata:type_name -> file_service.FileMetadata
-	6,  // 3: file_service.ListFilesResponse.files:type_name -> file_service.FileInfo
-	8,  // 4: file_service.FileInfo.created_at:type_name -> google.protobuf.Timestamp
-	8,  // 5: file_service.FileInfo.updated_at:type_name -> google.protobuf.Timestamp
-	8,  // 6: file_service.FileMetadata.created_at:type_name -> google.protobuf.Timestamp
-	0,  // 7: file_service.FileService.UploadFile:input_type -> file_service.UploadFileRequest
-	2,  // 8: file_service.FileService.DownloadFile:input_type -> file_service.DownloadFileRequest
-	4,  // 9: file_service.FileService.ListFiles:input_type -> file_service.ListFilesRequest
-	1,  // 10: file_service.FileService.UploadFile:output_type -> file_service.UploadFileResponse
-	3,  // 11: file_service.FileService.DownloadFile:output_type -> file_service.DownloadFileResponse
-	5,  // 12: file_service.FileService.ListFiles:output_type -> file_service.ListFilesResponse
-	10, // [10:13] is the sub-list for method output_type
-	7,  // [7:10] is the sub-list for method input_type
-	7,  // [7:7] is the sub-list for extension type_name
-	7,  // [7:7] is the sub-list for extension extendee
-	0,  // [0:7] is the sub-list for field type_name
+	46, // 0: file_service.UploadFileRequest.metadata:type_name -> file_service.FileMetadata
+	47, // 1: file_service.UploadFileResponse.created_at:type_name -> google.protobuf.Timestamp
+	47, // 2: file_service.DownloadFileRequest.if_modified_since:type_name -> google.protobuf.Timestamp
+	46, // 3: file_service.DownloadFileResponse.metadata:type_name -> file_service.FileMetadata
+	10, // 4: file_service.GetThumbnailResponse.metadata:type_name -> file_service.ThumbnailMetadata
+	0,  // 5: file_service.TransformImageRequest.format:type_name -> file_service.ImageFormat
+	13, // 6: file_service.TransformImageResponse.metadata:type_name -> file_service.RenditionMetadata
+	47, // 7: file_service.ListFilesRequest.created_after:type_name -> google.protobuf.Timestamp
+	47, // 8: file_service.ListFilesRequest.created_before:type_name -> google.protobuf.Timestamp
+	1,  // 9: file_service.ListFilesRequest.sort_by:type_name -> file_service.SortField
+	2,  // 10: file_service.ListFilesRequest.sort_direction:type_name -> file_service.SortDirection
+	44, // 11: file_service.ListFilesResponse.files:type_name -> file_service.FileInfo
+	47, // 12: file_service.StreamFilesRequest.created_after:type_name -> google.protobuf.Timestamp
+	47, // 13: file_service.StreamFilesRequest.created_before:type_name -> google.protobuf.Timestamp
+	44, // 14: file_service.StreamFilesResponse.files:type_name -> file_service.FileInfo
+	47, // 15: file_service.UploadSession.created_at:type_name -> google.protobuf.Timestamp
+	47, // 16: file_service.UploadSession.updated_at:type_name -> google.protobuf.Timestamp
+	44, // 17: file_service.RenameFileResponse.file:type_name -> file_service.FileInfo
+	44, // 18: file_service.CopyFileResponse.file:type_name -> file_service.FileInfo
+	43, // 19: file_service.ListFileVersionsResponse.versions:type_name -> file_service.FileVersion
+	44, // 20: file_service.RestoreFileVersionResponse.file:type_name -> file_service.FileInfo
+	42, // 21: file_service.ListTrashResponse.items:type_name -> file_service.TrashItem
+	44, // 22: file_service.RestoreFileResponse.file:type_name -> file_service.FileInfo
+	47, // 23: file_service.TrashItem.deleted_at:type_name -> google.protobuf.Timestamp
+	47, // 24: file_service.FileVersion.updated_at:type_name -> google.protobuf.Timestamp
+	47, // 25: file_service.FileVersion.archived_at:type_name -> google.protobuf.Timestamp
+	47, // 26: file_service.FileInfo.created_at:type_name -> google.protobuf.Timestamp
+	47, // 27: file_service.FileInfo.updated_at:type_name -> google.protobuf.Timestamp
+	45, // 28: file_service.FileInfo.image:type_name -> file_service.ImageInfo
+	47, // 29: file_service.ImageInfo.taken_at:type_name -> google.protobuf.Timestamp
+	47, // 30: file_service.FileMetadata.created_at:type_name -> google.protobuf.Timestamp
+	47, // 31: file_service.FileMetadata.updated_at:type_name -> google.protobuf.Timestamp
+	4,  // 32: file_service.FileMetadata.conflict_policy:type_name -> file_service.ConflictPolicy
+	45, // 33: file_service.FileMetadata.image:type_name -> file_service.ImageInfo
+	3,  // 34: file_service.FileMetadata.strip_metadata:type_name -> file_service.StripMetadata
+	5,  // 35: file_service.FileService.UploadFile:input_type -> file_service.UploadFileRequest
+	7,  // 36: file_service.FileService.DownloadFile:input_type -> file_service.DownloadFileRequest
+	15, // 37: file_service.FileService.ListFiles:input_type -> file_service.ListFilesRequest
+	17, // 38: file_service.FileService.StreamFiles:input_type -> file_service.StreamFilesRequest
+	19, // 39: file_service.FileService.DeleteFile:input_type -> file_service.DeleteFileRequest
+	26, // 40: file_service.FileService.RenameFile:input_type -> file_service.RenameFileRequest
+	28, // 41: file_service.FileService.CopyFile:input_type -> file_service.CopyFileRequest
+	30, // 42: file_service.FileService.ListFileVersions:input_type -> file_service.ListFileVersionsRequest
+	32, // 43: file_service.FileService.RestoreFileVersion:input_type -> file_service.RestoreFileVersionRequest
+	34, // 44: file_service.FileService.PruneFileVersions:input_type -> file_service.PruneFileVersionsRequest
+	36, // 45: file_service.FileService.ListTrash:input_type -> file_service.ListTrashRequest
+	38, // 46: file_service.FileService.RestoreFile:input_type -> file_service.RestoreFileRequest
+	40, // 47: file_service.FileService.EmptyTrash:input_type -> file_service.EmptyTrashRequest
+	21, // 48: file_service.FileService.CreateUploadSession:input_type -> file_service.CreateUploadSessionRequest
+	22, // 49: file_service.FileService.UploadSessionChunk:input_type -> file_service.UploadSessionChunkRequest
+	23, // 50: file_service.FileService.GetUploadSession:input_type -> file_service.GetUploadSessionRequest
+	24, // 51: file_service.FileService.FinalizeUploadSession:input_type -> file_service.FinalizeUploadSessionRequest
+	9,  // 52: file_service.FileService.GetThumbnail:input_type -> file_service.GetThumbnailRequest
+	12, // 53: file_service.FileService.TransformImage:input_type -> file_service.TransformImageRequest
+	6,  // 54: file_service.FileService.UploadFile:output_type -> file_service.UploadFileResponse
+	8,  // 55: file_service.FileService.DownloadFile:output_type -> file_service.DownloadFileResponse
+	16, // 56: file_service.FileService.ListFiles:output_type -> file_service.ListFilesResponse
+	18, // 57: file_service.FileService.StreamFiles:output_type -> file_service.StreamFilesResponse
+	20, // 58: file_service.FileService.DeleteFile:output_type -> file_service.DeleteFileResponse
+	27, // 59: file_service.FileService.RenameFile:output_type -> file_service.RenameFileResponse
+	29, // 60: file_service.FileService.CopyFile:output_type -> file_service.CopyFileResponse
+	31, // 61: file_service.FileService.ListFileVersions:output_type -> file_service.ListFileVersionsResponse
+	33, // 62: file_service.FileService.RestoreFileVersion:output_type -> file_service.RestoreFileVersionResponse
+	35, // 63: file_service.FileService.PruneFileVersions:output_type -> file_service.PruneFileVersionsResponse
+	37, // 64: file_service.FileService.ListTrash:output_type -> file_service.ListTrashResponse
+	39, // 65: file_service.FileService.RestoreFile:output_type -> file_service.RestoreFileResponse
+	41, // 66: file_service.FileService.EmptyTrash:output_type -> file_service.EmptyTrashResponse
+	25, // 67: file_service.FileService.CreateUploadSession:output_type -> file_service.UploadSession
+	25, // 68: file_service.FileService.UploadSessionChunk:output_type -> file_service.UploadSession
+	25, // 69: file_service.FileService.GetUploadSession:output_type -> file_service.UploadSession
+	6,  // 70: file_service.FileService.FinalizeUploadSession:output_type -> file_service.UploadFileResponse
+	11, // 71: file_service.FileService.GetThumbnail:output_type -> file_service.GetThumbnailResponse
+	14, // 72: file_service.FileService.TransformImage:output_type -> file_service.TransformImageResponse
+	54, // [54:73] is the sub-list for method output_type
+	35, // [35:54] is the sub-list for method input_type
+	35, // [35:35] is the sub-list for extension type_name
+	35, // [35:35] is the sub-list for extension extendee
+	0,  // [0:35] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_file_service_proto_init() }
@@ -588,18 +3620,27 @@ func file_api_proto_file_service_proto_init() {
 		(*DownloadFileResponse_Metadata)(nil),
 		(*DownloadFileResponse_Chunk)(nil),
 	}
+	file_api_proto_file_service_proto_msgTypes[6].OneofWrappers = []any{
+		(*GetThumbnailResponse_Metadata)(nil),
+		(*GetThumbnailResponse_Chunk)(nil),
+	}
+	file_api_proto_file_service_proto_msgTypes[9].OneofWrappers = []any{
+		(*TransformImageResponse_Metadata)(nil),
+		(*TransformImageResponse_Chunk)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_file_service_proto_rawDesc), len(file_api_proto_file_service_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   8,
+			NumEnums:      5,
+			NumMessages:   42,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_api_proto_file_service_proto_goTypes,
 		DependencyIndexes: file_api_proto_file_service_proto_depIdxs,
+		EnumInfos:         file_api_proto_file_service_proto_enumTypes,
 		MessageInfos:      file_api_proto_file_service_proto_msgTypes,
 	}.Build()
 	File_api_proto_file_service_proto = out.File
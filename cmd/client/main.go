@@ -244,10 +244,22 @@ func uploadFile(client proto.FileServiceClient, filename string) error {
 		return fmt.Errorf("create upload stream: %w", err)
 	}
 
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+
+	// Сервер сверит хеш принятых данных с ожидаемым и отклонит битый файл
+	checksum := fileHash(filename)
+
 	// Отправляем метаданные
 	if err := stream.Send(&proto.UploadFileRequest{
 		Data: &proto.UploadFileRequest_Metadata{
-			Metadata: &proto.FileMetadata{Filename: filepath.Base(filename)},
+			Metadata: &proto.FileMetadata{
+				Filename: filepath.Base(filename),
+				Size:     uint64(info.Size()),
+				Sha256:   checksum,
+			},
 		},
 	}); err != nil {
 		return fmt.Errorf("send metadata: %w", err)
@@ -277,7 +289,7 @@ func uploadFile(client proto.FileServiceClient, filename string) error {
 		return fmt.Errorf("receive response: %w", err)
 	}
 
-	log.Printf("Upload completed: %s (size: %d)", resp.Filename, resp.Size)
+	log.Printf("Upload completed: %s (size: %d, sha256: %s)", resp.Filename, resp.Size, resp.Sha256)
 	return nil
 }
 
@@ -295,6 +307,9 @@ func downloadAndVerify(client proto.FileServiceClient, filename, saveTo string)
 	}
 	defer output.Close()
 
+	// Хеш скачанных данных сверяется с тем, что сервер посчитал при загрузке
+	h := sha256.New()
+	var expected string
 	for {
 		resp, err := stream.Recv()
 		if err == io.EOF {
@@ -304,33 +319,28 @@ func downloadAndVerify(client proto.FileServiceClient, filename, saveTo string)
 			return fmt.Errorf("receive chunk: %w", err)
 		}
 
+		if metadata := resp.GetMetadata(); metadata != nil {
+			expected = metadata.GetSha256()
+		}
 		if chunk := resp.GetChunk(); chunk != nil {
-			if _, err := output.Write(chunk); err != nil {
+			if _, err := io.MultiWriter(output, h).Write(chunk); err != nil {
 				return fmt.Errorf("write chunk: %w", err)
 			}
 		}
 	}
 
 	// Проверяем целостность
-	if !verifyChecksum(filename, saveTo) {
-		return fmt.Errorf("checksum mismatch for %s", filename)
+	if expected == "" {
+		return fmt.Errorf("server did not return checksum for %s", filename)
+	}
+	if actual := fmt.Sprintf("%x", h.Sum(nil)); actual != expected {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", filename, expected, actual)
 	}
 
 	log.Printf("Download verified: %s", filepath.Base(filename))
 	return nil
 }
 
-func verifyChecksum(original, downloaded string) bool {
-	origHash := fileHash(original)
-	downHash := fileHash(downloaded)
-
-	if origHash == "" || downHash == "" {
-		return false
-	}
-
-	return origHash == downHash
-}
-
 func fileHash(path string) string {
 	f, err := os.Open(path)
 	if err != nil {
@@ -347,16 +357,28 @@ func fileHash(path string) string {
 }
 
 func listFiles(client proto.FileServiceClient) {
-	resp, err := client.ListFiles(context.Background(), &proto.ListFilesRequest{})
-	if err != nil {
-		log.Printf("ListFiles failed: %v", err)
-		return
-	}
-
 	log.Println("Files list:")
-	for _, file := range resp.Files {
-		log.Printf("- %s (created: %v)",
-			file.Filename,
-			file.CreatedAt.AsTime().Format(time.RFC3339))
+
+	req := &proto.ListFilesRequest{Recursive: true}
+	for {
+		resp, err := client.ListFiles(context.Background(), req)
+		if err != nil {
+			log.Printf("ListFiles failed: %v", err)
+			return
+		}
+
+		for _, file := range resp.Files {
+			log.Printf("- %s (%d bytes, %s, created: %v, updated: %v)",
+				file.Filename,
+				file.Size,
+				file.ContentType,
+				file.CreatedAt.AsTime().Format(time.RFC3339),
+				file.UpdatedAt.AsTime().Format(time.RFC3339))
+		}
+
+		if resp.NextPageToken == "" {
+			return
+		}
+		req.PageToken = resp.NextPageToken
 	}
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -15,6 +16,9 @@ import (
 const shutdownTimeout = 5 * time.Second
 
 func main() {
+	rebuildIndex := flag.Bool("rebuild-index", false, "rebuild the metadata index from files on disk and exit")
+	flag.Parse()
+
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
 	}))
@@ -26,7 +30,21 @@ func main() {
 		return
 	}
 
-	application := app.New(cfg)
+	if *rebuildIndex {
+		indexed, err := app.RebuildIndex(context.Background(), cfg)
+		if err != nil {
+			slog.Error("Failed to rebuild index", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("Index rebuilt", "files", indexed)
+		return
+	}
+
+	application, err := app.New(cfg)
+	if err != nil {
+		slog.Error("Failed to create application", "error", err)
+		return
+	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
@@ -3,11 +3,15 @@ package app
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
+	"sync"
+	"time"
 
 	"github.com/keenoobi/grpc-file-manager/api/proto"
 	"github.com/keenoobi/grpc-file-manager/internal/config"
+	"github.com/keenoobi/grpc-file-manager/internal/imaging"
 	"github.com/keenoobi/grpc-file-manager/internal/middleware"
 	"github.com/keenoobi/grpc-file-manager/internal/repository"
 	grpctransport "github.com/keenoobi/grpc-file-manager/internal/transport/grpc"
@@ -19,15 +23,60 @@ import (
 type App struct {
 	GRPCServer *grpc.Server
 	config     *config.Config
+	useCase    usecase.FileUseCase
+	repo       repository.FileRepository
 }
 
-func New(cfg *config.Config) *App {
-
-	repo := repository.NewFileRepository(cfg.Storage.Path)
-	useCase := usecase.NewFileUseCase(repo)
+func New(cfg *config.Config) (*App, error) {
+	// Превью и преобразования декодируют присланные клиентами изображения
+	if cfg.Images.MaxPixels <= 0 {
+		return nil, fmt.Errorf("images.max_pixels must be positive, got %d", cfg.Images.MaxPixels)
+	}
+	if err := checkStatePath(cfg); err != nil {
+		return nil, err
+	}
+	repo, err := newFileRepository(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sessions := repository.NewUploadSessionRepository(cfg.Storage.Path)
+	var options []usecase.Option
+	if thumbnails := cfg.Images.Thumbnails; len(thumbnails.Sizes) > 0 {
+		for _, size := range thumbnails.Sizes {
+			if size <= 0 {
+				return nil, fmt.Errorf("invalid thumbnail size %d", size)
+			}
+		}
+		options = append(options, usecase.WithThumbnails(repository.NewThumbnailRepository(cfg.Storage.Path), usecase.ThumbnailOptions{
+			Sizes:     thumbnails.Sizes,
+			Workers:   thumbnails.Workers,
+			Queue:     thumbnails.Queue,
+			MaxPixels: cfg.Images.MaxPixels,
+		}))
+	}
+	var renditions repository.RenditionCache
+	if cfg.Images.Transform.CacheSize > 0 {
+		if renditions, err = repository.NewRenditionCache(cfg.Storage.Path, cfg.Images.Transform.CacheSize); err != nil {
+			return nil, err
+		}
+	}
+	options = append(options, usecase.WithTransforms(renditions, usecase.TransformLimits{
+		MaxPixels:    cfg.Images.MaxPixels,
+		MaxDimension: cfg.Images.Transform.MaxDimension,
+	}))
+	strip, ok := imaging.StripPolicies[cfg.Images.StripMetadata]
+	if !ok {
+		return nil, fmt.Errorf("unknown strip_metadata policy %q, expected none, location or all", cfg.Images.StripMetadata)
+	}
+	options = append(options, usecase.WithStripPolicy(strip))
+	options = append(options, usecase.WithContentPolicy(usecase.ContentPolicy{
+		AllowedTypes: cfg.Uploads.AllowedTypes,
+		VerifyImages: cfg.Uploads.VerifyImages,
+	}))
+	useCase := usecase.NewFileUseCase(repo, sessions, options...)
 	fileServiceServer := grpctransport.NewFileServiceServer(useCase)
 
-	limiter := middleware.NewConcurrencyLimiter(cfg.Limits.Upload, cfg.Limits.List)
+	limiter := middleware.NewConcurrencyLimiter(cfg.Limits.Upload, cfg.Limits.List, cfg.Limits.Stream)
 
 	grpcServer := grpc.NewServer(
 		grpc.ChainUnaryInterceptor(
@@ -48,7 +97,9 @@ func New(cfg *config.Config) *App {
 	return &App{
 		GRPCServer: grpcServer,
 		config:     cfg,
-	}
+		useCase:    useCase,
+		repo:       repo,
+	}, nil
 }
 
 func (a *App) Run(ctx context.Context) error {
@@ -59,6 +110,7 @@ func (a *App) Run(ctx context.Context) error {
 
 	slog.Info("Server starting",
 		"port", a.config.Server.Port,
+		"storage_type", a.config.Storage.Type,
 		"storage_path", a.config.Storage.Path)
 
 	go func() {
@@ -66,9 +118,56 @@ func (a *App) Run(ctx context.Context) error {
 		a.GRPCServer.GracefulStop()
 	}()
 
+	var purges sync.WaitGroup
+	if trash := a.config.Storage.Trash; trash.Retention > 0 && trash.PurgeInterval > 0 {
+		purges.Add(1)
+		go purgePeriodically(ctx, &purges, "Trash", trash.PurgeInterval, func(ctx context.Context) (int, error) {
+			return a.useCase.PurgeTrash(ctx, trash.Retention)
+		})
+	}
+	if sessions := a.config.Storage.Sessions; sessions.TTL > 0 && sessions.PurgeInterval > 0 {
+		purges.Add(1)
+		go purgePeriodically(ctx, &purges, "Upload sessions", sessions.PurgeInterval, func(ctx context.Context) (int, error) {
+			return a.useCase.PurgeUploadSessions(ctx, sessions.TTL)
+		})
+	}
+
 	if err := a.GRPCServer.Serve(listener); err != nil && err != grpc.ErrServerStopped {
 		return fmt.Errorf("failed to serve: %w", err)
 	}
 
+	// Фоновые задачи пишут в хранилище, поэтому завершаются до его закрытия
+	purges.Wait()
+	if err := a.useCase.Close(); err != nil {
+		return fmt.Errorf("failed to stop background tasks: %w", err)
+	}
+	// Хранилище с индексом держит блокировку базы до закрытия
+	if closer, ok := a.repo.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return fmt.Errorf("failed to close storage: %w", err)
+		}
+	}
 	return nil
 }
+
+// purgePeriodically вызывает purge раз в interval до отмены ctx
+func purgePeriodically(ctx context.Context, done *sync.WaitGroup, what string, interval time.Duration, purge func(context.Context) (int, error)) {
+	defer done.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := purge(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error(what+" purge failed", "error", err)
+		} else if removed > 0 {
+			slog.Info(what+" purged", "removed", removed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
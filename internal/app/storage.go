@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/keenoobi/grpc-file-manager/internal/config"
+	"github.com/keenoobi/grpc-file-manager/internal/repository"
+)
+
+// storageBackend создает хранилище файлов из своего блока storage.<type>
+type storageBackend func(cfg *config.Config, versioning repository.VersioningOptions) (repository.FileRepository, error)
+
+// storageBackends - хранилища, которые можно выбрать через storage.type
+var storageBackends = map[string]storageBackend{
+	"local": func(cfg *config.Config, versioning repository.VersioningOptions) (repository.FileRepository, error) {
+		path := backendPath(cfg, cfg.Storage.Local.Path)
+		if cfg.Storage.Local.Index {
+			slog.Warn("Listing uses the metadata index: files changed in the storage directory outside the service "+
+				"are not listed until the index is rebuilt with -rebuild-index", "storage_path", path)
+			return repository.NewIndexedFileRepository(context.Background(), path, versioning)
+		}
+		return repository.NewFileRepository(path, versioning), nil
+	},
+	"cas": func(cfg *config.Config, versioning repository.VersioningOptions) (repository.FileRepository, error) {
+		return repository.NewCASRepository(backendPath(cfg, cfg.Storage.CAS.Path), versioning), nil
+	},
+	"memory": func(cfg *config.Config, versioning repository.VersioningOptions) (repository.FileRepository, error) {
+		return repository.NewMemoryRepository(versioning), nil
+	},
+	"s3": func(cfg *config.Config, versioning repository.VersioningOptions) (repository.FileRepository, error) {
+		s3 := cfg.Storage.S3
+		// Подключение проверяется при запуске: без бакета сервис бесполезен
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		return repository.NewS3Repository(ctx, repository.S3Options{
+			Endpoint:        s3.Endpoint,
+			Region:          s3.Region,
+			Bucket:          s3.Bucket,
+			Prefix:          s3.Prefix,
+			AccessKeyID:     s3.AccessKeyID,
+			SecretAccessKey: s3.SecretAccessKey,
+			UseSSL:          s3.UseSSL,
+			PathStyle:       s3.PathStyle,
+			PartSize:        s3.PartSize,
+		}, versioning)
+	},
+}
+
+// diskBackends хранят файлы в каталоге на диске. Остальные бэкенды
+// пользуются storage.path только для служебных данных сервиса
+var diskBackends = map[string]bool{"local": true, "cas": true}
+
+// checkStatePath проверяет каталог служебных данных. Сессии загрузки, превью
+// и кеш вариантов изображений всегда лежат на локальном диске в storage.path,
+// даже если файлы хранятся в памяти или в S3
+func checkStatePath(cfg *config.Config) error {
+	if cfg.Storage.Path == "" {
+		return fmt.Errorf("storage.path is required: upload sessions, thumbnails and renditions are kept there")
+	}
+	if !diskBackends[cfg.Storage.Type] {
+		slog.Warn("Upload sessions, thumbnails and renditions are kept on local disk, not in the file storage: "+
+			"they are lost with the disk and not shared between instances",
+			"storage_type", cfg.Storage.Type, "storage_path", cfg.Storage.Path)
+	}
+	return nil
+}
+
+func newFileRepository(cfg *config.Config) (repository.FileRepository, error) {
+	backend, ok := storageBackends[cfg.Storage.Type]
+	if !ok {
+		types := make([]string, 0, len(storageBackends))
+		for name := range storageBackends {
+			types = append(types, name)
+		}
+		sort.Strings(types)
+		return nil, fmt.Errorf("unknown storage type %q, expected one of: %s", cfg.Storage.Type, strings.Join(types, ", "))
+	}
+
+	return backend(cfg, repository.VersioningOptions{
+		Enabled:   cfg.Storage.Versioning.Enabled,
+		Retention: cfg.Storage.Versioning.Retention,
+	})
+}
+
+// RebuildIndex пересобирает индекс метаданных локального хранилища по
+// файлам на диске. Сервер при этом должен быть остановлен
+func RebuildIndex(ctx context.Context, cfg *config.Config) (int, error) {
+	if cfg.Storage.Type != "local" {
+		return 0, fmt.Errorf("metadata index is supported only by local storage, got %q", cfg.Storage.Type)
+	}
+	return repository.RebuildFileIndex(ctx, backendPath(cfg, cfg.Storage.Local.Path))
+}
+
+// backendPath возвращает каталог бэкенда, по умолчанию - storage.path
+func backendPath(cfg *config.Config, path string) string {
+	if path == "" {
+		return cfg.Storage.Path
+	}
+	return path
+}
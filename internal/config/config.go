@@ -15,11 +15,72 @@ type Config struct {
 	Limits struct {
 		Upload int `mapstructure:"upload"`
 		List   int `mapstructure:"list"`
+		Stream int `mapstructure:"stream"`
 	} `mapstructure:"limits"`
 
+	Uploads struct {
+		AllowedTypes []string `mapstructure:"allowed_types"` // MIME-типы по сигнатуре, пусто - любые
+		VerifyImages bool     `mapstructure:"verify_images"` // Проверять заголовок изображений
+	} `mapstructure:"uploads"`
+
 	Storage struct {
+		Type string `mapstructure:"type"` // local, cas, memory или s3
+		// Локальный каталог сессий загрузки, превью и кеша вариантов изображений
+		// для всех бэкендов, в том числе memory и s3, и каталог бэкендов по умолчанию
 		Path string `mapstructure:"path"`
+
+		Local struct {
+			Path  string `mapstructure:"path"`
+			Index bool   `mapstructure:"index"` // Список файлов из индекса метаданных
+		} `mapstructure:"local"`
+
+		CAS struct {
+			Path string `mapstructure:"path"`
+		} `mapstructure:"cas"`
+
+		S3 struct {
+			Endpoint        string `mapstructure:"endpoint"` // host:port без схемы
+			Region          string `mapstructure:"region"`
+			Bucket          string `mapstructure:"bucket"`
+			Prefix          string `mapstructure:"prefix"`
+			AccessKeyID     string `mapstructure:"access_key_id"`
+			SecretAccessKey string `mapstructure:"secret_access_key"`
+			UseSSL          bool   `mapstructure:"use_ssl"`
+			PathStyle       bool   `mapstructure:"path_style"`
+			PartSize        int64  `mapstructure:"part_size"`
+		} `mapstructure:"s3"`
+
+		Versioning struct {
+			Enabled   bool `mapstructure:"enabled"`
+			Retention int  `mapstructure:"retention"` // 0 - хранить все версии
+		} `mapstructure:"versioning"`
+
+		Trash struct {
+			Retention     time.Duration `mapstructure:"retention"` // 0 - не очищать автоматически
+			PurgeInterval time.Duration `mapstructure:"purge_interval"`
+		} `mapstructure:"trash"`
+
+		Sessions struct {
+			TTL           time.Duration `mapstructure:"ttl"` // Незавершенные загрузки без записи дольше удаляются
+			PurgeInterval time.Duration `mapstructure:"purge_interval"`
+		} `mapstructure:"sessions"`
 	} `mapstructure:"storage"`
+
+	Images struct {
+		MaxPixels     int64  `mapstructure:"max_pixels"`     // Изображения больше не декодируются, > 0
+		StripMetadata string `mapstructure:"strip_metadata"` // none, location или all
+
+		Thumbnails struct {
+			Sizes   []int `mapstructure:"sizes"` // Пусто - превью отключены
+			Workers int   `mapstructure:"workers"`
+			Queue   int   `mapstructure:"queue"`
+		} `mapstructure:"thumbnails"`
+
+		Transform struct {
+			MaxDimension int   `mapstructure:"max_dimension"` // Наибольшие ширина и высота результата
+			CacheSize    int64 `mapstructure:"cache_size"`    // Байт на диске, 0 - без кеша
+		} `mapstructure:"transform"`
+	} `mapstructure:"images"`
 }
 
 func Load(path string) (*Config, error) {
@@ -29,7 +90,26 @@ func Load(path string) (*Config, error) {
 	viper.SetDefault("server.port", ":50051")
 	viper.SetDefault("limits.upload", 10)
 	viper.SetDefault("limits.list", 100)
+	viper.SetDefault("limits.stream", 10)
+	viper.SetDefault("uploads.verify_images", false)
+	viper.SetDefault("storage.type", "local")
 	viper.SetDefault("storage.path", "./storage")
+	viper.SetDefault("storage.local.index", false)
+	viper.SetDefault("storage.s3.region", "us-east-1")
+	viper.SetDefault("storage.s3.part_size", 16<<20)
+	viper.SetDefault("storage.versioning.enabled", false)
+	viper.SetDefault("storage.versioning.retention", 10)
+	viper.SetDefault("storage.trash.retention", 30*24*time.Hour)
+	viper.SetDefault("storage.trash.purge_interval", time.Hour)
+	viper.SetDefault("storage.sessions.ttl", 24*time.Hour)
+	viper.SetDefault("storage.sessions.purge_interval", time.Hour)
+	viper.SetDefault("images.max_pixels", 50_000_000)
+	viper.SetDefault("images.strip_metadata", "none")
+	viper.SetDefault("images.thumbnails.sizes", []int{128, 512})
+	viper.SetDefault("images.thumbnails.workers", 2)
+	viper.SetDefault("images.thumbnails.queue", 100)
+	viper.SetDefault("images.transform.max_dimension", 4096)
+	viper.SetDefault("images.transform.cache_size", 256<<20)
 
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, err
@@ -1,6 +1,9 @@
 package entity
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type File struct {
 	Name      string
@@ -8,4 +11,24 @@ type File struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Path      string
+	Checksum  string // SHA-256 содержимого в hex
+
+	DeclaredSize int64 // Размер, заявленный клиентом при загрузке
+	Uploader     string
+	ContentType  string
+
+	Image *ImageInfo // nil - не изображение
+
+	// Какие метаданные удалены при загрузке. Только в ответе на загрузку,
+	// не сохраняется
+	StrippedMetadata []string
+}
+
+// ETag меняется при каждом изменении содержимого. Для файлов без
+// сохраненного хеша он строится из времени изменения и размера
+func (f *File) ETag() string {
+	if f.Checksum != "" {
+		return f.Checksum
+	}
+	return fmt.Sprintf("%x-%x", f.UpdatedAt.UnixNano(), f.Size)
 }
@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// FileVersion - сохраненное предыдущее содержимое файла
+type FileVersion struct {
+	Filename    string
+	Number      int64 // Номера растут с каждой перезаписью, начиная с 1
+	Size        int64
+	Checksum    string
+	ContentType string
+	Uploader    string
+	UpdatedAt   time.Time // Когда это содержимое было загружено
+	ArchivedAt  time.Time // Когда его заменила новая версия
+	Image       *ImageInfo
+	Path        string
+}
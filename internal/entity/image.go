@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// ImageInfo - свойства изображения, извлекаемые один раз при загрузке
+type ImageInfo struct {
+	Width      int
+	Height     int
+	Format     string // jpeg, png, gif или webp
+	ColorModel string // ycbcr, rgba, gray, cmyk, paletted и т.д.
+
+	// Поля EXIF, нулевые значения - поле не задано
+	TakenAt     time.Time // Время съемки
+	Orientation int       // 1-8, как хранить пиксели при показе
+	CameraModel string
+}
@@ -0,0 +1,11 @@
+package entity
+
+// Rendition - вариант изображения, полученный преобразованием исходного файла
+type Rendition struct {
+	Filename    string // Имя исходного файла
+	Width       int
+	Height      int
+	ContentType string
+	Size        int64  // Размер результата в байтах
+	Checksum    string // SHA-256 исходного содержимого
+}
@@ -0,0 +1,10 @@
+package entity
+
+// Thumbnail - уменьшенная копия изображения
+type Thumbnail struct {
+	Filename    string // Имя исходного файла
+	MaxSize     int    // Ограничение большей стороны в пикселях
+	ContentType string
+	Size        int64  // Размер превью в байтах
+	Checksum    string // SHA-256 исходного содержимого
+}
@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// TrashItem - удаленный файл, который еще можно восстановить
+type TrashItem struct {
+	ID          string
+	Filename    string // Имя, под которым файл был удален
+	Size        int64
+	Checksum    string
+	ContentType string
+	DeletedAt   time.Time
+}
@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+type UploadSession struct {
+	ID            string
+	Filename      string
+	Size          int64  // Ожидаемый размер, 0 - неизвестен
+	Checksum      string // Ожидаемый SHA-256, проверяется при завершении
+	CommittedSize int64
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
@@ -0,0 +1,168 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	// Защита от "декомпрессионных бомб": маленький файл, который
+	// разворачивается в гигабайты пикселей
+	ErrTooManyPixels = errors.New("image has too many pixels")
+)
+
+// Форматы, которые умеет декодировать пакет
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatGIF  = "gif"
+	FormatWebP = "webp"
+)
+
+// DefaultJPEGQuality - качество JPEG, если оно не задано явно
+const DefaultJPEGQuality = 85
+
+var contentTypes = map[string]string{
+	FormatJPEG: "image/jpeg",
+	FormatPNG:  "image/png",
+	FormatGIF:  "image/gif",
+	FormatWebP: "image/webp",
+}
+
+// ContentType возвращает MIME-тип формата или пустую строку
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// FormatByContentType возвращает формат по MIME-типу или пустую строку,
+// если такие изображения не декодируются
+func FormatByContentType(contentType string) string {
+	for format, known := range contentTypes {
+		if known == contentType {
+			return format
+		}
+	}
+	return ""
+}
+
+// Decode декодирует изображение, предварительно проверив по заголовку, что
+// в нем не больше maxPixels пикселей (0 - без ограничения). Заголовок и
+// само изображение читаются из r дважды, поэтому нужен io.ReadSeeker
+func Decode(r io.ReadSeeker, maxPixels int64) (image.Image, string, error) {
+	config, format, err := image.DecodeConfig(r)
+	if errors.Is(err, image.ErrFormat) {
+		return nil, "", ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if pixels := int64(config.Width) * int64(config.Height); maxPixels > 0 && pixels > maxPixels {
+		return nil, "", fmt.Errorf("%w: %dx%d, limit %d", ErrTooManyPixels, config.Width, config.Height, maxPixels)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+	img, format, err := image.Decode(r)
+	if err != nil {
+		return nil, "", err
+	}
+	return img, format, nil
+}
+
+// Fit уменьшает изображение так, чтобы обе стороны были не больше maxSize,
+// сохраняя пропорции. Изображения меньше maxSize не увеличиваются
+func Fit(img image.Image, maxSize int) image.Image {
+	return ScaleDown(img, maxSize, maxSize)
+}
+
+// ScaleDown уменьшает изображение, сохраняя пропорции, чтобы ширина была не
+// больше width, а высота - не больше height. Нулевое ограничение не действует.
+// Изображение не увеличивается
+func ScaleDown(img image.Image, width, height int) image.Image {
+	bounds := img.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	if (width == 0 || srcWidth <= width) && (height == 0 || srcHeight <= height) {
+		return img
+	}
+
+	// Сторона, которая сильнее выходит за ограничение, задает масштаб
+	if height == 0 || width > 0 && srcWidth*height >= srcHeight*width {
+		height = max(1, srcHeight*width/srcWidth)
+	} else {
+		width = max(1, srcWidth*height/srcHeight)
+	}
+	return Resize(img, width, height)
+}
+
+// CropToAspect обрезает изображение по центру до соотношения сторон
+// aspectWidth:aspectHeight
+func CropToAspect(img image.Image, aspectWidth, aspectHeight int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width*aspectHeight > height*aspectWidth {
+		width = max(1, height*aspectWidth/aspectHeight)
+	} else {
+		height = max(1, width*aspectHeight/aspectWidth)
+	}
+	if width == bounds.Dx() && height == bounds.Dy() {
+		return img
+	}
+
+	origin := bounds.Min.Add(image.Pt((bounds.Dx()-width)/2, (bounds.Dy()-height)/2))
+	rect := image.Rectangle{Min: origin, Max: origin.Add(image.Pt(width, height))}
+	// Все декодеры стандартной библиотеки возвращают типы с SubImage,
+	// поэтому пиксели обычно не копируются
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Copy(dst, image.Point{}, img, rect, draw.Src, nil)
+	return dst
+}
+
+// Resize масштабирует изображение до width x height
+func Resize(img image.Image, width, height int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// Encode кодирует изображение в формат format. quality используется только
+// для JPEG, 0 - DefaultJPEGQuality. WebP только декодируется
+func Encode(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case FormatJPEG:
+		if quality == 0 {
+			quality = DefaultJPEGQuality
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case FormatPNG:
+		return png.Encode(w, img)
+	case FormatGIF:
+		return gif.Encode(w, img, nil)
+	default:
+		return fmt.Errorf("%w: cannot encode %q", ErrUnsupportedFormat, format)
+	}
+}
+
+// EncodeBytes - Encode в память
+func EncodeBytes(img image.Image, format string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, img, format, quality); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
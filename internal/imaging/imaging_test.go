@@ -0,0 +1,83 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	data := testPNG(t, 40, 20)
+
+	img, format, err := Decode(bytes.NewReader(data), 0)
+	require.NoError(t, err)
+	require.Equal(t, FormatPNG, format)
+	require.Equal(t, image.Rect(0, 0, 40, 20), img.Bounds())
+
+	_, _, err = Decode(bytes.NewReader(data), 799)
+	require.ErrorIs(t, err, ErrTooManyPixels)
+
+	_, _, err = Decode(strings.NewReader("not an image"), 0)
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestFit(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 100))
+	require.Equal(t, image.Rect(0, 0, 128, 32), Fit(img, 128).Bounds())
+
+	tall := image.NewRGBA(image.Rect(0, 0, 100, 400))
+	require.Equal(t, image.Rect(0, 0, 32, 128), Fit(tall, 128).Bounds())
+
+	// Маленькие изображения не увеличиваются
+	require.Same(t, img, Fit(img, 1000))
+
+	data, err := EncodeBytes(Fit(img, 128), FormatJPEG, 0)
+	require.NoError(t, err)
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, FormatJPEG, format)
+
+	_, err = EncodeBytes(img, FormatWebP, 0)
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestScaleDown(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+
+	require.Equal(t, image.Rect(0, 0, 100, 50), ScaleDown(img, 100, 0).Bounds())
+	require.Equal(t, image.Rect(0, 0, 200, 100), ScaleDown(img, 0, 100).Bounds())
+	// В рамку 300x100 изображение вписывается по высоте
+	require.Equal(t, image.Rect(0, 0, 200, 100), ScaleDown(img, 300, 100).Bounds())
+	require.Same(t, img, ScaleDown(img, 800, 0))
+	require.Same(t, img, ScaleDown(img, 0, 0))
+}
+
+func TestCropToAspect(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+
+	square := CropToAspect(img, 1, 1)
+	require.Equal(t, image.Rect(100, 0, 300, 200), square.Bounds())
+
+	wide := CropToAspect(img, 16, 9)
+	require.Equal(t, image.Rect(22, 0, 377, 200), wide.Bounds())
+
+	portrait := CropToAspect(img, 1, 2)
+	require.Equal(t, image.Rect(150, 0, 250, 200), portrait.Bounds())
+
+	require.Same(t, img, CropToAspect(img, 2, 1))
+}
@@ -0,0 +1,89 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// Inspect читает свойства изображения по началу файла: размеры, формат и
+// цветовую модель из заголовка, время съемки, ориентацию и модель камеры из
+// EXIF. Битый или отсутствующий EXIF не считается ошибкой
+func Inspect(header []byte) (*entity.ImageInfo, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(header))
+	if errors.Is(err, image.ErrFormat) {
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info := &entity.ImageInfo{
+		Width:      config.Width,
+		Height:     config.Height,
+		Format:     format,
+		ColorModel: colorModelName(config.ColorModel),
+	}
+	if format == FormatJPEG {
+		readEXIF(header, info)
+	}
+	return info, nil
+}
+
+func readEXIF(header []byte, info *entity.ImageInfo) {
+	x, err := exif.Decode(bytes.NewReader(header))
+	if err != nil {
+		return
+	}
+	if takenAt, err := x.DateTime(); err == nil {
+		info.TakenAt = takenAt
+	}
+	if tag, err := x.Get(exif.Orientation); err == nil {
+		if orientation, err := tag.Int(0); err == nil && orientation >= 1 && orientation <= 8 {
+			info.Orientation = orientation
+		}
+	}
+	if tag, err := x.Get(exif.Model); err == nil {
+		if model, err := tag.StringVal(); err == nil {
+			info.CameraModel = strings.TrimSpace(strings.TrimRight(model, "\x00"))
+		}
+	}
+}
+
+func colorModelName(model color.Model) string {
+	// Palette - срез, поэтому проверяется до сравнения с моделями
+	if _, ok := model.(color.Palette); ok {
+		return "paletted"
+	}
+	switch model {
+	case color.YCbCrModel:
+		return "ycbcr"
+	case color.NYCbCrAModel:
+		return "nycbcra"
+	case color.CMYKModel:
+		return "cmyk"
+	case color.RGBAModel:
+		return "rgba"
+	case color.RGBA64Model:
+		return "rgba64"
+	case color.NRGBAModel:
+		return "nrgba"
+	case color.NRGBA64Model:
+		return "nrgba64"
+	case color.GrayModel:
+		return "gray"
+	case color.Gray16Model:
+		return "gray16"
+	case color.AlphaModel:
+		return "alpha"
+	case color.Alpha16Model:
+		return "alpha16"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,117 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Широта в GPS IFD тестового EXIF: 55°45'12.34"
+var testLatitude = []uint32{55, 1, 45, 1, 1234, 100}
+
+// testEXIF собирает EXIF в формате TIFF с моделью камеры, ориентацией,
+// временем съемки и, если withGPS, координатами
+func testEXIF(model string, orientation uint16, takenAt string, withGPS bool) []byte {
+	le := binary.LittleEndian
+	entry := func(b []byte, tag, typ uint16, count, value uint32) []byte {
+		b = le.AppendUint16(b, tag)
+		b = le.AppendUint16(b, typ)
+		b = le.AppendUint32(b, count)
+		return le.AppendUint32(b, value)
+	}
+
+	// IFD0: Model, Orientation, указатели на Exif IFD и GPS IFD
+	entries := 3
+	if withGPS {
+		entries++
+	}
+	modelOffset := uint32(8 + 2 + 12*entries + 4)
+	exifOffset := modelOffset + uint32(len(model)+1)
+	takenAtOffset := exifOffset + 2 + 12 + 4
+	gpsOffset := takenAtOffset + uint32(len(takenAt)+1)
+
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = le.AppendUint16(tiff, uint16(entries))
+	tiff = entry(tiff, 0x0110, 2, uint32(len(model)+1), modelOffset)
+	tiff = entry(tiff, 0x0112, 3, 1, uint32(orientation))
+	tiff = entry(tiff, 0x8769, 4, 1, exifOffset)
+	if withGPS {
+		tiff = entry(tiff, 0x8825, 4, 1, gpsOffset)
+	}
+	tiff = le.AppendUint32(tiff, 0)
+	tiff = append(tiff, model...)
+	tiff = append(tiff, 0)
+
+	// Exif IFD: DateTimeOriginal
+	tiff = le.AppendUint16(tiff, 1)
+	tiff = entry(tiff, 0x9003, 2, uint32(len(takenAt)+1), takenAtOffset)
+	tiff = le.AppendUint32(tiff, 0)
+	tiff = append(tiff, takenAt...)
+	tiff = append(tiff, 0)
+
+	if withGPS {
+		// GPS IFD: GPSLatitudeRef со значением внутри записи и GPSLatitude
+		// из трех дробей после IFD
+		tiff = le.AppendUint16(tiff, 2)
+		tiff = entry(tiff, 0x0001, 2, 2, uint32('N'))
+		tiff = entry(tiff, 0x0002, 5, 3, gpsOffset+2+2*12+4)
+		tiff = le.AppendUint32(tiff, 0)
+		tiff = append(tiff, testLatitudeBytes()...)
+	}
+	return tiff
+}
+
+func testLatitudeBytes() []byte {
+	var b []byte
+	for _, v := range testLatitude {
+		b = binary.LittleEndian.AppendUint32(b, v)
+	}
+	return b
+}
+
+// testAPP1 оборачивает EXIF в сегмент JPEG
+func testAPP1(tiff []byte) []byte {
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func testJPEG(t *testing.T, width, height int, app1 []byte) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil))
+	data := buf.Bytes()
+	// APP1 вставляется сразу после SOI
+	return append(append([]byte{0xFF, 0xD8}, app1...), data[2:]...)
+}
+
+func TestInspect(t *testing.T) {
+	info, err := Inspect(testJPEG(t, 64, 48, testAPP1(testEXIF("Camera X", 6, "2024:05:01 10:20:30", true))))
+	require.NoError(t, err)
+	require.Equal(t, 64, info.Width)
+	require.Equal(t, 48, info.Height)
+	require.Equal(t, FormatJPEG, info.Format)
+	require.Equal(t, "ycbcr", info.ColorModel)
+	require.Equal(t, 6, info.Orientation)
+	require.Equal(t, "Camera X", info.CameraModel)
+	require.Equal(t, "2024-05-01 10:20:30", info.TakenAt.Format(time.DateTime))
+
+	info, err = Inspect(testJPEG(t, 8, 8, nil))
+	require.NoError(t, err)
+	require.Zero(t, info.Orientation)
+	require.True(t, info.TakenAt.IsZero())
+
+	info, err = Inspect(testPNG(t, 40, 20))
+	require.NoError(t, err)
+	require.Equal(t, FormatPNG, info.Format)
+	require.Equal(t, 40, info.Width)
+	require.NotEmpty(t, info.ColorModel)
+
+	_, err = Inspect([]byte("plain text"))
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+}
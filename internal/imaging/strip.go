@@ -0,0 +1,441 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"slices"
+	"strings"
+)
+
+// StripPolicy - какие метаданные удаляются из JPEG и PNG при загрузке
+type StripPolicy int
+
+const (
+	StripNone     StripPolicy = iota
+	StripLocation             // Координаты GPS из EXIF и XMP
+	StripAll                  // Все метаданные, кроме ориентации и цветового профиля
+)
+
+// StripPolicies - политики по названиям в конфигурации
+var StripPolicies = map[string]StripPolicy{
+	"none":     StripNone,
+	"location": StripLocation,
+	"all":      StripAll,
+}
+
+// Что удалено из файла. Значения возвращаются клиенту в ответе на загрузку
+const (
+	StrippedGPS          = "exif_gps"      // GPS-теги EXIF, остальной EXIF сохранен
+	StrippedEXIF         = "exif"          // EXIF целиком
+	StrippedXMP          = "xmp"           // Сегмент XMP
+	StrippedIPTC         = "iptc"          // JPEG APP13
+	StrippedComment      = "comment"       // JPEG COM
+	StrippedAppSegment   = "app_segment"   // Прочие сегменты APPn в JPEG
+	StrippedMPF          = "mpf"           // Дополнительные кадры MPF в JPEG
+	StrippedText         = "text"          // Текстовые чанки PNG
+	StrippedTime         = "time"          // PNG tIME
+	StrippedTrailingData = "trailing_data" // Данные после конца изображения
+)
+
+var ErrMalformedImage = errors.New("malformed image")
+
+var (
+	jpegSignature = []byte{0xFF, 0xD8}
+	pngSignature  = []byte("\x89PNG\r\n\x1a\n")
+
+	exifHeader         = []byte("Exif\x00\x00")
+	xmpHeader          = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	xmpExtensionHeader = []byte("http://ns.adobe.com/xmp/extension/\x00")
+	mpfHeader          = []byte("MPF\x00")
+	iccHeader          = []byte("ICC_PROFILE\x00")
+)
+
+// Чанки PNG с метаданными читаются в память целиком, больше - удаляются
+// не читая
+const maxMetadataChunk = 16 << 20
+
+// StripMetadata копирует изображение из src в dst, удаляя метаданные по
+// политике, и возвращает, что было удалено. JPEG и PNG разбираются по
+// сегментам без декодирования пикселей, остальные данные копируются как есть.
+// ErrMalformedImage означает, что структуру файла не удалось разобрать и
+// удалить метаданные нельзя
+func StripMetadata(dst io.Writer, src io.Reader, policy StripPolicy) ([]string, error) {
+	if policy == StripNone {
+		_, err := io.Copy(dst, src)
+		return nil, err
+	}
+
+	r := bufio.NewReaderSize(src, 64<<10)
+	// Короткий файл - не изображение, ошибку вернет копирование
+	head, _ := r.Peek(len(pngSignature))
+
+	var err error
+	s := &stripper{r: r, w: dst, policy: policy}
+	switch {
+	case bytes.HasPrefix(head, jpegSignature):
+		err = s.jpeg()
+	case bytes.Equal(head, pngSignature):
+		err = s.png()
+	default:
+		_, err = io.Copy(dst, r)
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		err = fmt.Errorf("%w: unexpected end of data", ErrMalformedImage)
+	}
+	return s.stripped, err
+}
+
+type stripper struct {
+	r        *bufio.Reader
+	w        io.Writer
+	policy   StripPolicy
+	stripped []string
+}
+
+func (s *stripper) report(item string) {
+	if item != "" && !slices.Contains(s.stripped, item) {
+		s.stripped = append(s.stripped, item)
+	}
+}
+
+func (s *stripper) write(data ...[]byte) error {
+	for _, b := range data {
+		if _, err := s.w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropTrailing отбрасывает данные после конца изображения: в них могут
+// быть дополнительные кадры или чужие метаданные
+func (s *stripper) dropTrailing() error {
+	n, err := io.Copy(io.Discard, s.r)
+	if n > 0 {
+		s.report(StrippedTrailingData)
+	}
+	return err
+}
+
+const (
+	jpegSOS = 0xDA
+	jpegEOI = 0xD9
+	jpegCOM = 0xFE
+)
+
+func (s *stripper) jpeg() error {
+	if _, err := io.CopyN(s.w, s.r, int64(len(jpegSignature))); err != nil {
+		return err
+	}
+	marker, err := s.readMarker()
+	for err == nil {
+		switch {
+		case marker == jpegEOI:
+			if err := s.write([]byte{0xFF, marker}); err != nil {
+				return err
+			}
+			return s.dropTrailing()
+		case marker >= 0xD0 && marker <= 0xD7 || marker == 0x01:
+			// RSTn и TEM без длины
+			err = s.write([]byte{0xFF, marker})
+		case marker == jpegSOS:
+			if err = s.copySegment(marker); err == nil {
+				// Сжатые данные заканчиваются следующим маркером
+				marker, err = s.copyScan()
+				continue
+			}
+		default:
+			err = s.jpegSegment(marker)
+		}
+		if err == nil {
+			marker, err = s.readMarker()
+		}
+	}
+	return err
+}
+
+func (s *stripper) readMarker() (byte, error) {
+	b, err := s.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != 0xFF {
+		return 0, fmt.Errorf("%w: expected JPEG marker, got 0x%02x", ErrMalformedImage, b)
+	}
+	// Перед маркером может быть сколько угодно заполняющих 0xFF
+	for b == 0xFF {
+		if b, err = s.r.ReadByte(); err != nil {
+			return 0, err
+		}
+	}
+	return b, nil
+}
+
+// readLength читает длину сегмента и возвращает длину его данных
+func (s *stripper) readLength() (int, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(s.r, length[:]); err != nil {
+		return 0, err
+	}
+	n := int(binary.BigEndian.Uint16(length[:]))
+	if n < 2 {
+		return 0, fmt.Errorf("%w: JPEG segment length %d", ErrMalformedImage, n)
+	}
+	return n - 2, nil
+}
+
+func (s *stripper) copySegment(marker byte) error {
+	n, err := s.readLength()
+	if err != nil {
+		return err
+	}
+	if err := s.write(jpegSegmentHeader(marker, n)); err != nil {
+		return err
+	}
+	_, err = io.CopyN(s.w, s.r, int64(n))
+	return err
+}
+
+func jpegSegmentHeader(marker byte, n int) []byte {
+	return binary.BigEndian.AppendUint16([]byte{0xFF, marker}, uint16(n+2))
+}
+
+// copyScan копирует сжатые данные скана и возвращает маркер, которым они
+// закончились. 0xFF внутри данных экранируется нулем, RSTn остаются в скане
+func (s *stripper) copyScan() (byte, error) {
+	for {
+		chunk, err := s.r.ReadSlice(0xFF)
+		if err == bufio.ErrBufferFull {
+			if err := s.write(chunk); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if err := s.write(chunk[:len(chunk)-1]); err != nil {
+			return 0, err
+		}
+
+		next, err := s.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case next == 0x00 || next >= 0xD0 && next <= 0xD7:
+			if err := s.write([]byte{0xFF, next}); err != nil {
+				return 0, err
+			}
+		case next == 0xFF:
+			// Заполняющий байт перед маркером
+			s.r.UnreadByte()
+		default:
+			return next, nil
+		}
+	}
+}
+
+// jpegSegment копирует сегмент, удаляя или изменяя сегменты с метаданными.
+// Таблицы и заголовки кадра копируются потоком, APPn и COM не больше 64 КиБ
+// и читаются целиком
+func (s *stripper) jpegSegment(marker byte) error {
+	if !(marker >= 0xE0 && marker <= 0xEF || marker == jpegCOM) {
+		return s.copySegment(marker)
+	}
+
+	n, err := s.readLength()
+	if err != nil {
+		return err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(s.r, payload); err != nil {
+		return err
+	}
+
+	payload, item := s.filterJPEGSegment(marker, payload)
+	s.report(item)
+	if payload == nil {
+		return nil
+	}
+	return s.write(jpegSegmentHeader(marker, len(payload)), payload)
+}
+
+// filterJPEGSegment возвращает новое содержимое сегмента (nil - удалить) и
+// что из него удалено
+func (s *stripper) filterJPEGSegment(marker byte, payload []byte) ([]byte, string) {
+	switch marker {
+	case 0xE0, 0xEE:
+		// JFIF и Adobe нужны декодерам для правильных цветов
+		return payload, ""
+	case 0xE1:
+		switch {
+		case bytes.HasPrefix(payload, exifHeader):
+			tiff, item := s.filterEXIF(payload[len(exifHeader):])
+			if tiff == nil {
+				return nil, item
+			}
+			return slices.Concat(exifHeader, tiff), item
+		case bytes.HasPrefix(payload, xmpHeader), bytes.HasPrefix(payload, xmpExtensionHeader):
+			// Расширенный XMP разбит на части произвольно, и координаты могут
+			// оказаться на границе частей, поэтому он удаляется всегда
+			if s.policy == StripAll || bytes.HasPrefix(payload, xmpExtensionHeader) || bytes.Contains(payload, []byte("GPS")) {
+				return nil, StrippedXMP
+			}
+			return payload, ""
+		}
+	case 0xE2:
+		switch {
+		case bytes.HasPrefix(payload, iccHeader):
+			return payload, ""
+		case bytes.HasPrefix(payload, mpfHeader):
+			// Дополнительные кадры лежат после конца основного изображения со
+			// своим EXIF и отбрасываются, поэтому ссылки на них тоже не нужны
+			return nil, StrippedMPF
+		}
+	case 0xED:
+		if s.policy == StripAll {
+			return nil, StrippedIPTC
+		}
+	case jpegCOM:
+		if s.policy == StripAll {
+			return nil, StrippedComment
+		}
+		return payload, ""
+	}
+	if s.policy == StripAll {
+		return nil, StrippedAppSegment
+	}
+	return payload, ""
+}
+
+// filterEXIF возвращает EXIF без координат или, при StripAll, только с
+// ориентацией, чтобы изображение не повернулось. nil - удалить EXIF целиком
+func (s *stripper) filterEXIF(tiff []byte) ([]byte, string) {
+	if s.policy == StripAll {
+		return orientationEXIF(tiff), StrippedEXIF
+	}
+	removed, err := stripGPS(tiff)
+	if err != nil {
+		// Битый EXIF не проверить на координаты
+		return nil, StrippedEXIF
+	}
+	if removed {
+		return tiff, StrippedGPS
+	}
+	return tiff, ""
+}
+
+func (s *stripper) png() error {
+	if _, err := io.CopyN(s.w, s.r, int64(len(pngSignature))); err != nil {
+		return err
+	}
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(s.r, header); err != nil {
+			return err
+		}
+		length := binary.BigEndian.Uint32(header)
+		if length > 1<<31-1 {
+			return fmt.Errorf("%w: PNG chunk length %d", ErrMalformedImage, length)
+		}
+		chunkType := string(header[4:])
+
+		switch chunkType {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+			data, complete, err := s.readChunk(length)
+			if err != nil {
+				return err
+			}
+			data, item := s.filterPNGChunk(chunkType, data, complete)
+			s.report(item)
+			if data != nil {
+				if err := s.writeChunk(chunkType, data); err != nil {
+					return err
+				}
+			}
+		default:
+			if err := s.write(header); err != nil {
+				return err
+			}
+			// Данные и CRC
+			if _, err := io.CopyN(s.w, s.r, int64(length)+4); err != nil {
+				return err
+			}
+		}
+
+		if chunkType == "IEND" {
+			return s.dropTrailing()
+		}
+	}
+}
+
+// readChunk читает данные чанка без CRC. Слишком большой чанк пропускается,
+// и complete = false
+func (s *stripper) readChunk(length uint32) ([]byte, bool, error) {
+	if length > maxMetadataChunk {
+		_, err := io.CopyN(io.Discard, s.r, int64(length)+4)
+		return nil, false, err
+	}
+	data := make([]byte, length+4)
+	if _, err := io.ReadFull(s.r, data); err != nil {
+		return nil, false, err
+	}
+	return data[:length], true, nil
+}
+
+func (s *stripper) writeChunk(chunkType string, data []byte) error {
+	header := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	header = append(header, chunkType...)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	return s.write(header, data, crc.Sum(nil))
+}
+
+// Текстовые чанки ImageMagick, в которых EXIF и XMP хранятся в hex
+var rawProfiles = map[string]string{
+	"raw profile type exif": StrippedEXIF,
+	"raw profile type app1": StrippedEXIF,
+	"raw profile type xmp":  StrippedXMP,
+}
+
+// filterPNGChunk возвращает новые данные чанка (nil - удалить) и что из него
+// удалено. Недочитанный чанк (complete = false) нельзя проверить или
+// изменить, поэтому он удаляется
+func (s *stripper) filterPNGChunk(chunkType string, data []byte, complete bool) ([]byte, string) {
+	switch chunkType {
+	case "eXIf":
+		if !complete {
+			return nil, StrippedEXIF
+		}
+		return s.filterEXIF(data)
+	case "tIME":
+		if s.policy == StripAll {
+			return nil, StrippedTime
+		}
+		return data, ""
+	}
+
+	keyword, _, _ := bytes.Cut(data, []byte{0})
+	if string(keyword) == "XML:com.adobe.xmp" {
+		// Сжатый текст не проверить на координаты
+		compressed := chunkType == "zTXt" || chunkType == "iTXt" && len(data) > len(keyword)+1 && data[len(keyword)+1] == 1
+		if s.policy == StripAll || !complete || compressed || bytes.Contains(data, []byte("GPS")) {
+			return nil, StrippedXMP
+		}
+		return data, ""
+	}
+	if item, ok := rawProfiles[strings.ToLower(string(keyword))]; ok {
+		return nil, item
+	}
+	if s.policy == StripAll || !complete {
+		return nil, StrippedText
+	}
+	return data, ""
+}
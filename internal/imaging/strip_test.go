@@ -0,0 +1,114 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"slices"
+	"testing"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/stretchr/testify/require"
+)
+
+func strip(t *testing.T, data []byte, policy StripPolicy) ([]byte, []string) {
+	var out bytes.Buffer
+	stripped, err := StripMetadata(&out, bytes.NewReader(data), policy)
+	require.NoError(t, err)
+	return out.Bytes(), stripped
+}
+
+func testSegment(marker byte, payload []byte) []byte {
+	segment := binary.BigEndian.AppendUint16([]byte{0xFF, marker}, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func TestStripMetadata_JPEG(t *testing.T) {
+	xmp := testSegment(0xE1, append([]byte("http://ns.adobe.com/xap/1.0/\x00"), `<x:xmpmeta exif:GPSLatitude="55,45N"/>`...))
+	comment := testSegment(0xFE, []byte("holiday"))
+	segments := slices.Concat(testAPP1(testEXIF("Camera X", 6, "2024:05:01 10:20:30", true)), xmp, comment)
+	original := append(testJPEG(t, 32, 16, segments), "trailer"...)
+
+	out, stripped := strip(t, original, StripLocation)
+	require.Equal(t, []string{StrippedGPS, StrippedXMP, StrippedTrailingData}, stripped)
+	require.False(t, bytes.Contains(out, testLatitudeBytes()), "GPS values must be erased")
+	require.True(t, bytes.Contains(out, []byte("holiday")))
+
+	x, err := exif.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+	_, _, err = x.LatLong()
+	require.Error(t, err)
+	info, err := Inspect(out)
+	require.NoError(t, err)
+	require.Equal(t, "Camera X", info.CameraModel)
+	require.Equal(t, 6, info.Orientation)
+	require.False(t, info.TakenAt.IsZero())
+	_, _, err = image.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+
+	out, stripped = strip(t, original, StripAll)
+	require.Equal(t, []string{StrippedEXIF, StrippedXMP, StrippedComment, StrippedTrailingData}, stripped)
+	require.False(t, bytes.Contains(out, []byte("holiday")))
+	// Остается только ориентация, иначе фото с телефона повернется
+	info, err = Inspect(out)
+	require.NoError(t, err)
+	require.Equal(t, 6, info.Orientation)
+	require.Empty(t, info.CameraModel)
+	require.True(t, info.TakenAt.IsZero())
+	_, _, err = image.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+
+	// Без метаданных изображение не меняется
+	plain := testJPEG(t, 8, 8, nil)
+	out, stripped = strip(t, plain, StripAll)
+	require.Empty(t, stripped)
+	require.Equal(t, plain, out)
+
+	_, err = StripMetadata(&bytes.Buffer{}, bytes.NewReader(original[:40]), StripLocation)
+	require.ErrorIs(t, err, ErrMalformedImage)
+}
+
+func testChunk(chunkType string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func TestStripMetadata_PNG(t *testing.T) {
+	png := testPNG(t, 16, 8)
+	// Чанки метаданных вставляются после IHDR
+	ihdrEnd := len(pngSignature) + 8 + 13 + 4
+	original := slices.Concat(png[:ihdrEnd],
+		testChunk("eXIf", testEXIF("Camera X", 6, "2024:05:01 10:20:30", true)),
+		testChunk("tEXt", []byte("Comment\x00holiday")),
+		testChunk("iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00<x:xmpmeta exif:GPSLatitude=\"55,45N\"/>")),
+		testChunk("tIME", []byte{0x07, 0xE8, 5, 1, 10, 20, 30}),
+		png[ihdrEnd:])
+
+	out, stripped := strip(t, original, StripLocation)
+	require.Equal(t, []string{StrippedGPS, StrippedXMP}, stripped)
+	require.False(t, bytes.Contains(out, testLatitudeBytes()))
+	require.True(t, bytes.Contains(out, []byte("holiday")))
+	_, _, err := image.Decode(bytes.NewReader(out))
+	require.NoError(t, err, "chunk CRCs must stay valid")
+
+	out, stripped = strip(t, original, StripAll)
+	require.Equal(t, []string{StrippedEXIF, StrippedText, StrippedXMP, StrippedTime}, stripped)
+	require.False(t, bytes.Contains(out, []byte("holiday")))
+	require.True(t, bytes.Contains(out, []byte("eXIf")), "orientation is kept")
+	_, _, err = image.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+}
+
+func TestStripMetadata_Passthrough(t *testing.T) {
+	data := []byte("not an image at all")
+	out, stripped := strip(t, data, StripAll)
+	require.Empty(t, stripped)
+	require.Equal(t, data, out)
+
+	out, stripped = strip(t, nil, StripAll)
+	require.Empty(t, stripped)
+	require.Empty(t, out)
+}
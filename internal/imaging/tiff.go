@@ -0,0 +1,152 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// EXIF хранится в формате TIFF: заголовок с порядком байт и цепочка IFD,
+// каждая запись которых - 12 байт: тег, тип, количество значений и сами
+// значения или, если они не помещаются в 4 байта, смещение до них
+
+const (
+	tagOrientation = 0x0112
+	tagGPSInfo     = 0x8825
+)
+
+// Размеры значений TIFF по типам
+var tiffTypeSizes = map[uint16]uint64{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+type tiff struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func parseTIFF(data []byte) (*tiff, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: TIFF header too short", ErrMalformedImage)
+	}
+	t := &tiff{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("%w: unknown TIFF byte order", ErrMalformedImage)
+	}
+	if t.order.Uint16(data[2:]) != 42 {
+		return nil, fmt.Errorf("%w: bad TIFF magic", ErrMalformedImage)
+	}
+	return t, nil
+}
+
+// ifd возвращает смещение первой записи IFD и число записей
+func (t *tiff) ifd(offset uint32) (int, int, error) {
+	if uint64(offset)+2 > uint64(len(t.data)) {
+		return 0, 0, fmt.Errorf("%w: IFD offset %d out of range", ErrMalformedImage, offset)
+	}
+	start := int(offset) + 2
+	count := int(t.order.Uint16(t.data[offset:]))
+	if start+12*count+4 > len(t.data) {
+		return 0, 0, fmt.Errorf("%w: IFD at %d out of range", ErrMalformedImage, offset)
+	}
+	return start, count, nil
+}
+
+func (t *tiff) ifd0() (int, int, error) {
+	return t.ifd(t.order.Uint32(t.data[4:]))
+}
+
+// stripGPS удаляет указатель на GPS IFD из IFD0 и затирает нулями саму GPS
+// IFD с ее значениями: без указателя они не читаются, но остаются в байтах
+// файла. Смещения остальных данных не меняются
+func stripGPS(data []byte) (bool, error) {
+	t, err := parseTIFF(data)
+	if err != nil {
+		return false, err
+	}
+	start, count, err := t.ifd0()
+	if err != nil {
+		return false, err
+	}
+
+	for i := range count {
+		entry := start + 12*i
+		if t.order.Uint16(data[entry:]) != tagGPSInfo {
+			continue
+		}
+		if err := t.clearIFD(t.order.Uint32(data[entry+8:])); err != nil {
+			return false, err
+		}
+		// Записи после удаленной и смещение следующей IFD сдвигаются на ее место
+		end := start + 12*count + 4
+		copy(data[entry:], data[entry+12:end])
+		clear(data[end-12 : end])
+		t.order.PutUint16(data[start-2:], uint16(count-1))
+		return true, nil
+	}
+	return false, nil
+}
+
+func (t *tiff) clearIFD(offset uint32) error {
+	start, count, err := t.ifd(offset)
+	if err != nil {
+		return err
+	}
+	for i := range count {
+		entry := start + 12*i
+		typeSize, ok := tiffTypeSizes[t.order.Uint16(t.data[entry+2:])]
+		if !ok {
+			return fmt.Errorf("%w: unknown TIFF type", ErrMalformedImage)
+		}
+		size := typeSize * uint64(t.order.Uint32(t.data[entry+4:]))
+		if size <= 4 {
+			continue
+		}
+		valueOffset := uint64(t.order.Uint32(t.data[entry+8:]))
+		if valueOffset+size > uint64(len(t.data)) {
+			return fmt.Errorf("%w: TIFF value out of range", ErrMalformedImage)
+		}
+		clear(t.data[valueOffset : valueOffset+size])
+	}
+	clear(t.data[start-2 : start+12*count+4])
+	return nil
+}
+
+// orientationEXIF возвращает EXIF из одного тега ориентации или nil, если
+// ориентация не задана или обычная
+func orientationEXIF(data []byte) []byte {
+	t, err := parseTIFF(data)
+	if err != nil {
+		return nil
+	}
+	start, count, err := t.ifd0()
+	if err != nil {
+		return nil
+	}
+
+	for i := range count {
+		entry := start + 12*i
+		if t.order.Uint16(data[entry:]) != tagOrientation || t.order.Uint16(data[entry+2:]) != 3 {
+			continue
+		}
+		orientation := t.order.Uint16(data[entry+8:])
+		if orientation < 2 || orientation > 8 {
+			return nil
+		}
+
+		be := binary.BigEndian
+		out := []byte("MM\x00\x2a\x00\x00\x00\x08")
+		out = be.AppendUint16(out, 1)
+		out = be.AppendUint16(out, tagOrientation)
+		out = be.AppendUint16(out, 3)
+		out = be.AppendUint32(out, 1)
+		out = be.AppendUint16(out, orientation)
+		out = be.AppendUint16(out, 0)
+		return be.AppendUint32(out, 0)
+	}
+	return nil
+}
@@ -2,53 +2,101 @@ package middleware
 
 import (
 	"context"
+	"path"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+const (
+	uploadFileMethod   = "/file_service.FileService/UploadFile"
+	downloadFileMethod = "/file_service.FileService/DownloadFile"
+	listFilesMethod    = "/file_service.FileService/ListFiles"
+	streamFilesMethod  = "/file_service.FileService/StreamFiles"
+	deleteFileMethod   = "/file_service.FileService/DeleteFile"
+	renameFileMethod   = "/file_service.FileService/RenameFile"
+	copyFileMethod     = "/file_service.FileService/CopyFile"
+
+	listFileVersionsMethod   = "/file_service.FileService/ListFileVersions"
+	restoreFileVersionMethod = "/file_service.FileService/RestoreFileVersion"
+	pruneFileVersionsMethod  = "/file_service.FileService/PruneFileVersions"
+
+	listTrashMethod   = "/file_service.FileService/ListTrash"
+	restoreFileMethod = "/file_service.FileService/RestoreFile"
+	emptyTrashMethod  = "/file_service.FileService/EmptyTrash"
+
+	createUploadSessionMethod   = "/file_service.FileService/CreateUploadSession"
+	uploadSessionChunkMethod    = "/file_service.FileService/UploadSessionChunk"
+	finalizeUploadSessionMethod = "/file_service.FileService/FinalizeUploadSession"
+
+	getThumbnailMethod   = "/file_service.FileService/GetThumbnail"
+	transformImageMethod = "/file_service.FileService/TransformImage"
+)
+
 type ConcurrencyLimiter struct {
 	uploadDownloadSem chan struct{}
 	listSem           chan struct{}
+	streamSem         chan struct{}
 }
 
-func NewConcurrencyLimiter(uploadDownloadLimit, listLimit int) *ConcurrencyLimiter {
+func NewConcurrencyLimiter(uploadDownloadLimit, listLimit, streamLimit int) *ConcurrencyLimiter {
 	return &ConcurrencyLimiter{
 		uploadDownloadSem: make(chan struct{}, uploadDownloadLimit),
 		listSem:           make(chan struct{}, listLimit),
+		streamSem:         make(chan struct{}, streamLimit),
+	}
+}
+
+// semaphore возвращает семафор, которым ограничивается метод, или nil,
+// если метод не ограничивается
+func (l *ConcurrencyLimiter) semaphore(fullMethod string) chan struct{} {
+	switch fullMethod {
+	case uploadFileMethod, downloadFileMethod, deleteFileMethod, renameFileMethod, copyFileMethod,
+		restoreFileVersionMethod, pruneFileVersionsMethod, restoreFileMethod, emptyTrashMethod,
+		createUploadSessionMethod, uploadSessionChunkMethod, finalizeUploadSessionMethod,
+		getThumbnailMethod, transformImageMethod:
+		return l.uploadDownloadSem
+	case listFilesMethod, listFileVersionsMethod, listTrashMethod:
+		return l.listSem
+	case streamFilesMethod:
+		// Обход всего хранилища держит соединение долго, поэтому у него свой лимит
+		return l.streamSem
+	default:
+		return nil
 	}
 }
 
 func (l *ConcurrencyLimiter) UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
-	if info.FullMethod != "/file_service.FileService/ListFiles" {
-		return handler(ctx, req) // Ограничения только для ListFiles
+	sem := l.semaphore(info.FullMethod)
+	if sem == nil {
+		return handler(ctx, req)
 	}
 
 	select {
-	case l.listSem <- struct{}{}:
-		defer func() { <-l.listSem }()
+	case sem <- struct{}{}:
+		defer func() { <-sem }()
 		return handler(ctx, req)
 	case <-ctx.Done():
 		return nil, status.FromContextError(ctx.Err()).Err()
 	default:
-		return nil, status.Errorf(codes.ResourceExhausted, "too many concurrent ListFiles requests")
+		return nil, status.Errorf(codes.ResourceExhausted, "too many concurrent %s requests", path.Base(info.FullMethod))
 	}
 }
 
 func (l *ConcurrencyLimiter) StreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
-	if info.FullMethod != "/file_service.FileService/UploadFile" &&
-		info.FullMethod != "/file_service.FileService/DownloadFile" {
-		return handler(srv, ss) // Ограничения только для UploadFile/DownloadFile
+	sem := l.semaphore(info.FullMethod)
+	if sem == nil {
+		return handler(srv, ss)
 	}
 
 	select {
-	case l.uploadDownloadSem <- struct{}{}:
-		defer func() { <-l.uploadDownloadSem }()
+	case sem <- struct{}{}:
+		defer func() { <-sem }()
 		return handler(srv, ss)
 	case <-ss.Context().Done():
 		return status.FromContextError(ss.Context().Err()).Err()
 	default:
-		return status.Errorf(codes.ResourceExhausted, "too many concurrent UploadFile/DownloadFile requests")
+		return status.Errorf(codes.ResourceExhausted, "too many concurrent %s requests", path.Base(info.FullMethod))
 	}
 }
@@ -25,7 +25,7 @@ func TestConcurrencyLimiter(t *testing.T) {
 	t.Run("Unary ListFiles limit", func(t *testing.T) {
 		const limit = 3
 		const requests = 5
-		limiter := middleware.NewConcurrencyLimiter(2, limit)
+		limiter := middleware.NewConcurrencyLimiter(2, limit, 1)
 
 		var wg sync.WaitGroup
 		var errCount int32
@@ -64,7 +64,7 @@ func TestConcurrencyLimiter(t *testing.T) {
 	t.Run("Stream UploadFile limit", func(t *testing.T) {
 		const limit = 2
 		const requests = 4
-		limiter := middleware.NewConcurrencyLimiter(limit, 3)
+		limiter := middleware.NewConcurrencyLimiter(limit, 3, 1)
 
 		var wg sync.WaitGroup
 		var errCount int32
@@ -101,7 +101,7 @@ func TestConcurrencyLimiter(t *testing.T) {
 	})
 
 	t.Run("Different methods use different limits", func(t *testing.T) {
-		limiter := middleware.NewConcurrencyLimiter(1, 1)
+		limiter := middleware.NewConcurrencyLimiter(1, 1, 1)
 
 		listDone := make(chan struct{})
 		go func() {
@@ -141,4 +141,87 @@ func TestConcurrencyLimiter(t *testing.T) {
 		close(listDone)
 		close(uploadDone)
 	})
+
+	t.Run("DeleteFile and CopyFile share upload slots", func(t *testing.T) {
+		limiter := middleware.NewConcurrencyLimiter(1, 10, 1)
+
+		uploadStarted := make(chan struct{})
+		uploadDone := make(chan struct{})
+		go func() {
+			limiter.StreamInterceptor(
+				nil,
+				&mockStream{ctx: context.Background()},
+				&grpc.StreamServerInfo{FullMethod: "/file_service.FileService/UploadFile"},
+				func(srv any, stream grpc.ServerStream) error {
+					close(uploadStarted)
+					<-uploadDone
+					return nil
+				},
+			)
+		}()
+		<-uploadStarted
+
+		for _, method := range []string{"DeleteFile", "CopyFile"} {
+			_, err := limiter.UnaryInterceptor(
+				context.Background(),
+				nil,
+				&grpc.UnaryServerInfo{FullMethod: "/file_service.FileService/" + method},
+				func(ctx context.Context, req any) (any, error) {
+					return nil, nil
+				},
+			)
+			if status.Code(err) != codes.ResourceExhausted {
+				t.Errorf("Expected %s to be rejected, got: %v", method, err)
+			}
+		}
+
+		close(uploadDone)
+	})
+
+	t.Run("StreamFiles has its own slots", func(t *testing.T) {
+		limiter := middleware.NewConcurrencyLimiter(1, 1, 1)
+
+		streamStarted := make(chan struct{})
+		streamDone := make(chan struct{})
+		go func() {
+			limiter.StreamInterceptor(
+				nil,
+				&mockStream{ctx: context.Background()},
+				&grpc.StreamServerInfo{FullMethod: "/file_service.FileService/StreamFiles"},
+				func(srv any, stream grpc.ServerStream) error {
+					close(streamStarted)
+					<-streamDone
+					return nil
+				},
+			)
+		}()
+		<-streamStarted
+
+		// Занятый слот StreamFiles не мешает ListFiles
+		_, err := limiter.UnaryInterceptor(
+			context.Background(),
+			nil,
+			&grpc.UnaryServerInfo{FullMethod: "/file_service.FileService/ListFiles"},
+			func(ctx context.Context, req any) (any, error) {
+				return nil, nil
+			},
+		)
+		if err != nil {
+			t.Errorf("Expected ListFiles to succeed, got error: %v", err)
+		}
+
+		err = limiter.StreamInterceptor(
+			nil,
+			&mockStream{ctx: context.Background()},
+			&grpc.StreamServerInfo{FullMethod: "/file_service.FileService/StreamFiles"},
+			func(srv any, stream grpc.ServerStream) error {
+				return nil
+			},
+		)
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("Expected second StreamFiles to be rejected, got: %v", err)
+		}
+
+		close(streamDone)
+	})
 }
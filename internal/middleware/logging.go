@@ -5,12 +5,13 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/keenoobi/grpc-file-manager/api/proto"
 	"google.golang.org/grpc"
 )
 
 func LoggingUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
 	start := time.Now()
-	slog.Info("Unary call started", "method", info.FullMethod, "request", req)
+	slog.Info("Unary call started", "method", info.FullMethod, requestAttr(req))
 
 	resp, err = handler(ctx, req)
 
@@ -18,6 +19,17 @@ func LoggingUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServe
 	return
 }
 
+// requestAttr не пишет в лог содержимое чанков загрузки, только их размер
+func requestAttr(req any) slog.Attr {
+	if chunk, ok := req.(*proto.UploadSessionChunkRequest); ok {
+		return slog.Group("request",
+			"session_id", chunk.GetSessionId(),
+			"offset", chunk.GetOffset(),
+			"chunk_size", len(chunk.GetChunk()))
+	}
+	return slog.Any("request", req)
+}
+
 func LoggingStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 	start := time.Now()
 	slog.Info("Stream call started", "method", info.FullMethod)
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/keenoobi/grpc-file-manager/api/proto"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestLoggingUnaryInterceptor_RedactsChunks(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	req := &proto.UploadSessionChunkRequest{SessionId: "abc", Offset: 4, Chunk: []byte("secret payload")}
+	_, err := LoggingUnaryInterceptor(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: "/file_service.FileService/UploadSessionChunk"},
+		func(ctx context.Context, req any) (any, error) { return nil, nil })
+	require.NoError(t, err)
+
+	require.NotContains(t, logs.String(), "secret payload")
+	require.Contains(t, logs.String(), "request.chunk_size=14")
+	require.Contains(t, logs.String(), "request.session_id=abc")
+}
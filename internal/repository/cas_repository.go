@@ -0,0 +1,760 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+)
+
+// Каталог хранилища с дедупликацией внутри storagePath
+const casDir = ".cas"
+
+// casEntry - запись индекса: имя файла ссылается на blob по SHA-256
+type casEntry struct {
+	fileMetadata
+	Size int64 `json:"size"`
+}
+
+// casWrite - как commit поступает с уже существующим файлом
+type casWrite int
+
+const (
+	casCreate    casWrite = iota // Вернуть ошибку os.ErrExist
+	casReplace                   // Заменить, сохранив время создания
+	casOverwrite                 // Заменить вместе с метаданными
+)
+
+// casRepository хранит одинаковое содержимое один раз: blob лежит в
+// .cas/blobs под своим SHA-256, а имена файлов - записи индекса .cas/index.
+// На blob ссылаются текущие файлы, версии и записи корзины, счетчик ссылок
+// хранится в .cas/refs, и blob удаляется вместе с последней ссылкой
+type casRepository struct {
+	root     string
+	versions *versionStore
+	trash    *trashStore
+	locks    nameLocks
+	refs     sync.Mutex // Счетчики ссылок и появление/удаление blob
+}
+
+func NewCASRepository(storagePath string, versioning VersioningOptions) FileRepository {
+	root := filepath.Join(storagePath, casDir)
+	for _, dir := range []string{"blobs", "refs", "index", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			panic(err)
+		}
+	}
+	trash, err := newTrashStore(root)
+	if err != nil {
+		panic(err)
+	}
+	versions, err := newVersionStore(root, versioning)
+	if err != nil {
+		panic(err)
+	}
+	return &casRepository{
+		root:     root,
+		versions: versions,
+		trash:    trash,
+	}
+}
+
+func (r *casRepository) Save(ctx context.Context, file *entity.File, data io.Reader, opts SaveOptions) error {
+	if err := validateName(file.Name); err != nil {
+		return err
+	}
+	// Заведомо невыполнимое условие отклоняется до приема содержимого
+	if err := r.checkPrecondition(file.Name, opts.Precondition); err != nil {
+		return err
+	}
+
+	checksum, size, err := r.storeBlob(data, opts.Checksum)
+	if err != nil {
+		return err
+	}
+
+	if file.CreatedAt.IsZero() {
+		file.CreatedAt = time.Now()
+	}
+	if file.UpdatedAt.IsZero() {
+		file.UpdatedAt = time.Now()
+	}
+
+	entry := &casEntry{
+		fileMetadata: fileMetadata{
+			SHA256:       checksum,
+			CreatedAt:    file.CreatedAt,
+			UpdatedAt:    file.UpdatedAt,
+			DeclaredSize: file.DeclaredSize,
+			Uploader:     file.Uploader,
+			ContentType:  file.ContentType,
+			Image:        newImageMetadata(file.Image),
+		},
+		Size: size,
+	}
+	switch opts.Conflict {
+	case ConflictFail:
+		err = r.commit(file.Name, entry, opts.Precondition, casCreate)
+	case ConflictRename:
+		file.Name, err = r.commitUnique(file.Name, entry, opts.Precondition)
+	default:
+		err = r.commit(file.Name, entry, opts.Precondition, casReplace)
+	}
+	if err != nil {
+		r.release(checksum)
+		return err
+	}
+
+	file.Size = size
+	file.Path = r.blobPath(checksum)
+	file.Checksum = checksum
+	file.CreatedAt = entry.CreatedAt
+	return nil
+}
+
+// storeBlob сохраняет содержимое и добавляет ссылку на его blob. Если такое
+// содержимое уже хранится, загруженная копия удаляется
+func (r *casRepository) storeBlob(data io.Reader, expected string) (_ string, _ int64, err error) {
+	f, err := createTemp(filepath.Join(r.root, "tmp", "blob"))
+	if err != nil {
+		return "", 0, fmt.Errorf("create temp file failed: %w", err)
+	}
+	tempPath := f.Name()
+	defer os.Remove(tempPath)
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, hash), data)
+	if err != nil {
+		f.Close()
+		return "", 0, fmt.Errorf("write failed: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", 0, fmt.Errorf("close failed: %w", err)
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if expected != "" && !strings.EqualFold(expected, checksum) {
+		return "", 0, fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, expected, checksum)
+	}
+
+	r.refs.Lock()
+	defer r.refs.Unlock()
+
+	blob := r.blobPath(checksum)
+	if _, err := os.Stat(blob); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+			return "", 0, err
+		}
+		if err := os.Rename(tempPath, blob); err != nil {
+			return "", 0, fmt.Errorf("rename failed: %w", err)
+		}
+	} else if err != nil {
+		return "", 0, err
+	}
+	if err := r.addRef(checksum, 1); err != nil {
+		return "", 0, err
+	}
+	return checksum, size, nil
+}
+
+// commit записывает файл name в индекс. Ссылка на blob, которую держал
+// замененный файл, переходит к версии или освобождается
+func (r *casRepository) commit(name string, entry *casEntry, cond Precondition, mode casWrite) error {
+	unlock := r.locks.lock(name)
+	defer unlock()
+
+	previous, err := r.load(name)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var current *entity.File
+	if previous != nil {
+		current = r.file(name, previous)
+	}
+	if err := cond.check(current); err != nil {
+		return err
+	}
+
+	if previous != nil {
+		switch mode {
+		case casCreate:
+			return &os.PathError{Op: "save", Path: name, Err: os.ErrExist}
+		case casReplace:
+			if !previous.CreatedAt.IsZero() {
+				entry.CreatedAt = previous.CreatedAt
+			}
+		}
+	}
+	if err := r.write(name, entry); err != nil {
+		return fmt.Errorf("save metadata failed: %w", err)
+	}
+	if previous != nil {
+		return r.retire(name, previous)
+	}
+	return nil
+}
+
+// commitUnique сохраняет файл под именем name, а если оно занято - под
+// первым свободным "photo (1).jpg", "photo (2).jpg" и т.д.
+func (r *casRepository) commitUnique(name string, entry *casEntry, cond Precondition) (string, error) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+
+	for i := 0; i <= maxRenameAttempts; i++ {
+		candidate := name
+		if i > 0 {
+			// Условие относится к запрошенному имени, а не к подобранному
+			candidate = fmt.Sprintf("%s (%d)%s", stem, i, ext)
+			cond = Precondition{}
+		}
+
+		err := r.commit(candidate, entry, cond, casCreate)
+		if err == nil {
+			return candidate, nil
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+	}
+	return "", &os.PathError{Op: "save", Path: name, Err: os.ErrExist}
+}
+
+// retire убирает замененное содержимое файла: при включенном версионировании
+// оно становится версией и ссылка на blob переходит к ней, иначе ссылка
+// освобождается
+func (r *casRepository) retire(name string, previous *casEntry) error {
+	if !r.versions.opts.Enabled {
+		return r.release(previous.SHA256)
+	}
+	pruned, err := r.versions.add(name, &previous.fileMetadata, previous.Size, nil)
+	if err != nil {
+		return fmt.Errorf("archive previous version failed: %w", err)
+	}
+	return r.releaseVersions(pruned)
+}
+
+func (r *casRepository) checkPrecondition(name string, cond Precondition) error {
+	if !cond.isSet() {
+		return nil
+	}
+	current, err := r.stat(name)
+	if os.IsNotExist(err) {
+		return cond.check(nil)
+	}
+	if err != nil {
+		return err
+	}
+	return cond.check(current)
+}
+
+func (r *casRepository) Get(ctx context.Context, filename string) (*entity.File, io.ReadSeekCloser, error) {
+	// Под блокировкой blob не может исчезнуть между чтением индекса и открытием
+	unlock := r.locks.lock(filename)
+	defer unlock()
+
+	file, err := r.stat(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, f, nil
+}
+
+func (r *casRepository) Stat(ctx context.Context, filename string) (*entity.File, error) {
+	return r.stat(filename)
+}
+
+func (r *casRepository) List(ctx context.Context, opts ListOptions) (*ListResult, error) {
+	return listFiles(opts, r.walk(ctx), true)
+}
+
+func (r *casRepository) Stream(ctx context.Context, filter FileFilter, batchSize int, fn func([]*entity.File) error) error {
+	return streamFiles(filter, batchSize, r.walk(ctx), fn)
+}
+
+// walk обходит индекс: его каталоги повторяют папки хранилища
+func (r *casRepository) walk(ctx context.Context) walkFunc {
+	return func(filter *FileFilter, readSize int, onFile func(*entity.File) error, onFolder func(string)) error {
+		match := func(name string, entry os.DirEntry) (*entity.File, error) {
+			if !filter.matchName(path.Base(name)) {
+				return nil, nil
+			}
+			e, err := r.load(name)
+			if os.IsNotExist(err) {
+				return nil, nil // Файл удалили во время обхода
+			}
+			if err != nil {
+				return nil, err
+			}
+			file := r.file(name, e)
+			if !filter.matchFile(file) {
+				return nil, nil
+			}
+			return file, nil
+		}
+		return walkTree(ctx, filepath.Join(r.root, "index"), filter, readSize, match, onFile, onFolder)
+	}
+}
+
+// Delete переносит запись файла в корзину вместе со ссылкой на blob
+func (r *casRepository) Delete(ctx context.Context, filename string, cond Precondition) (*entity.TrashItem, error) {
+	unlock := r.locks.lock(filename)
+	defer unlock()
+
+	entry, err := r.load(filename)
+	if err != nil {
+		return nil, err
+	}
+	file := r.file(filename, entry)
+	if err := cond.check(file); err != nil {
+		return nil, err
+	}
+
+	item, err := r.trash.add(file, &entry.fileMetadata, nil)
+	if err != nil {
+		return nil, fmt.Errorf("move to trash failed: %w", err)
+	}
+	if err := os.Remove(r.entryPath(filename)); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (r *casRepository) ListTrash(ctx context.Context) ([]*entity.TrashItem, error) {
+	return r.trash.list()
+}
+
+// RestoreTrash возвращает файл из корзины под исходным именем или под
+// destination, если оно задано. Существующий файл не заменяется
+func (r *casRepository) RestoreTrash(ctx context.Context, id, destination string) (*entity.File, error) {
+	info, err := r.trash.get(id)
+	if err != nil {
+		return nil, err
+	}
+	name := info.Filename
+	if destination != "" {
+		name = destination
+	}
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	// Блокировка по id не дает восстановить одну запись дважды
+	unlock := r.locks.lock(name, r.trash.infoPath(id))
+	defer unlock()
+
+	if _, err := os.Stat(r.trash.infoPath(id)); err != nil {
+		return nil, err
+	}
+	if _, err := r.load(name); err == nil {
+		return nil, &os.PathError{Op: "restore", Path: name, Err: os.ErrExist}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	entry := &casEntry{fileMetadata: info.Metadata, Size: info.Size}
+	if err := r.write(name, entry); err != nil {
+		return nil, fmt.Errorf("save metadata failed: %w", err)
+	}
+	if err := r.trash.remove(id); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return r.file(name, entry), nil
+}
+
+// PurgeTrash окончательно удаляет записи корзины, попавшие в нее раньше
+// deletedBefore, вместе с историей версий, если имя больше не используется
+func (r *casRepository) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	items, err := r.trash.list()
+	if err != nil {
+		return 0, err
+	}
+
+	kept := make(map[string]bool)
+	var purged []string
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return len(purged), err
+		}
+		if !item.DeletedAt.Before(deletedBefore) {
+			kept[item.Filename] = true
+			continue
+		}
+		removed, err := r.purgeItem(item)
+		if err != nil {
+			return len(purged), err
+		}
+		if removed {
+			purged = append(purged, item.Filename)
+		}
+	}
+
+	for _, name := range purged {
+		if kept[name] {
+			continue
+		}
+		if err := r.pruneDeleted(name); err != nil {
+			return len(purged), err
+		}
+	}
+	return len(purged), nil
+}
+
+// purgeItem удаляет запись корзины под той же блокировкой, что и
+// RestoreTrash: восстановленный файл ссылается на blob записи, и ссылку
+// нельзя снять, пока восстановление не закончилось
+func (r *casRepository) purgeItem(item *entity.TrashItem) (bool, error) {
+	unlock := r.locks.lock(item.Filename, r.trash.infoPath(item.ID))
+	defer unlock()
+
+	if _, err := os.Stat(r.trash.infoPath(item.ID)); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil // Запись восстановили во время очистки
+		}
+		return false, err
+	}
+	if err := r.trash.remove(item.ID); err != nil {
+		return false, err
+	}
+	return true, r.release(item.Checksum)
+}
+
+// pruneDeleted удаляет историю версий name, если файла с этим именем нет
+func (r *casRepository) pruneDeleted(name string) error {
+	unlock := r.locks.lock(name)
+	defer unlock()
+
+	if _, err := r.load(name); !os.IsNotExist(err) {
+		return nil
+	}
+	removed, err := r.versions.prune(name, 0)
+	if err != nil {
+		return err
+	}
+	return r.releaseVersions(removed)
+}
+
+func (r *casRepository) ListVersions(ctx context.Context, filename string) ([]*entity.FileVersion, error) {
+	if err := validateName(filename); err != nil {
+		return nil, err
+	}
+	versions, err := r.versions.list(filename)
+	if err != nil {
+		return nil, err
+	}
+	for _, version := range versions {
+		version.Path = r.blobPath(version.Checksum)
+	}
+	return versions, nil
+}
+
+func (r *casRepository) GetVersion(ctx context.Context, filename string, number int64) (*entity.FileVersion, io.ReadSeekCloser, error) {
+	if err := validateName(filename); err != nil {
+		return nil, nil, err
+	}
+
+	unlock := r.locks.lock(filename)
+	defer unlock()
+
+	version, err := r.versions.get(filename, number)
+	if err != nil {
+		return nil, nil, err
+	}
+	version.Path = r.blobPath(version.Checksum)
+
+	f, err := os.Open(version.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return version, f, nil
+}
+
+// RestoreVersion делает содержимое версии текущим. Содержимое не
+// копируется: на blob версии просто появляется еще одна ссылка
+func (r *casRepository) RestoreVersion(ctx context.Context, filename string, number int64) (*entity.File, error) {
+	if err := validateName(filename); err != nil {
+		return nil, err
+	}
+
+	unlock := r.locks.lock(filename)
+	version, err := r.versions.get(filename, number)
+	if err == nil {
+		err = r.retain(version.Checksum)
+	}
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entry := &casEntry{
+		fileMetadata: fileMetadata{
+			SHA256:      version.Checksum,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			Uploader:    version.Uploader,
+			ContentType: version.ContentType,
+			Image:       newImageMetadata(version.Image),
+		},
+		Size: version.Size,
+	}
+	if err := r.commit(filename, entry, Precondition{}, casReplace); err != nil {
+		r.release(version.Checksum)
+		return nil, err
+	}
+	return r.file(filename, entry), nil
+}
+
+// PruneVersions оставляет keep последних версий и возвращает число удаленных
+func (r *casRepository) PruneVersions(ctx context.Context, filename string, keep int) (int, error) {
+	if err := validateName(filename); err != nil {
+		return 0, err
+	}
+	if keep < 0 {
+		return 0, fmt.Errorf("%w: negative keep %d", ErrInvalidVersion, keep)
+	}
+
+	unlock := r.locks.lock(filename)
+	defer unlock()
+
+	removed, err := r.versions.prune(filename, keep)
+	if err != nil {
+		return len(removed), err
+	}
+	return len(removed), r.releaseVersions(removed)
+}
+
+// Rename переносит запись индекса, содержимое при этом не трогается.
+// Без overwrite существующий файл назначения не заменяется, и возвращается
+// ошибка os.ErrExist
+func (r *casRepository) Rename(ctx context.Context, from, to string, overwrite bool) (*entity.File, error) {
+	if err := validateName(to); err != nil {
+		return nil, err
+	}
+
+	unlock := r.locks.lock(from, to)
+	defer unlock()
+
+	entry, err := r.load(from)
+	if err != nil {
+		return nil, err
+	}
+	if from == to {
+		return r.file(to, entry), nil
+	}
+
+	previous, err := r.load(to)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if previous != nil && !overwrite {
+		return nil, &os.PathError{Op: "rename", Path: to, Err: os.ErrExist}
+	}
+
+	if err := r.write(to, entry); err != nil {
+		return nil, fmt.Errorf("rename metadata failed: %w", err)
+	}
+	if err := os.Remove(r.entryPath(from)); err != nil {
+		return nil, err
+	}
+	if previous != nil {
+		if err := r.retire(to, previous); err != nil {
+			return nil, err
+		}
+	}
+	return r.file(to, entry), nil
+}
+
+// Copy создает новое имя для того же blob, содержимое не копируется.
+// Копия получает новые время создания и изменения
+func (r *casRepository) Copy(ctx context.Context, from, to string, overwrite bool) (*entity.File, error) {
+	if err := validateName(to); err != nil {
+		return nil, err
+	}
+
+	unlock := r.locks.lock(from)
+	source, err := r.load(from)
+	if err == nil && from != to {
+		err = r.retain(source.SHA256)
+	}
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+	if from == to {
+		if !overwrite {
+			return nil, &os.PathError{Op: "copy", Path: to, Err: os.ErrExist}
+		}
+		return r.file(to, source), nil
+	}
+
+	now := time.Now()
+	entry := &casEntry{
+		fileMetadata: fileMetadata{
+			SHA256:      source.SHA256,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			ContentType: source.ContentType,
+			Image:       source.Image,
+		},
+		Size: source.Size,
+	}
+	mode := casCreate
+	if overwrite {
+		mode = casOverwrite
+	}
+	if err := r.commit(to, entry, Precondition{}, mode); err != nil {
+		r.release(source.SHA256)
+		return nil, err
+	}
+	return r.file(to, entry), nil
+}
+
+// retain добавляет ссылку на уже сохраненный blob
+func (r *casRepository) retain(checksum string) error {
+	r.refs.Lock()
+	defer r.refs.Unlock()
+
+	if _, err := os.Stat(r.blobPath(checksum)); err != nil {
+		return err
+	}
+	return r.addRef(checksum, 1)
+}
+
+// release снимает ссылку на blob, blob без ссылок удаляется
+func (r *casRepository) release(checksum string) error {
+	r.refs.Lock()
+	defer r.refs.Unlock()
+
+	return r.addRef(checksum, -1)
+}
+
+func (r *casRepository) releaseVersions(versions []*entity.FileVersion) error {
+	for _, version := range versions {
+		if err := r.release(version.Checksum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addRef меняет счетчик ссылок на blob, вызывается под r.refs
+func (r *casRepository) addRef(checksum string, delta int64) error {
+	count, err := r.refCount(checksum)
+	if err != nil {
+		return err
+	}
+	count += delta
+
+	path := r.refPath(checksum)
+	if count <= 0 {
+		if err := os.Remove(r.blobPath(checksum)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, []byte(strconv.FormatInt(count, 10)))
+}
+
+func (r *casRepository) refCount(checksum string) (int64, error) {
+	raw, err := os.ReadFile(r.refPath(checksum))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	count, err := strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("corrupted reference count for %s: %w", checksum, err)
+	}
+	return count, nil
+}
+
+// stat возвращает файл по записи индекса
+func (r *casRepository) stat(filename string) (*entity.File, error) {
+	entry, err := r.load(filename)
+	if err != nil {
+		return nil, err
+	}
+	return r.file(filename, entry), nil
+}
+
+// load читает запись индекса. Для папок возвращается ошибка "не существует":
+// они не являются файлами хранилища
+func (r *casRepository) load(filename string) (*casEntry, error) {
+	if err := validateName(filename); err != nil {
+		return nil, err
+	}
+
+	path := r.entryPath(filename)
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if info, statErr := os.Stat(path); statErr == nil && info.IsDir() {
+			return nil, &os.PathError{Op: "stat", Path: filename, Err: os.ErrNotExist}
+		}
+		return nil, err
+	}
+
+	var entry casEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return nil, fmt.Errorf("corrupted index entry for %s: %w", filename, err)
+	}
+	return &entry, nil
+}
+
+func (r *casRepository) write(filename string, entry *casEntry) error {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	path := r.entryPath(filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, raw)
+}
+
+func (r *casRepository) file(name string, entry *casEntry) *entity.File {
+	file := &entity.File{
+		Name: name,
+		Size: entry.Size,
+		Path: r.blobPath(entry.SHA256),
+	}
+	entry.apply(file)
+	return file
+}
+
+func (r *casRepository) entryPath(filename string) string {
+	return filepath.Join(r.root, "index", filepath.FromSlash(filename))
+}
+
+// Blob раскладываются по подкаталогам из первых двух символов хеша,
+// чтобы в одном каталоге не оказывались миллионы файлов
+func (r *casRepository) blobPath(checksum string) string {
+	return filepath.Join(r.root, "blobs", checksum[:2], checksum)
+}
+
+func (r *casRepository) refPath(checksum string) string {
+	return filepath.Join(r.root, "refs", checksum[:2], checksum)
+}
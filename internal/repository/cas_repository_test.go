@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCASRepository_Deduplication(t *testing.T) {
+	storage := t.TempDir()
+	repo := NewCASRepository(storage, VersioningOptions{}).(*casRepository)
+	ctx := context.Background()
+
+	save := func(name, content string) *entity.File {
+		file := &entity.File{Name: name}
+		require.NoError(t, repo.Save(ctx, file, bytes.NewReader([]byte(content)), SaveOptions{}))
+		return file
+	}
+	read := func(name string) string {
+		_, reader, err := repo.Get(ctx, name)
+		require.NoError(t, err)
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(data)
+	}
+	blobs := func() int {
+		count := 0
+		filepath.WalkDir(filepath.Join(storage, casDir, "blobs"), func(path string, d os.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				count++
+			}
+			return nil
+		})
+		return count
+	}
+	refs := func(file *entity.File) int64 {
+		count, err := repo.refCount(file.Checksum)
+		require.NoError(t, err)
+		return count
+	}
+
+	a := save("a.txt", "same content")
+	b := save("docs/b.txt", "same content")
+	save("c.txt", "other")
+	require.Equal(t, a.Checksum, b.Checksum)
+	require.Equal(t, 2, blobs())
+	require.Equal(t, int64(2), refs(a))
+
+	result, err := repo.List(ctx, ListOptions{FileFilter: FileFilter{Recursive: true}})
+	require.NoError(t, err)
+	require.Len(t, result.Files, 3)
+	require.Equal(t, int64(12+12+5), result.LogicalBytes)
+	require.Equal(t, int64(12+5), result.PhysicalBytes)
+
+	t.Run("copy and rename share the blob", func(t *testing.T) {
+		copied, err := repo.Copy(ctx, "a.txt", "a-copy.txt", false)
+		require.NoError(t, err)
+		require.Equal(t, a.Checksum, copied.Checksum)
+		require.Equal(t, int64(3), refs(a))
+
+		_, err = repo.Rename(ctx, "a-copy.txt", "docs/a-copy.txt", false)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), refs(a))
+		require.Equal(t, "same content", read("docs/a-copy.txt"))
+
+		_, err = repo.Rename(ctx, "docs/a-copy.txt", "docs/b.txt", false)
+		require.True(t, os.IsExist(err))
+		require.Equal(t, 2, blobs())
+	})
+
+	t.Run("overwrite releases the previous blob", func(t *testing.T) {
+		save("c.txt", "replaced")
+		require.Equal(t, "replaced", read("c.txt"))
+		require.Equal(t, 2, blobs())
+	})
+
+	t.Run("blob is removed after its last name", func(t *testing.T) {
+		for _, name := range []string{"a.txt", "docs/b.txt", "docs/a-copy.txt"} {
+			_, err := repo.Delete(ctx, name, Precondition{})
+			require.NoError(t, err)
+		}
+		// Записи корзины тоже держат ссылку
+		require.Equal(t, 2, blobs())
+		require.Equal(t, int64(3), refs(a))
+
+		items, err := repo.ListTrash(ctx)
+		require.NoError(t, err)
+		restored, err := repo.RestoreTrash(ctx, items[0].ID, "")
+		require.NoError(t, err)
+		require.Equal(t, "same content", read(restored.Name))
+
+		removed, err := repo.PurgeTrash(ctx, time.Now())
+		require.NoError(t, err)
+		require.Equal(t, 2, removed)
+		require.Equal(t, int64(1), refs(a))
+
+		_, err = repo.Delete(ctx, restored.Name, Precondition{})
+		require.NoError(t, err)
+		_, err = repo.PurgeTrash(ctx, time.Now())
+		require.NoError(t, err)
+		require.Equal(t, int64(0), refs(a))
+		require.Equal(t, 1, blobs())
+	})
+}
+
+func TestCASRepository_Versions(t *testing.T) {
+	storage := t.TempDir()
+	repo := NewCASRepository(storage, VersioningOptions{Enabled: true, Retention: 2}).(*casRepository)
+	ctx := context.Background()
+
+	save := func(content string) *entity.File {
+		file := &entity.File{Name: "doc.txt"}
+		require.NoError(t, repo.Save(ctx, file, bytes.NewReader([]byte(content)), SaveOptions{}))
+		return file
+	}
+
+	first := save("v1")
+	created := first.CreatedAt
+	save("v2")
+	save("v1")
+	current := save("v3")
+	require.Equal(t, created.Unix(), current.CreatedAt.Unix())
+
+	versions, err := repo.ListVersions(ctx, "doc.txt")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	require.Equal(t, int64(2), versions[0].Number)
+	require.Equal(t, int64(3), versions[1].Number)
+
+	// Версия 1 ("v1") удалена по ограничению, но то же содержимое держит версия 3
+	count, err := repo.refCount(first.Checksum)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	_, reader, err := repo.GetVersion(ctx, "doc.txt", 3)
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	require.Equal(t, "v1", string(data))
+
+	restored, err := repo.RestoreVersion(ctx, "doc.txt", 3)
+	require.NoError(t, err)
+	require.Equal(t, first.Checksum, restored.Checksum)
+	count, err = repo.refCount(first.Checksum)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	removed, err := repo.PruneVersions(ctx, "doc.txt", 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, removed)
+	count, err = repo.refCount(first.Checksum)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+}
+
+func TestCASRepository_RestoreDuringPurge(t *testing.T) {
+	repo := NewCASRepository(t.TempDir(), VersioningOptions{}).(*casRepository)
+	ctx := context.Background()
+
+	const count = 20
+	ids := make([]string, count)
+	for i := range ids {
+		name := fmt.Sprintf("%d.txt", i)
+		require.NoError(t, repo.Save(ctx, &entity.File{Name: name}, bytes.NewReader([]byte(name)), SaveOptions{}))
+		item, err := repo.Delete(ctx, name, Precondition{})
+		require.NoError(t, err)
+		ids[i] = item.ID
+	}
+
+	// Каждая запись либо восстановлена вместе с содержимым, либо удалена
+	var wg sync.WaitGroup
+	restored := make([]bool, count)
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.RestoreTrash(ctx, id, "")
+			restored[i] = err == nil
+		}()
+	}
+	purged, err := repo.PurgeTrash(ctx, time.Now().Add(time.Hour))
+	wg.Wait()
+	require.NoError(t, err)
+
+	kept := 0
+	for i, ok := range restored {
+		if !ok {
+			continue
+		}
+		kept++
+		_, reader, err := repo.Get(ctx, fmt.Sprintf("%d.txt", i))
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%d.txt", i), string(data))
+	}
+	require.Equal(t, count, kept+purged)
+}
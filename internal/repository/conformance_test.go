@@ -0,0 +1,481 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+// backendFactory создает пустое хранилище для одного теста
+type backendFactory func(t *testing.T, versioning VersioningOptions) FileRepository
+
+// conformanceBackends - все реализации FileRepository. Каждая обязана
+// проходить общий набор тестов, чтобы бэкенды были взаимозаменяемы
+var conformanceBackends = map[string]backendFactory{
+	"local": func(t *testing.T, versioning VersioningOptions) FileRepository {
+		return NewFileRepository(t.TempDir(), versioning)
+	},
+	"local-indexed": func(t *testing.T, versioning VersioningOptions) FileRepository {
+		repo, err := NewIndexedFileRepository(context.Background(), t.TempDir(), versioning)
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.(io.Closer).Close() })
+		return repo
+	},
+	"cas": func(t *testing.T, versioning VersioningOptions) FileRepository {
+		return NewCASRepository(t.TempDir(), versioning)
+	},
+	"memory": func(t *testing.T, versioning VersioningOptions) FileRepository {
+		return NewMemoryRepository(versioning)
+	},
+	"s3": func(t *testing.T, versioning VersioningOptions) FileRepository {
+		return newTestS3Repository(t, versioning)
+	},
+}
+
+func TestConformance(t *testing.T) {
+	for name, factory := range conformanceBackends {
+		t.Run(name, func(t *testing.T) {
+			testConformance(t, factory)
+		})
+	}
+}
+
+func testConformance(t *testing.T, newRepo backendFactory) {
+	ctx := context.Background()
+
+	save := func(t *testing.T, repo FileRepository, name, content string, opts SaveOptions) (*entity.File, error) {
+		file := &entity.File{Name: name, ContentType: "text/plain", Uploader: "tester"}
+		err := repo.Save(ctx, file, bytes.NewReader([]byte(content)), opts)
+		return file, err
+	}
+	mustSave := func(t *testing.T, repo FileRepository, name, content string) *entity.File {
+		file, err := save(t, repo, name, content, SaveOptions{})
+		require.NoError(t, err)
+		return file
+	}
+	read := func(t *testing.T, repo FileRepository, name string) string {
+		_, reader, err := repo.Get(ctx, name)
+		require.NoError(t, err)
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(data)
+	}
+	names := func(files []*entity.File) []string {
+		result := make([]string, 0, len(files))
+		for _, file := range files {
+			result = append(result, file.Name)
+		}
+		sort.Strings(result)
+		return result
+	}
+
+	t.Run("save and get", func(t *testing.T) {
+		repo := newRepo(t, VersioningOptions{})
+		sum := sha256.Sum256([]byte("hello"))
+
+		saved := mustSave(t, repo, "docs/hello.txt", "hello")
+		require.Equal(t, int64(5), saved.Size)
+		require.Equal(t, hex.EncodeToString(sum[:]), saved.Checksum)
+
+		file, reader, err := repo.Get(ctx, "docs/hello.txt")
+		require.NoError(t, err)
+		defer reader.Close()
+		require.Equal(t, int64(5), file.Size)
+		require.Equal(t, saved.Checksum, file.Checksum)
+		require.Equal(t, "text/plain", file.ContentType)
+		require.Equal(t, "tester", file.Uploader)
+
+		// Содержимое читается с произвольного места
+		_, err = reader.Seek(1, io.SeekStart)
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.Equal(t, "ello", string(data))
+
+		_, _, err = repo.Get(ctx, "missing.txt")
+		require.True(t, os.IsNotExist(err))
+		_, _, err = repo.Get(ctx, "docs")
+		require.True(t, os.IsNotExist(err), "folder is not a file")
+
+		stat, err := repo.Stat(ctx, "docs/hello.txt")
+		require.NoError(t, err)
+		require.Equal(t, file.Checksum, stat.Checksum)
+		require.Equal(t, file.Size, stat.Size)
+		require.Equal(t, file.Uploader, stat.Uploader)
+		_, err = repo.Stat(ctx, "missing.txt")
+		require.True(t, os.IsNotExist(err))
+		_, err = repo.Stat(ctx, "docs")
+		require.True(t, os.IsNotExist(err))
+		_, err = repo.Stat(ctx, "../escape.txt")
+		require.ErrorIs(t, err, ErrInvalidPath)
+	})
+
+	t.Run("invalid names", func(t *testing.T) {
+		repo := newRepo(t, VersioningOptions{})
+		for _, name := range []string{"", "../escape.txt", "/abs.txt", ".hidden", "a/.meta/b"} {
+			_, err := save(t, repo, name, "x", SaveOptions{})
+			require.ErrorIs(t, err, ErrInvalidPath, name)
+		}
+		_, err := repo.List(ctx, ListOptions{FileFilter: FileFilter{Folder: "../"}})
+		require.ErrorIs(t, err, ErrInvalidPath)
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		repo := newRepo(t, VersioningOptions{})
+		_, err := save(t, repo, "a.txt", "data", SaveOptions{Checksum: "deadbeef"})
+		require.ErrorIs(t, err, ErrChecksumMismatch)
+		_, _, err = repo.Get(ctx, "a.txt")
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("overwrite keeps created at", func(t *testing.T) {
+		repo := newRepo(t, VersioningOptions{})
+		first := mustSave(t, repo, "a.txt", "first")
+		time.Sleep(10 * time.Millisecond)
+		second := mustSave(t, repo, "a.txt", "second")
+
+		require.Equal(t, first.CreatedAt.Unix(), second.CreatedAt.Unix())
+		require.NotEqual(t, first.ETag(), second.ETag())
+		require.Equal(t, "second", read(t, repo, "a.txt"))
+	})
+
+	t.Run("conflict policy", func(t *testing.T) {
+		repo := newRepo(t, VersioningOptions{})
+		mustSave(t, repo, "photo.jpg", "original")
+
+		_, err := save(t, repo, "photo.jpg", "new", SaveOptions{Conflict: ConflictFail})
+		require.True(t, os.IsExist(err))
+		require.Equal(t, "original", read(t, repo, "photo.jpg"))
+
+		renamed, err := save(t, repo, "photo.jpg", "new", SaveOptions{Conflict: ConflictRename})
+		require.NoError(t, err)
+		require.Equal(t, "photo (1).jpg", renamed.Name)
+		require.Equal(t, "new", read(t, repo, "photo (1).jpg"))
+
+		fresh, err := save(t, repo, "other.jpg", "x", SaveOptions{Conflict: ConflictRename})
+		require.NoError(t, err)
+		require.Equal(t, "other.jpg", fresh.Name)
+	})
+
+	t.Run("preconditions", func(t *testing.T) {
+		repo := newRepo(t, VersioningOptions{})
+		_, err := save(t, repo, "a.txt", "x", SaveOptions{Precondition: Precondition{IfMatch: "*"}})
+		require.ErrorIs(t, err, ErrPreconditionFailed)
+
+		first, err := save(t, repo, "a.txt", "first", SaveOptions{Precondition: Precondition{IfNoneMatch: "*"}})
+		require.NoError(t, err)
+		_, err = save(t, repo, "a.txt", "again", SaveOptions{Precondition: Precondition{IfNoneMatch: "*"}})
+		require.ErrorIs(t, err, ErrPreconditionFailed)
+
+		_, err = save(t, repo, "a.txt", "second", SaveOptions{Precondition: Precondition{IfMatch: first.ETag()}})
+		require.NoError(t, err)
+		_, err = save(t, repo, "a.txt", "third", SaveOptions{Precondition: Precondition{IfMatch: first.ETag()}})
+		require.ErrorIs(t, err, ErrPreconditionFailed)
+		require.Equal(t, "second", read(t, repo, "a.txt"))
+
+		_, err = repo.Delete(ctx, "a.txt", Precondition{IfMatch: first.ETag()})
+		require.ErrorIs(t, err, ErrPreconditionFailed)
+	})
+
+	t.Run("list and stream", func(t *testing.T) {
+		repo := newRepo(t, VersioningOptions{})
+		mustSave(t, repo, "a.txt", "aa")
+		mustSave(t, repo, "b.jpg", "bbbb")
+		mustSave(t, repo, "docs/c.txt", "c")
+		mustSave(t, repo, "docs/2024/d.txt", "dddddd")
+
+		root, err := repo.List(ctx, ListOptions{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a.txt", "b.jpg"}, names(root.Files))
+		require.Equal(t, []string{"docs"}, root.Folders)
+
+		docs, err := repo.List(ctx, ListOptions{FileFilter: FileFilter{Folder: "docs"}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"docs/c.txt"}, names(docs.Files))
+		require.Equal(t, []string{"docs/2024"}, docs.Folders)
+
+		all, err := repo.List(ctx, ListOptions{FileFilter: FileFilter{Recursive: true}})
+		require.NoError(t, err)
+		require.Len(t, all.Files, 4)
+		require.Equal(t, int64(13), all.LogicalBytes)
+
+		filtered, err := repo.List(ctx, ListOptions{FileFilter: FileFilter{Recursive: true, NamePattern: "*.txt", MinSize: 2}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a.txt", "docs/2024/d.txt"}, names(filtered.Files))
+
+		byMetadata, err := repo.List(ctx, ListOptions{FileFilter: FileFilter{Recursive: true, ContentType: "text/", Uploader: "tester"}})
+		require.NoError(t, err)
+		require.Len(t, byMetadata.Files, 4)
+		byMetadata, err = repo.List(ctx, ListOptions{FileFilter: FileFilter{Recursive: true, Uploader: "someone"}})
+		require.NoError(t, err)
+		require.Empty(t, byMetadata.Files)
+
+		bySize := ListOptions{FileFilter: FileFilter{Recursive: true}, SortBy: SortBySize, PageSize: 3}
+		page, err := repo.List(ctx, bySize)
+		require.NoError(t, err)
+		require.Equal(t, "docs/2024/d.txt", page.Files[0].Name)
+		require.NotEmpty(t, page.NextPageToken)
+		bySize.PageToken = page.NextPageToken
+		page, err = repo.List(ctx, bySize)
+		require.NoError(t, err)
+		require.Len(t, page.Files, 1)
+		require.Equal(t, "docs/c.txt", page.Files[0].Name)
+		require.Empty(t, page.NextPageToken)
+
+		var batches [][]*entity.File
+		err = repo.Stream(ctx, FileFilter{Recursive: true}, 3, func(files []*entity.File) error {
+			batches = append(batches, files)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, batches, 2)
+		require.Len(t, batches[0], 3)
+
+		stop := errors.New("stop")
+		err = repo.Stream(ctx, FileFilter{Recursive: true}, 1, func([]*entity.File) error { return stop })
+		require.ErrorIs(t, err, stop)
+	})
+
+	t.Run("rename and copy", func(t *testing.T) {
+		repo := newRepo(t, VersioningOptions{})
+		original := mustSave(t, repo, "a.txt", "first")
+		mustSave(t, repo, "b.txt", "second")
+
+		_, err := repo.Rename(ctx, "missing.txt", "c.txt", false)
+		require.True(t, os.IsNotExist(err))
+		_, err = repo.Rename(ctx, "a.txt", "b.txt", false)
+		require.True(t, os.IsExist(err))
+
+		moved, err := repo.Rename(ctx, "a.txt", "docs/a.txt", false)
+		require.NoError(t, err)
+		require.Equal(t, "docs/a.txt", moved.Name)
+		require.Equal(t, original.Checksum, moved.Checksum)
+		_, _, err = repo.Get(ctx, "a.txt")
+		require.True(t, os.IsNotExist(err))
+
+		_, err = repo.Rename(ctx, "docs/a.txt", "b.txt", true)
+		require.NoError(t, err)
+		require.Equal(t, "first", read(t, repo, "b.txt"))
+
+		_, err = repo.Copy(ctx, "b.txt", "b.txt", false)
+		require.True(t, os.IsExist(err))
+		copied, err := repo.Copy(ctx, "b.txt", "copy/b.txt", false)
+		require.NoError(t, err)
+		require.Equal(t, original.Checksum, copied.Checksum)
+		require.Equal(t, "text/plain", copied.ContentType)
+		require.True(t, copied.CreatedAt.After(original.CreatedAt))
+		require.Equal(t, "first", read(t, repo, "copy/b.txt"))
+
+		mustSave(t, repo, "c.txt", "third")
+		_, err = repo.Copy(ctx, "c.txt", "b.txt", false)
+		require.True(t, os.IsExist(err))
+		_, err = repo.Copy(ctx, "c.txt", "b.txt", true)
+		require.NoError(t, err)
+		require.Equal(t, "third", read(t, repo, "b.txt"))
+		require.Equal(t, "first", read(t, repo, "copy/b.txt"))
+	})
+
+	t.Run("trash", func(t *testing.T) {
+		repo := newRepo(t, VersioningOptions{})
+		original := mustSave(t, repo, "docs/a.txt", "first")
+
+		_, err := repo.Delete(ctx, "missing.txt", Precondition{})
+		require.True(t, os.IsNotExist(err))
+
+		deleted, err := repo.Delete(ctx, "docs/a.txt", Precondition{})
+		require.NoError(t, err)
+		require.Equal(t, "docs/a.txt", deleted.Filename)
+		require.Equal(t, original.Checksum, deleted.Checksum)
+
+		result, err := repo.List(ctx, ListOptions{FileFilter: FileFilter{Recursive: true}})
+		require.NoError(t, err)
+		require.Empty(t, result.Files)
+
+		items, err := repo.ListTrash(ctx)
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		require.Equal(t, int64(5), items[0].Size)
+
+		mustSave(t, repo, "docs/a.txt", "second")
+		_, err = repo.RestoreTrash(ctx, deleted.ID, "")
+		require.True(t, os.IsExist(err))
+
+		restored, err := repo.RestoreTrash(ctx, deleted.ID, "docs/restored.txt")
+		require.NoError(t, err)
+		require.Equal(t, original.Checksum, restored.Checksum)
+		require.Equal(t, original.CreatedAt.Unix(), restored.CreatedAt.Unix())
+		require.Equal(t, "first", read(t, repo, "docs/restored.txt"))
+		_, err = repo.RestoreTrash(ctx, deleted.ID, "")
+		require.True(t, os.IsNotExist(err))
+
+		_, err = repo.Delete(ctx, "docs/restored.txt", Precondition{})
+		require.NoError(t, err)
+		cutoff := time.Now()
+		time.Sleep(10 * time.Millisecond)
+		_, err = repo.Delete(ctx, "docs/a.txt", Precondition{})
+		require.NoError(t, err)
+
+		removed, err := repo.PurgeTrash(ctx, cutoff)
+		require.NoError(t, err)
+		require.Equal(t, 1, removed)
+		removed, err = repo.PurgeTrash(ctx, time.Now())
+		require.NoError(t, err)
+		require.Equal(t, 1, removed)
+
+		items, err = repo.ListTrash(ctx)
+		require.NoError(t, err)
+		require.Empty(t, items)
+	})
+
+	t.Run("versions", func(t *testing.T) {
+		repo := newRepo(t, VersioningOptions{Enabled: true, Retention: 2})
+		first := mustSave(t, repo, "doc.txt", "v1")
+		mustSave(t, repo, "doc.txt", "v2")
+		mustSave(t, repo, "doc.txt", "v3")
+		mustSave(t, repo, "doc.txt", "v4")
+
+		versions, err := repo.ListVersions(ctx, "doc.txt")
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		require.Equal(t, []int64{2, 3}, []int64{versions[0].Number, versions[1].Number})
+
+		version, reader, err := repo.GetVersion(ctx, "doc.txt", 2)
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		require.NoError(t, err)
+		require.Equal(t, "v2", string(data))
+		require.Equal(t, int64(2), version.Size)
+
+		_, _, err = repo.GetVersion(ctx, "doc.txt", 1)
+		require.True(t, os.IsNotExist(err))
+		_, _, err = repo.GetVersion(ctx, "doc.txt", 0)
+		require.ErrorIs(t, err, ErrInvalidVersion)
+
+		restored, err := repo.RestoreVersion(ctx, "doc.txt", 2)
+		require.NoError(t, err)
+		require.Equal(t, first.CreatedAt.Unix(), restored.CreatedAt.Unix())
+		require.Equal(t, "v2", read(t, repo, "doc.txt"))
+
+		versions, err = repo.ListVersions(ctx, "doc.txt")
+		require.NoError(t, err)
+		require.Equal(t, int64(4), versions[len(versions)-1].Number)
+
+		removed, err := repo.PruneVersions(ctx, "doc.txt", 0)
+		require.NoError(t, err)
+		require.Equal(t, 2, removed)
+		versions, err = repo.ListVersions(ctx, "doc.txt")
+		require.NoError(t, err)
+		require.Empty(t, versions)
+
+		_, err = repo.PruneVersions(ctx, "doc.txt", -1)
+		require.ErrorIs(t, err, ErrInvalidVersion)
+	})
+
+	t.Run("prune during saves", func(t *testing.T) {
+		// Очистка истории не мешает перезаписи и не оставляет битых версий
+		repo := newRepo(t, VersioningOptions{Enabled: true})
+		mustSave(t, repo, "busy.txt", "v0")
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 1; i <= 30; i++ {
+				mustSave(t, repo, "busy.txt", fmt.Sprintf("v%d", i))
+			}
+		}()
+		for pruning := true; pruning; {
+			select {
+			case <-done:
+				pruning = false
+			default:
+			}
+			_, err := repo.PruneVersions(ctx, "busy.txt", 1)
+			require.NoError(t, err)
+		}
+
+		versions, err := repo.ListVersions(ctx, "busy.txt")
+		require.NoError(t, err)
+		for _, version := range versions {
+			_, reader, err := repo.GetVersion(ctx, "busy.txt", version.Number)
+			require.NoError(t, err)
+			reader.Close()
+		}
+		require.Equal(t, "v30", read(t, repo, "busy.txt"))
+	})
+
+	t.Run("image metadata", func(t *testing.T) {
+		repo := newRepo(t, VersioningOptions{Enabled: true})
+		info := &entity.ImageInfo{
+			Width:       640,
+			Height:      480,
+			Format:      "jpeg",
+			ColorModel:  "ycbcr",
+			TakenAt:     time.Date(2024, 5, 1, 10, 20, 30, 0, time.UTC),
+			Orientation: 6,
+			CameraModel: "Camera X",
+		}
+		file := &entity.File{Name: "photo.jpg", ContentType: "image/jpeg", Image: info}
+		require.NoError(t, repo.Save(ctx, file, bytes.NewReader([]byte("jpeg")), SaveOptions{}))
+
+		stored, reader, err := repo.Get(ctx, "photo.jpg")
+		require.NoError(t, err)
+		reader.Close()
+		require.Equal(t, info, stored.Image)
+
+		result, err := repo.List(ctx, ListOptions{})
+		require.NoError(t, err)
+		require.Len(t, result.Files, 1)
+		require.Equal(t, info, result.Files[0].Image)
+
+		copied, err := repo.Copy(ctx, "photo.jpg", "copy.jpg", false)
+		require.NoError(t, err)
+		require.Equal(t, info, copied.Image)
+		moved, err := repo.Rename(ctx, "copy.jpg", "moved.jpg", false)
+		require.NoError(t, err)
+		require.Equal(t, info, moved.Image)
+
+		// Свойства относятся к содержимому и уходят в версию вместе с ним
+		mustSave(t, repo, "photo.jpg", "not an image")
+		stored, reader, err = repo.Get(ctx, "photo.jpg")
+		require.NoError(t, err)
+		reader.Close()
+		require.Nil(t, stored.Image)
+
+		version, reader, err := repo.GetVersion(ctx, "photo.jpg", 1)
+		require.NoError(t, err)
+		reader.Close()
+		require.Equal(t, info, version.Image)
+		restored, err := repo.RestoreVersion(ctx, "photo.jpg", 1)
+		require.NoError(t, err)
+		require.Equal(t, info, restored.Image)
+
+		deleted, err := repo.Delete(ctx, "moved.jpg", Precondition{})
+		require.NoError(t, err)
+		restored, err = repo.RestoreTrash(ctx, deleted.ID, "")
+		require.NoError(t, err)
+		require.Equal(t, info, restored.Image)
+	})
+
+	t.Run("versioning disabled", func(t *testing.T) {
+		repo := newRepo(t, VersioningOptions{})
+		mustSave(t, repo, "doc.txt", "v1")
+		mustSave(t, repo, "doc.txt", "v2")
+
+		versions, err := repo.ListVersions(ctx, "doc.txt")
+		require.NoError(t, err)
+		require.Empty(t, versions)
+	})
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// copyContents копирует файл через reflink (FICLONE), если файловая система
+// это поддерживает (btrfs, xfs). Иначе io.Copy между двумя *os.File
+// использует copy_file_range, а при его недоступности - обычное чтение и запись
+func copyContents(dst, src *os.File) error {
+	if err := unix.IoctlFileClone(int(dst.Fd()), int(src.Fd())); err == nil {
+		return nil
+	}
+	_, err := io.Copy(dst, src)
+	return err
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package repository
+
+import (
+	"io"
+	"os"
+)
+
+func copyContents(dst, src *os.File) error {
+	_, err := io.Copy(dst, src)
+	return err
+}
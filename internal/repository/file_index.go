@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Файл индекса метаданных внутри хранилища
+const indexFile = ".index.db"
+
+var (
+	indexBucket = []byte("files")
+	stateBucket = []byte("state")
+	// Ключ есть, только если процесс, работавший с индексом, закрыл его
+	// штатно и все изменения хранилища попали в индекс
+	cleanKey = []byte("clean")
+)
+
+// fileIndex - встроенная база (bbolt) с записью на каждый файл хранилища.
+// Ключи упорядочены по имени, поэтому содержимое папки - это диапазон
+// ключей с ее префиксом, и список строится без обхода каталогов и stat
+type fileIndex struct {
+	db *bolt.DB
+
+	mu    sync.Mutex
+	dirty bool // Индекс разошелся с диском и будет пересобран при следующем запуске
+}
+
+// indexEntry хранит все, что нужно для выдачи файла в списке
+type indexEntry struct {
+	fileMetadata
+	Size int64 `json:"size"`
+}
+
+// openFileIndex открывает индекс и сообщает, нужно ли заполнить его заново
+// по файлам на диске: индекс только что создан, предыдущий процесс не закрыл
+// его штатно или не смог записать в него изменение. Отметка о штатном
+// закрытии снимается на время работы. База блокируется на время работы
+// процесса: второй процесс с тем же хранилищем получит ошибку
+func openFileIndex(storagePath string) (*fileIndex, bool, error) {
+	path := filepath.Join(storagePath, indexFile)
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, false, fmt.Errorf("open index %s failed: %w", path, err)
+	}
+
+	stale := false
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(indexBucket); err != nil {
+			return err
+		}
+		state, err := tx.CreateBucketIfNotExists(stateBucket)
+		if err != nil {
+			return err
+		}
+		stale = state.Get(cleanKey) == nil
+		return state.Delete(cleanKey)
+	})
+	if err != nil {
+		db.Close()
+		return nil, false, err
+	}
+	return &fileIndex{db: db}, stale, nil
+}
+
+// markDirty отмечает, что изменение хранилища не попало в индекс. Индекс
+// остается в работе, а пересобирается при следующем запуске
+func (i *fileIndex) markDirty() {
+	i.mu.Lock()
+	i.dirty = true
+	i.mu.Unlock()
+}
+
+// close ставит отметку о штатном закрытии, если индекс не разошелся с диском
+func (i *fileIndex) close() error {
+	i.mu.Lock()
+	dirty := i.dirty
+	i.mu.Unlock()
+
+	if !dirty {
+		err := i.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(stateBucket).Put(cleanKey, []byte{1})
+		})
+		if err != nil {
+			i.db.Close()
+			return err
+		}
+	}
+	return i.db.Close()
+}
+
+func (i *fileIndex) put(file *entity.File) error {
+	raw, err := json.Marshal(newIndexEntry(file))
+	if err != nil {
+		return err
+	}
+	return i.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(indexBucket).Put([]byte(file.Name), raw)
+	})
+}
+
+func (i *fileIndex) remove(name string) error {
+	return i.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(indexBucket).Delete([]byte(name))
+	})
+}
+
+// rebuild заменяет содержимое индекса файлами, которые fill передает в put.
+// Все выполняется одной транзакцией: до ее завершения читатели видят
+// прежний индекс целиком
+func (i *fileIndex) rebuild(fill func(put func(*entity.File) error) error) (int, error) {
+	count := 0
+	err := i.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(indexBucket); err != nil {
+			return err
+		}
+		bucket, err := tx.CreateBucket(indexBucket)
+		if err != nil {
+			return err
+		}
+		return fill(func(file *entity.File) error {
+			raw, err := json.Marshal(newIndexEntry(file))
+			if err != nil {
+				return err
+			}
+			count++
+			return bucket.Put([]byte(file.Name), raw)
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// indexRecord - запись индекса, прочитанная внутри транзакции
+type indexRecord struct {
+	name  string
+	entry indexEntry
+}
+
+// walk обходит записи с префиксом prefix порциями по readSize. Без
+// recursive вложенные папки передаются в onFolder, а их содержимое
+// пропускается переходом курсора за конец папки. Колбэки вызываются вне
+// транзакции, чтобы медленный получатель не держал базу
+func (i *fileIndex) walk(ctx context.Context, prefix string, recursive bool, readSize int,
+	onRecord func(name string, entry *indexEntry) error, onFolder func(string)) error {
+	next := []byte(prefix)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var records []indexRecord
+		var folders []string
+		err := i.db.View(func(tx *bolt.Tx) error {
+			cursor := tx.Bucket(indexBucket).Cursor()
+			key, value := cursor.Seek(next)
+			next = nil
+			for ; key != nil && bytes.HasPrefix(key, []byte(prefix)); key, value = cursor.Seek(next) {
+				if len(records)+len(folders) == readSize {
+					next = bytes.Clone(key)
+					return nil
+				}
+
+				if !recursive {
+					if slash := bytes.IndexByte(key[len(prefix):], '/'); slash >= 0 {
+						folder := key[:len(prefix)+slash]
+						folders = append(folders, string(folder))
+						// '0' следует сразу за '/', поэтому все ключи папки меньше
+						next = append(bytes.Clone(folder), '0')
+						continue
+					}
+				}
+
+				var entry indexEntry
+				if err := json.Unmarshal(value, &entry); err != nil {
+					return fmt.Errorf("corrupted index entry for %s: %w", key, err)
+				}
+				records = append(records, indexRecord{name: string(key), entry: entry})
+				next = append(bytes.Clone(key), 0)
+			}
+			next = nil
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, folder := range folders {
+			if onFolder != nil {
+				onFolder(folder)
+			}
+		}
+		for _, record := range records {
+			if err := onRecord(record.name, &record.entry); err != nil {
+				return err
+			}
+		}
+		if next == nil {
+			return nil
+		}
+	}
+}
+
+func newIndexEntry(file *entity.File) *indexEntry {
+	return &indexEntry{
+		fileMetadata: fileMetadata{
+			SHA256:       file.Checksum,
+			CreatedAt:    file.CreatedAt,
+			UpdatedAt:    file.UpdatedAt,
+			DeclaredSize: file.DeclaredSize,
+			Uploader:     file.Uploader,
+			ContentType:  file.ContentType,
+			Image:        newImageMetadata(file.Image),
+		},
+		Size: file.Size,
+	}
+}
+
+func (e *indexEntry) file(name, path string) *entity.File {
+	file := &entity.File{Name: name, Size: e.Size, Path: path}
+	e.apply(file)
+	return file
+}
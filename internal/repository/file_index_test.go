@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileIndex(t *testing.T) {
+	storage := t.TempDir()
+	ctx := context.Background()
+
+	// Файлы, сохраненные до включения индекса, попадают в новый индекс
+	plain := NewFileRepository(storage, VersioningOptions{})
+	require.NoError(t, plain.Save(ctx, &entity.File{Name: "old.txt", ContentType: "text/plain"}, bytes.NewReader([]byte("old")), SaveOptions{}))
+
+	repo, err := NewIndexedFileRepository(ctx, storage, VersioningOptions{})
+	require.NoError(t, err)
+	indexed := repo.(*fileRepository)
+
+	save := func(name, contentType, uploader string) {
+		file := &entity.File{Name: name, ContentType: contentType, Uploader: uploader}
+		require.NoError(t, repo.Save(ctx, file, bytes.NewReader([]byte(name)), SaveOptions{}))
+	}
+	names := func(filter FileFilter) []string {
+		result, err := repo.List(ctx, ListOptions{FileFilter: filter, SortBy: SortByName, SortAscending: true})
+		require.NoError(t, err)
+		var names []string
+		for _, file := range result.Files {
+			names = append(names, file.Name)
+		}
+		return names
+	}
+
+	save("photo.jpg", "image/jpeg", "alice")
+	save("docs/report.pdf", "application/pdf", "bob")
+	save("docs/img/scan.png", "image/png", "bob")
+
+	require.Equal(t, []string{"old.txt", "photo.jpg"}, names(FileFilter{}))
+	require.Equal(t, []string{"docs/img/scan.png", "photo.jpg"}, names(FileFilter{Recursive: true, ContentType: "image/"}))
+	require.Equal(t, []string{"docs/img/scan.png", "docs/report.pdf"}, names(FileFilter{Recursive: true, Uploader: "bob"}))
+
+	result, err := repo.List(ctx, ListOptions{FileFilter: FileFilter{Folder: "docs"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"docs/img"}, result.Folders)
+
+	_, err = repo.Rename(ctx, "photo.jpg", "docs/photo.jpg", false)
+	require.NoError(t, err)
+	_, err = repo.Delete(ctx, "old.txt", Precondition{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"docs/photo.jpg", "docs/report.pdf"}, names(FileFilter{Folder: "docs"}))
+	require.Empty(t, names(FileFilter{}))
+
+	t.Run("rebuild after drift", func(t *testing.T) {
+		// Изменения в обход сервиса индекс не видит
+		require.NoError(t, os.WriteFile(filepath.Join(storage, "manual.txt"), []byte("manual"), 0644))
+		require.NoError(t, os.Remove(filepath.Join(storage, "docs", "report.pdf")))
+		require.Equal(t, []string{"docs/photo.jpg", "docs/report.pdf"}, names(FileFilter{Folder: "docs"}))
+		require.Empty(t, names(FileFilter{}))
+
+		// Работающее хранилище держит блокировку индекса
+		_, err := RebuildFileIndex(ctx, storage)
+		require.Error(t, err)
+
+		require.NoError(t, indexed.Close())
+		count, err := RebuildFileIndex(ctx, storage)
+		require.NoError(t, err)
+		require.Equal(t, 3, count)
+
+		repo, err = NewIndexedFileRepository(ctx, storage, VersioningOptions{})
+		require.NoError(t, err)
+		defer repo.(*fileRepository).Close()
+		require.Equal(t, []string{"manual.txt"}, names(FileFilter{}))
+		require.Equal(t, []string{"docs/photo.jpg"}, names(FileFilter{Folder: "docs"}))
+	})
+
+	t.Run("rebuild after unclean shutdown", func(t *testing.T) {
+		repo, err := NewIndexedFileRepository(ctx, storage, VersioningOptions{})
+		require.NoError(t, err)
+		// Процесс завершился, не закрыв индекс
+		require.NoError(t, repo.(*fileRepository).index.db.Close())
+		require.NoError(t, os.WriteFile(filepath.Join(storage, "crash.txt"), []byte("crash"), 0644))
+
+		repo, err = NewIndexedFileRepository(ctx, storage, VersioningOptions{})
+		require.NoError(t, err)
+		result, err := repo.List(ctx, ListOptions{SortBy: SortByName, SortAscending: true})
+		require.NoError(t, err)
+		require.Len(t, result.Files, 2)
+		require.Equal(t, "crash.txt", result.Files[0].Name)
+
+		// Изменение, не попавшее в индекс, тоже приводит к пересборке
+		repo.(*fileRepository).index.markDirty()
+		require.NoError(t, repo.(*fileRepository).Close())
+		require.NoError(t, os.WriteFile(filepath.Join(storage, "dirty.txt"), []byte("dirty"), 0644))
+
+		repo, err = NewIndexedFileRepository(ctx, storage, VersioningOptions{})
+		require.NoError(t, err)
+		defer repo.(*fileRepository).Close()
+		result, err = repo.List(ctx, ListOptions{})
+		require.NoError(t, err)
+		require.Len(t, result.Files, 3)
+	})
+}
+
+func TestFileIndex_DeleteWhenIndexFails(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewIndexedFileRepository(ctx, t.TempDir(), VersioningOptions{})
+	require.NoError(t, err)
+	require.NoError(t, repo.Save(ctx, &entity.File{Name: "a.txt"}, bytes.NewReader([]byte("a")), SaveOptions{}))
+
+	// Файл уже перенесен в корзину, поэтому удаление успешно, а индекс
+	// пересоберется при следующем запуске
+	index := repo.(*fileRepository).index
+	require.NoError(t, index.db.Close())
+	item, err := repo.Delete(ctx, "a.txt", Precondition{})
+	require.NoError(t, err)
+	require.Equal(t, "a.txt", item.Filename)
+	require.True(t, index.dirty)
+
+	items, err := repo.ListTrash(ctx)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+}
@@ -2,122 +2,947 @@ package repository
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
-	"sort"
+	"strings"
+	"time"
 
 	"github.com/keenoobi/grpc-file-manager/internal/entity"
 )
 
+var (
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrInvalidPath      = errors.New("invalid path")
+)
+
 type FileRepository interface {
-	Save(ctx context.Context, file *entity.File, data io.Reader) error
-	Get(ctx context.Context, filename string) (*entity.File, io.ReadCloser, error)
-	List(ctx context.Context) ([]*entity.File, error)
+	Save(ctx context.Context, file *entity.File, data io.Reader, opts SaveOptions) error
+	Get(ctx context.Context, filename string) (*entity.File, io.ReadSeekCloser, error)
+	// Stat возвращает сведения о файле, не открывая содержимое
+	Stat(ctx context.Context, filename string) (*entity.File, error)
+	List(ctx context.Context, opts ListOptions) (*ListResult, error)
+	Stream(ctx context.Context, filter FileFilter, batchSize int, fn func([]*entity.File) error) error
+	Delete(ctx context.Context, filename string, cond Precondition) (*entity.TrashItem, error)
+	Rename(ctx context.Context, from, to string, overwrite bool) (*entity.File, error)
+	Copy(ctx context.Context, from, to string, overwrite bool) (*entity.File, error)
+
+	ListVersions(ctx context.Context, filename string) ([]*entity.FileVersion, error)
+	GetVersion(ctx context.Context, filename string, number int64) (*entity.FileVersion, io.ReadSeekCloser, error)
+	RestoreVersion(ctx context.Context, filename string, number int64) (*entity.File, error)
+	PruneVersions(ctx context.Context, filename string, keep int) (int, error)
+
+	ListTrash(ctx context.Context) ([]*entity.TrashItem, error)
+	RestoreTrash(ctx context.Context, id, destination string) (*entity.File, error)
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error)
+}
+
+type SaveOptions struct {
+	// Ожидаемый SHA-256 содержимого в hex, пустая строка - без проверки
+	Checksum string
+	// Что делать, если файл с таким именем уже есть
+	Conflict ConflictPolicy
+	// Условие на текущую версию файла, проверяется атомарно с заменой
+	Precondition Precondition
 }
 
+type ConflictPolicy int
+
+const (
+	ConflictOverwrite ConflictPolicy = iota
+	ConflictFail                     // Вернуть ошибку os.ErrExist
+	ConflictRename                   // Сохранить под свободным именем "photo (1).jpg"
+)
+
 type fileRepository struct {
 	storagePath string
+	metadata    *metadataStore
+	versions    *versionStore
+	trash       *trashStore
+	index       *fileIndex // nil - список строится обходом каталогов
+	locks       nameLocks
 }
 
-func NewFileRepository(storagePath string) FileRepository {
+func NewFileRepository(storagePath string, versioning VersioningOptions) FileRepository {
 	if err := os.MkdirAll(storagePath, 0755); err != nil {
 		panic(err)
 	}
-	return &fileRepository{storagePath: storagePath}
+	metadata, err := newMetadataStore(storagePath)
+	if err != nil {
+		panic(err)
+	}
+	trash, err := newTrashStore(storagePath)
+	if err != nil {
+		panic(err)
+	}
+	versions, err := newVersionStore(storagePath, versioning)
+	if err != nil {
+		panic(err)
+	}
+	return &fileRepository{
+		storagePath: storagePath,
+		metadata:    metadata,
+		versions:    versions,
+		trash:       trash,
+	}
+}
+
+// NewIndexedFileRepository создает хранилище, у которого List и Stream
+// работают по индексу метаданных, а не обходом каталогов. Новый индекс, а
+// также индекс после аварийного завершения или несохраненного изменения
+// заполняется по файлам на диске. Файлы, измененные в обход сервиса,
+// попадают в индекс только после RebuildFileIndex
+func NewIndexedFileRepository(ctx context.Context, storagePath string, versioning VersioningOptions) (FileRepository, error) {
+	repo := NewFileRepository(storagePath, versioning).(*fileRepository)
+	index, stale, err := openFileIndex(storagePath)
+	if err != nil {
+		return nil, err
+	}
+	repo.index = index
+
+	if stale {
+		if _, err := repo.rebuildIndex(ctx); err != nil {
+			index.close()
+			return nil, fmt.Errorf("build index failed: %w", err)
+		}
+	}
+	return repo, nil
+}
+
+// RebuildFileIndex пересобирает индекс хранилища storagePath по файлам на
+// диске и возвращает число проиндексированных файлов. Индекс блокируется
+// работающим сервером, поэтому пересборка выполняется при остановленном
+func RebuildFileIndex(ctx context.Context, storagePath string) (int, error) {
+	repo := NewFileRepository(storagePath, VersioningOptions{}).(*fileRepository)
+	index, _, err := openFileIndex(storagePath)
+	if err != nil {
+		return 0, err
+	}
+	defer index.close()
+	repo.index = index
+	return repo.rebuildIndex(ctx)
+}
+
+func (r *fileRepository) rebuildIndex(ctx context.Context) (int, error) {
+	return r.index.rebuild(func(put func(*entity.File) error) error {
+		return r.walkDisk(ctx)(&FileFilter{Recursive: true}, MaxPageSize, put, nil)
+	})
+}
+
+// Close освобождает индекс, если он есть
+func (r *fileRepository) Close() error {
+	if r.index == nil {
+		return nil
+	}
+	return r.index.close()
+}
+
+// reindex приводит записи индекса для names в соответствие с диском.
+// Вызывается под блокировкой имен после каждого изменения
+func (r *fileRepository) reindex(names ...string) error {
+	if r.index == nil {
+		return nil
+	}
+	for _, name := range names {
+		file, err := r.stat(name)
+		switch {
+		case os.IsNotExist(err):
+			err = r.index.remove(name)
+		case err == nil:
+			err = r.index.put(file)
+		}
+		if err != nil {
+			return fmt.Errorf("update index failed: %w", err)
+		}
+	}
+	return nil
 }
 
-func (r *fileRepository) Save(ctx context.Context, file *entity.File, data io.Reader) error {
-	path := filepath.Join(r.storagePath, file.Name)
+func (r *fileRepository) Save(ctx context.Context, file *entity.File, data io.Reader, opts SaveOptions) (err error) {
+	path, err := r.resolve(file.Name)
+	if err != nil {
+		return err
+	}
+	// Заведомо невыполнимое условие отклоняется до приема содержимого.
+	// Окончательная проверка все равно выполняется под блокировкой
+	if err = r.checkPrecondition(file.Name, opts.Precondition); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create folder failed: %w", err)
+	}
 
-	// Создаем временный файл
-	tempPath := path + ".tmp"
-	f, err := os.Create(tempPath)
+	// У каждой загрузки свой временный файл, поэтому параллельные загрузки
+	// одного имени не портят данные друг друга
+	f, err := createTemp(path)
 	if err != nil {
 		return fmt.Errorf("create temp file failed: %w", err)
 	}
+	tempPath := f.Name()
 
+	// Недописанный файл (обрыв соединения, ошибка записи) не должен остаться на диске
 	defer func() {
 		if err != nil {
 			os.Remove(tempPath)
 		}
 	}()
 
-	size, err := io.Copy(f, data)
+	// Хеш считается в том же проходе, что и запись на диск
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, hash), data)
 	if err != nil {
 		f.Close()
 		return fmt.Errorf("write failed: %w", err)
 	}
 
-	if err := f.Close(); err != nil {
+	if err = f.Close(); err != nil {
 		return fmt.Errorf("close failed: %w", err)
 	}
 
-	if err := os.Rename(tempPath, path); err != nil {
-		return fmt.Errorf("rename failed: %w", err)
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if opts.Checksum != "" && !strings.EqualFold(opts.Checksum, checksum) {
+		return fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, opts.Checksum, checksum)
+	}
+
+	if file.CreatedAt.IsZero() {
+		file.CreatedAt = time.Now()
+	}
+	if file.UpdatedAt.IsZero() {
+		file.UpdatedAt = time.Now()
+	}
+
+	meta := &fileMetadata{
+		SHA256:       checksum,
+		CreatedAt:    file.CreatedAt,
+		UpdatedAt:    file.UpdatedAt,
+		DeclaredSize: file.DeclaredSize,
+		Uploader:     file.Uploader,
+		ContentType:  file.ContentType,
+		Image:        newImageMetadata(file.Image),
+	}
+	switch opts.Conflict {
+	case ConflictFail:
+		err = r.commit(file.Name, meta, opts.Precondition, ensureAbsent(path), func() error {
+			return renameNoReplace(tempPath, path)
+		})
+	case ConflictRename:
+		file.Name, path, err = r.commitUnique(file.Name, path, tempPath, meta, opts.Precondition)
+	default:
+		err = r.commit(file.Name, meta, opts.Precondition, func(previous *fileMetadata) error {
+			return r.prepareReplace(file.Name, path, previous, meta)
+		}, func() error {
+			if err := os.Rename(tempPath, path); err != nil {
+				return fmt.Errorf("rename failed: %w", err)
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return err
 	}
 
 	file.Size = size
 	file.Path = path
+	file.Checksum = checksum
+	file.CreatedAt = meta.CreatedAt
 	return nil
 }
 
-func (r *fileRepository) Get(ctx context.Context, filename string) (*entity.File, io.ReadCloser, error) {
-	path := filepath.Join(r.storagePath, filename)
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return nil, nil, err // Возвращаем оригинальную ошибку
+// commit публикует содержимое под именем name вместе с метаданными.
+// Блокировка имени не дает параллельной операции вклиниться между проверкой
+// условия, заменой файла и записью метаданных. prepare получает метаданные
+// текущего содержимого до записи новых. Метаданные записываются до
+// публикации и возвращаются к прежним, если publish не удался, поэтому
+// содержимое не остается с чужими метаданными. Ошибка индекса после
+// публикации не отменяет операцию: индекс пересоберется при следующем запуске
+func (r *fileRepository) commit(name string, meta *fileMetadata, cond Precondition, prepare func(previous *fileMetadata) error, publish func() error) error {
+	unlock := r.locks.lock(name)
+	defer unlock()
+
+	if err := r.checkPrecondition(name, cond); err != nil {
+		return err
+	}
+	previous, err := r.metadata.load(name)
+	if err != nil {
+		return fmt.Errorf("load metadata failed: %w", err)
+	}
+	if prepare != nil {
+		if err := prepare(previous); err != nil {
+			return err
+		}
 	}
 
-	info, err := os.Stat(path)
+	restore, err := r.metadata.snapshot(name)
+	if err != nil {
+		return fmt.Errorf("load metadata failed: %w", err)
+	}
+	if err := r.metadata.save(name, meta); err != nil {
+		return fmt.Errorf("save metadata failed: %w", err)
+	}
+	if err := publish(); err != nil {
+		if restoreErr := restore(); restoreErr != nil {
+			return errors.Join(err, fmt.Errorf("restore metadata failed: %w", restoreErr))
+		}
+		return err
+	}
+	if err := r.reindex(name); err != nil {
+		r.index.markDirty()
+	}
+	return nil
+}
+
+// prepareReplace сохраняет текущее содержимое как версию перед заменой.
+// При перезаписи сохраняется время создания исходного файла
+func (r *fileRepository) prepareReplace(name, path string, previous, meta *fileMetadata) error {
+	if err := r.archive(name, path, previous); err != nil {
+		return fmt.Errorf("archive previous version failed: %w", err)
+	}
+	if !previous.CreatedAt.IsZero() {
+		meta.CreatedAt = previous.CreatedAt
+	}
+	return nil
+}
+
+// ensureAbsent не дает записать метаданные поверх метаданных существующего
+// файла. Под блокировкой имени файл не может появиться до публикации, а
+// renameNoReplace все равно не заменит созданный в обход сервиса
+func ensureAbsent(path string) func(*fileMetadata) error {
+	return func(*fileMetadata) error {
+		if _, err := os.Lstat(path); err == nil {
+			return &os.PathError{Op: "save", Path: path, Err: os.ErrExist}
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+}
+
+// checkPrecondition проверяет условие на текущую версию файла name
+func (r *fileRepository) checkPrecondition(name string, cond Precondition) error {
+	if !cond.isSet() {
+		return nil
+	}
+	current, err := r.stat(name)
+	if os.IsNotExist(err) {
+		return cond.check(nil)
+	}
+	if err != nil {
+		return err
+	}
+	return cond.check(current)
+}
+
+// maxRenameAttempts ограничивает перебор имен вида "photo (N).jpg"
+const maxRenameAttempts = 1000
+
+// commitUnique сохраняет файл под именем name, а если оно занято - под
+// первым свободным "photo (1).jpg", "photo (2).jpg" и т.д.
+func (r *fileRepository) commitUnique(name, path, tempPath string, meta *fileMetadata, cond Precondition) (string, string, error) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+
+	for i := 0; i <= maxRenameAttempts; i++ {
+		candidate, candidatePath := name, path
+		if i > 0 {
+			// Условие относится к запрошенному имени, а не к подобранному
+			candidate = fmt.Sprintf("%s (%d)%s", stem, i, ext)
+			candidatePath = filepath.Join(r.storagePath, filepath.FromSlash(candidate))
+			cond = Precondition{}
+		}
+
+		err := r.commit(candidate, meta, cond, ensureAbsent(candidatePath), func() error {
+			return renameNoReplace(tempPath, candidatePath)
+		})
+		if err == nil {
+			return candidate, candidatePath, nil
+		}
+		if !os.IsExist(err) {
+			return "", "", err
+		}
+	}
+	return "", "", &os.PathError{Op: "save", Path: name, Err: os.ErrExist}
+}
+
+func (r *fileRepository) Get(ctx context.Context, filename string) (*entity.File, io.ReadSeekCloser, error) {
+	file, err := r.stat(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(file.Path)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	return file, f, nil
+}
+
+func (r *fileRepository) Stat(ctx context.Context, filename string) (*entity.File, error) {
+	return r.stat(filename)
+}
+
+func (r *fileRepository) List(ctx context.Context, opts ListOptions) (*ListResult, error) {
+	return listFiles(opts, r.walk(ctx), false)
+}
+
+// Stream обходит каталог порциями через ReadDir(n), не загружая весь список
+// в память, и передает найденные файлы в fn пачками по batchSize
+func (r *fileRepository) Stream(ctx context.Context, filter FileFilter, batchSize int, fn func([]*entity.File) error) error {
+	return streamFiles(filter, batchSize, r.walk(ctx), fn)
+}
+
+// walk обходит файлы хранилища по индексу, а без него - по каталогам
+func (r *fileRepository) walk(ctx context.Context) walkFunc {
+	if r.index != nil {
+		return r.walkIndex(ctx)
+	}
+	return r.walkDisk(ctx)
+}
+
+// walkIndex отбирает файлы по записям индекса, не обращаясь к диску
+func (r *fileRepository) walkIndex(ctx context.Context) walkFunc {
+	return func(filter *FileFilter, readSize int, onFile func(*entity.File) error, onFolder func(string)) error {
+		prefix := ""
+		if folder := path.Clean(filter.Folder); folder != "." && folder != "" {
+			if err := validateName(filter.Folder); err != nil {
+				return err
+			}
+			prefix = folder + "/"
+		}
+
+		return r.index.walk(ctx, prefix, filter.Recursive, readSize, func(name string, entry *indexEntry) error {
+			if !filter.matchName(path.Base(name)) {
+				return nil
+			}
+			file := entry.file(name, filepath.Join(r.storagePath, filepath.FromSlash(name)))
+			if !filter.matchFile(file) {
+				return nil
+			}
+			return onFile(file)
+		}, onFolder)
+	}
+}
+
+// walkDisk обходит каталоги хранилища. Служебные каталоги (с точки) пропускаются
+func (r *fileRepository) walkDisk(ctx context.Context) walkFunc {
+	return func(filter *FileFilter, readSize int, onFile func(*entity.File) error, onFolder func(string)) error {
+		match := func(name string, entry os.DirEntry) (*entity.File, error) {
+			return r.matchEntry(name, entry, filter)
+		}
+		return walkTree(ctx, r.storagePath, filter, readSize, match, onFile, onFolder)
+	}
+}
+
+// matchEntry возвращает файл для записи каталога или nil, если запись
+// не проходит фильтр
+func (r *fileRepository) matchEntry(name string, entry os.DirEntry, filter *FileFilter) (*entity.File, error) {
+	// Фильтры по имени отсекают лишние файлы до stat и чтения метаданных
+	if !filter.matchName(path.Base(name)) {
+		return nil, nil
+	}
+
+	info, err := entry.Info()
+	if err != nil {
+		return nil, nil
+	}
+
+	meta, err := r.metadata.load(name)
+	if err != nil {
+		return nil, err
+	}
+
 	file := &entity.File{
-		Name:      filename,
+		Name:      name,
 		Size:      info.Size(),
 		CreatedAt: info.ModTime(),
 		UpdatedAt: info.ModTime(),
-		Path:      path,
+		Path:      filepath.Join(r.storagePath, filepath.FromSlash(name)),
 	}
+	meta.apply(file)
+	if !filter.matchFile(file) {
+		return nil, nil
+	}
+	return file, nil
+}
+
+// Delete переносит файл в корзину. История версий привязана к имени и
+// остается на месте, пока запись корзины не будет удалена окончательно
+func (r *fileRepository) Delete(ctx context.Context, filename string, cond Precondition) (*entity.TrashItem, error) {
+	unlock := r.locks.lock(filename)
+	defer unlock()
 
-	f, err := os.Open(path)
+	file, err := r.stat(filename)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	if err := cond.check(file); err != nil {
+		return nil, err
+	}
+	meta, err := r.metadata.load(filename)
+	if err != nil {
+		return nil, err
 	}
 
-	return file, f, nil
+	item, err := r.trash.put(file, meta)
+	if err != nil {
+		return nil, fmt.Errorf("move to trash failed: %w", err)
+	}
+	if err := r.metadata.remove(filename); err != nil {
+		return nil, err
+	}
+	// Файл уже в корзине: ошибка индекса не отменяет удаление, индекс
+	// пересоберется при следующем запуске
+	if err := r.reindex(filename); err != nil {
+		r.index.markDirty()
+	}
+	return item, nil
+}
+
+func (r *fileRepository) ListTrash(ctx context.Context) ([]*entity.TrashItem, error) {
+	return r.trash.list()
 }
 
-func (r *fileRepository) List(ctx context.Context) ([]*entity.File, error) {
-	entries, err := os.ReadDir(r.storagePath)
+// RestoreTrash возвращает файл из корзины под исходным именем или под
+// destination, если оно задано. Существующий файл не заменяется
+func (r *fileRepository) RestoreTrash(ctx context.Context, id, destination string) (*entity.File, error) {
+	info, err := r.trash.get(id)
 	if err != nil {
 		return nil, err
 	}
+	name := info.Filename
+	if destination != "" {
+		name = destination
+	}
+	target, err := r.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return nil, fmt.Errorf("create folder failed: %w", err)
+	}
+	err = r.commit(name, &info.Metadata, Precondition{}, ensureAbsent(target), func() error {
+		return renameNoReplace(r.trash.contentPath(id), target)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.trash.remove(id); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return r.stat(name)
+}
+
+// PurgeTrash окончательно удаляет файлы, попавшие в корзину раньше
+// deletedBefore, вместе с историей версий, если имя больше не используется
+func (r *fileRepository) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	items, err := r.trash.list()
+	if err != nil {
+		return 0, err
+	}
+
+	kept := make(map[string]bool)
+	var purged []string
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return len(purged), err
+		}
+		if !item.DeletedAt.Before(deletedBefore) {
+			kept[item.Filename] = true
+			continue
+		}
+		if err := r.trash.remove(item.ID); err != nil && !os.IsNotExist(err) {
+			return len(purged), err
+		}
+		purged = append(purged, item.Filename)
+	}
 
-	var files []*entity.File
-	for _, entry := range entries {
-		if entry.IsDir() {
+	for _, name := range purged {
+		if kept[name] {
 			continue
 		}
+		if err := r.pruneDeleted(name); err != nil {
+			return len(purged), err
+		}
+	}
+	return len(purged), nil
+}
+
+// pruneDeleted удаляет историю версий name, если файла с этим именем нет
+func (r *fileRepository) pruneDeleted(name string) error {
+	unlock := r.locks.lock(name)
+	defer unlock()
+
+	if _, err := r.stat(name); !os.IsNotExist(err) {
+		return nil
+	}
+	_, err := r.versions.prune(name, 0)
+	return err
+}
+
+// ListVersions возвращает сохраненные версии файла от старых к новым.
+// Версии привязаны к имени и остаются доступны, даже если файла с этим
+// именем сейчас нет (например, после переименования)
+func (r *fileRepository) ListVersions(ctx context.Context, filename string) ([]*entity.FileVersion, error) {
+	if _, err := r.resolve(filename); err != nil {
+		return nil, err
+	}
+	return r.versions.list(filename)
+}
+
+func (r *fileRepository) GetVersion(ctx context.Context, filename string, number int64) (*entity.FileVersion, io.ReadSeekCloser, error) {
+	if _, err := r.resolve(filename); err != nil {
+		return nil, nil, err
+	}
+	version, err := r.versions.get(filename, number)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(version.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return version, f, nil
+}
+
+// RestoreVersion делает содержимое версии текущим. Сама версия остается
+// в истории, а заменяемое содержимое становится новой версией
+func (r *fileRepository) RestoreVersion(ctx context.Context, filename string, number int64) (*entity.File, error) {
+	version, reader, err := r.GetVersion(ctx, filename, number)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	file := &entity.File{
+		Name:        filename,
+		Uploader:    version.Uploader,
+		ContentType: version.ContentType,
+		Image:       version.Image,
+	}
+	if err := r.Save(ctx, file, reader, SaveOptions{Checksum: version.Checksum}); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// PruneVersions оставляет keep последних версий и возвращает число удаленных
+func (r *fileRepository) PruneVersions(ctx context.Context, filename string, keep int) (int, error) {
+	if _, err := r.resolve(filename); err != nil {
+		return 0, err
+	}
+	if keep < 0 {
+		return 0, fmt.Errorf("%w: negative keep %d", ErrInvalidVersion, keep)
+	}
+
+	unlock := r.locks.lock(filename)
+	defer unlock()
+
+	removed, err := r.versions.prune(filename, keep)
+	return len(removed), err
+}
+
+// Rename перемещает файл вместе с метаданными, при необходимости создавая
+// папку назначения. Без overwrite существующий файл назначения не заменяется,
+// и возвращается ошибка os.ErrExist
+func (r *fileRepository) Rename(ctx context.Context, from, to string, overwrite bool) (*entity.File, error) {
+	unlock := r.locks.lock(from, to)
+	defer unlock()
+
+	file, err := r.stat(from)
+	if err != nil {
+		return nil, err
+	}
+	target, err := r.resolve(to)
+	if err != nil {
+		return nil, err
+	}
+	if target == file.Path {
+		return file, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return nil, fmt.Errorf("create folder failed: %w", err)
+	}
+	if overwrite {
+		err = r.archiveExisting(to, target)
+	} else {
+		err = ensureAbsent(target)(nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Как и в commit, метаданные переносятся раньше содержимого: если перенос
+	// содержимого не удался, обе записи возвращаются как были
+	restoreFrom, err := r.metadata.snapshot(from)
+	if err != nil {
+		return nil, fmt.Errorf("load metadata failed: %w", err)
+	}
+	restoreTo, err := r.metadata.snapshot(to)
+	if err != nil {
+		return nil, fmt.Errorf("load metadata failed: %w", err)
+	}
+	if err := r.metadata.rename(from, to); err != nil {
+		return nil, fmt.Errorf("rename metadata failed: %w", err)
+	}
+	if overwrite {
+		err = os.Rename(file.Path, target)
+	} else {
+		err = renameNoReplace(file.Path, target)
+	}
+	if err != nil {
+		if restoreErr := errors.Join(restoreFrom(), restoreTo()); restoreErr != nil {
+			return nil, errors.Join(err, fmt.Errorf("restore metadata failed: %w", restoreErr))
+		}
+		return nil, err
+	}
+	if err := r.reindex(from, to); err != nil {
+		r.index.markDirty()
+	}
+
+	file.Name = to
+	file.Path = target
+	return file, nil
+}
 
-		info, err := entry.Info()
+// Copy копирует содержимое файла внутри хранилища. Копия получает новые
+// время создания и изменения, контрольная сумма и тип содержимого
+// переносятся из исходного файла
+func (r *fileRepository) Copy(ctx context.Context, from, to string, overwrite bool) (_ *entity.File, err error) {
+	target, err := r.resolve(to)
+	if err != nil {
+		return nil, err
+	}
+	if from == to {
+		source, err := r.stat(from)
 		if err != nil {
-			continue
+			return nil, err
+		}
+		if !overwrite {
+			return nil, &os.PathError{Op: "copy", Path: to, Err: os.ErrExist}
 		}
+		return source, nil
+	}
 
-		files = append(files, &entity.File{
-			Name:      entry.Name(),
-			Size:      info.Size(),
-			CreatedAt: info.ModTime(),
-			UpdatedAt: info.ModTime(),
-			Path:      filepath.Join(r.storagePath, entry.Name()),
-		})
+	// Исходный файл читается под его блокировкой, чтобы хеш и тип содержимого
+	// относились к скопированным байтам. Блокировка назначения берется уже в commit
+	unlock := r.locks.lock(from)
+	source, tempPath, err := r.copyToTemp(from, target, overwrite)
+	unlock()
+	if err != nil {
+		return nil, err
 	}
-	sort.Slice(files, func(i, j int) bool {
-		return files[i].CreatedAt.After(files[j].CreatedAt)
+	defer func() {
+		if err != nil {
+			os.Remove(tempPath)
+		}
+	}()
+
+	now := time.Now()
+	meta := &fileMetadata{
+		SHA256:      source.Checksum,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		ContentType: source.ContentType,
+		Image:       newImageMetadata(source.Image),
+	}
+	prepare := ensureAbsent(target)
+	if overwrite {
+		prepare = func(previous *fileMetadata) error {
+			if err := r.archive(to, target, previous); err != nil {
+				return fmt.Errorf("archive previous version failed: %w", err)
+			}
+			return nil
+		}
+	}
+	err = r.commit(to, meta, Precondition{}, prepare, func() error {
+		if !overwrite {
+			return renameNoReplace(tempPath, target)
+		}
+		return os.Rename(tempPath, target)
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.File{
+		Name:        to,
+		Size:        source.Size,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Path:        target,
+		Checksum:    source.Checksum,
+		ContentType: source.ContentType,
+		Image:       source.Image,
+	}, nil
+}
+
+// copyToTemp копирует содержимое файла from во временный файл рядом с target.
+// Без overwrite занятое имя назначения отклоняется до копирования
+func (r *fileRepository) copyToTemp(from, target string, overwrite bool) (_ *entity.File, _ string, err error) {
+	source, err := r.stat(from)
+	if err != nil {
+		return nil, "", err
+	}
+	if !overwrite {
+		if err := ensureAbsent(target)(nil); err != nil {
+			return nil, "", err
+		}
+	}
+	if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return nil, "", fmt.Errorf("create folder failed: %w", err)
+	}
 
-	return files, nil
+	src, err := os.Open(source.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer src.Close()
+
+	dst, err := createTemp(target)
+	if err != nil {
+		return nil, "", fmt.Errorf("create temp file failed: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(dst.Name())
+		}
+	}()
+
+	if source.Checksum == "" {
+		// У файлов, сохраненных до появления метаданных, хеша нет - считаем его при копировании
+		hash := sha256.New()
+		_, err = io.Copy(io.MultiWriter(dst, hash), src)
+		source.Checksum = hex.EncodeToString(hash.Sum(nil))
+	} else {
+		err = copyContents(dst, src)
+	}
+	if err != nil {
+		dst.Close()
+		return nil, "", fmt.Errorf("copy failed: %w", err)
+	}
+	if err = dst.Close(); err != nil {
+		return nil, "", fmt.Errorf("close failed: %w", err)
+	}
+	return source, dst.Name(), nil
+}
+
+// archive сохраняет текущее содержимое файла как версию, если версионирование
+// включено. meta - метаданные текущего содержимого
+func (r *fileRepository) archive(filename, path string, meta *fileMetadata) error {
+	if !r.versions.opts.Enabled {
+		return nil
+	}
+	return r.versions.archive(filename, path, meta)
+}
+
+func (r *fileRepository) archiveExisting(filename, path string) error {
+	if !r.versions.opts.Enabled {
+		return nil
+	}
+	meta, err := r.metadata.load(filename)
+	if err != nil {
+		return err
+	}
+	if err := r.versions.archive(filename, path, meta); err != nil {
+		return fmt.Errorf("archive previous version failed: %w", err)
+	}
+	return nil
+}
+
+// createTemp создает уникальный временный файл рядом с path. Имя начинается
+// с точки, поэтому недописанный файл не попадает в списки
+func createTemp(path string) (*os.File, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	// CreateTemp создает файл с правами 0600, а файлы хранилища всегда были 0644
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// renameNoReplace атомарно переносит файл, только если target не существует.
+// Проверка "есть ли файл" перед os.Rename оставила бы гонку с параллельной
+// загрузкой, поэтому содержимое сначала связывается жесткой ссылкой: link
+// завершается ошибкой EEXIST, если имя уже занято
+func renameNoReplace(source, target string) error {
+	if err := os.Link(source, target); err != nil {
+		return err
+	}
+	if err := os.Remove(source); err != nil {
+		os.Remove(target)
+		return err
+	}
+	return nil
+}
+
+// stat возвращает файл хранилища с метаданными. Для папок возвращается
+// ошибка "не существует": они не являются файлами хранилища
+func (r *fileRepository) stat(filename string) (*entity.File, error) {
+	path, err := r.resolve(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err // Возвращаем оригинальную ошибку
+	}
+	if info.IsDir() {
+		return nil, &os.PathError{Op: "stat", Path: path, Err: os.ErrNotExist}
+	}
+
+	meta, err := r.metadata.load(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &entity.File{
+		Name:      filename,
+		Size:      info.Size(),
+		CreatedAt: info.ModTime(),
+		UpdatedAt: info.ModTime(),
+		Path:      path,
+	}
+	meta.apply(file)
+	return file, nil
+}
+
+// resolve переводит имя файла вида "project/2024/photo.jpg" в путь на диске.
+// Имена уже проверены в usecase, но репозиторий сам не дает выйти за
+// пределы хранилища или попасть в служебные каталоги
+func (r *fileRepository) resolve(name string) (string, error) {
+	if err := validateName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(r.storagePath, filepath.FromSlash(name)), nil
+}
+
+// validateName не дает выйти за пределы хранилища или попасть в служебные каталоги
+func validateName(name string) error {
+	if name == "" || !filepath.IsLocal(filepath.FromSlash(name)) || isHiddenPath(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidPath, name)
+	}
+	return nil
+}
+
+func isHiddenPath(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return true
+		}
+	}
+	return false
 }
@@ -3,9 +3,13 @@ package repository
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -15,7 +19,7 @@ import (
 )
 
 func TestFileRepository_SaveAndGet(t *testing.T) {
-	repo := NewFileRepository(t.TempDir())
+	repo := NewFileRepository(t.TempDir(), VersioningOptions{})
 	ctx := context.Background()
 
 	t.Run("success save and get", func(t *testing.T) {
@@ -23,7 +27,7 @@ func TestFileRepository_SaveAndGet(t *testing.T) {
 		file := &entity.File{Name: "test.txt", CreatedAt: time.Now()}
 
 		// Сохраняем файл
-		err := repo.Save(ctx, file, bytes.NewReader(data))
+		err := repo.Save(ctx, file, bytes.NewReader(data), SaveOptions{})
 		require.NoError(t, err)
 
 		// Получаем файл
@@ -45,8 +49,126 @@ func TestFileRepository_SaveAndGet(t *testing.T) {
 	})
 }
 
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestFileRepository_Checksum(t *testing.T) {
+	dir := t.TempDir()
+	repo := NewFileRepository(dir, VersioningOptions{})
+	ctx := context.Background()
+
+	data := []byte("checksum data")
+	sum := sha256.Sum256(data)
+	expected := hex.EncodeToString(sum[:])
+
+	file := &entity.File{Name: "sum.txt"}
+	require.NoError(t, repo.Save(ctx, file, bytes.NewReader(data), SaveOptions{Checksum: expected}))
+	require.Equal(t, expected, file.Checksum)
+
+	// Хеш сохраняется вместе с файлом и переживает перезапуск
+	repo = NewFileRepository(dir, VersioningOptions{})
+	saved, reader, err := repo.Get(ctx, "sum.txt")
+	require.NoError(t, err)
+	reader.Close()
+	require.Equal(t, expected, saved.Checksum)
+
+	result, err := repo.List(ctx, ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Files, 1)
+	require.Equal(t, expected, result.Files[0].Checksum)
+
+	// Несовпадение хеша не затирает уже сохраненный файл
+	err = repo.Save(ctx, &entity.File{Name: "sum.txt"}, bytes.NewReader([]byte("corrupted")), SaveOptions{Checksum: expected})
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+
+	_, reader, err = repo.Get(ctx, "sum.txt")
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	require.Equal(t, data, content)
+}
+
+func TestFileRepository_Metadata(t *testing.T) {
+	dir := t.TempDir()
+	repo := NewFileRepository(dir, VersioningOptions{})
+	ctx := context.Background()
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	file := &entity.File{
+		Name:         "photo.jpg",
+		CreatedAt:    created,
+		UpdatedAt:    created,
+		DeclaredSize: 4,
+		Uploader:     "alice",
+		ContentType:  "image/jpeg",
+	}
+	require.NoError(t, repo.Save(ctx, file, bytes.NewReader([]byte("v1")), SaveOptions{}))
+
+	// Перезапись обновляет UpdatedAt, но не время создания
+	updated := created.Add(time.Hour)
+	overwrite := &entity.File{Name: "photo.jpg", CreatedAt: updated, UpdatedAt: updated, Uploader: "bob", ContentType: "image/jpeg"}
+	require.NoError(t, repo.Save(ctx, overwrite, bytes.NewReader([]byte("v2")), SaveOptions{}))
+	require.True(t, created.Equal(overwrite.CreatedAt))
+
+	saved, reader, err := NewFileRepository(dir, VersioningOptions{}).Get(ctx, "photo.jpg")
+	require.NoError(t, err)
+	reader.Close()
+	require.True(t, created.Equal(saved.CreatedAt))
+	require.True(t, updated.Equal(saved.UpdatedAt))
+	require.Equal(t, "bob", saved.Uploader)
+	require.Equal(t, "image/jpeg", saved.ContentType)
+	require.Equal(t, int64(2), saved.Size)
+}
+
+func TestFileRepository_SaveInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	repo := NewFileRepository(dir, VersioningOptions{})
+	ctx := context.Background()
+
+	// Клиент отключился после первой порции данных
+	reader := &failingReader{data: []byte("partial"), err: context.Canceled}
+	err := repo.Save(ctx, &entity.File{Name: "broken.bin"}, reader, SaveOptions{})
+	require.ErrorIs(t, err, context.Canceled)
+
+	result, err := repo.List(ctx, ListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, result.Files)
+
+	leftovers, _ := filepath.Glob(filepath.Join(dir, ".broken.bin.*.tmp"))
+	require.Empty(t, leftovers)
+}
+
+func TestFileRepository_Delete(t *testing.T) {
+	repo := NewFileRepository(t.TempDir(), VersioningOptions{})
+	ctx := context.Background()
+
+	err := repo.Save(ctx, &entity.File{Name: "delete.txt"}, bytes.NewReader([]byte("data")), SaveOptions{})
+	require.NoError(t, err)
+
+	_, err = repo.Delete(ctx, "delete.txt", Precondition{})
+	require.NoError(t, err)
+
+	_, _, err = repo.Get(ctx, "delete.txt")
+	require.True(t, os.IsNotExist(err))
+
+	_, err = repo.Delete(ctx, "delete.txt", Precondition{})
+	require.True(t, os.IsNotExist(err))
+}
+
 func TestFileRepository_Concurrency(t *testing.T) {
-	repo := NewFileRepository(t.TempDir())
+	repo := NewFileRepository(t.TempDir(), VersioningOptions{})
 	ctx := context.Background()
 	var wg sync.WaitGroup
 
@@ -56,13 +178,826 @@ func TestFileRepository_Concurrency(t *testing.T) {
 			defer wg.Done()
 			filename := fmt.Sprintf("file_%d.txt", i)
 			data := []byte(fmt.Sprintf("content %d", i))
-			err := repo.Save(ctx, &entity.File{Name: filename}, bytes.NewReader(data))
+			err := repo.Save(ctx, &entity.File{Name: filename}, bytes.NewReader(data), SaveOptions{})
+			require.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	result, err := repo.List(ctx, ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Files, 10)
+}
+
+func TestFileRepository_ListOptions(t *testing.T) {
+	repo := NewFileRepository(t.TempDir(), VersioningOptions{})
+	ctx := context.Background()
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"a.jpg", "b.png", "c.jpg", "d.jpg", "e.txt"} {
+		file := &entity.File{Name: name, CreatedAt: base.Add(time.Duration(i) * time.Hour)}
+		data := bytes.Repeat([]byte("x"), (i+1)*10)
+		require.NoError(t, repo.Save(ctx, file, bytes.NewReader(data), SaveOptions{}))
+	}
+
+	names := func(files []*entity.File) []string {
+		var result []string
+		for _, f := range files {
+			result = append(result, f.Name)
+		}
+		return result
+	}
+
+	t.Run("default order is newest first", func(t *testing.T) {
+		result, err := repo.List(ctx, ListOptions{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"e.txt", "d.jpg", "c.jpg", "b.png", "a.jpg"}, names(result.Files))
+		require.Empty(t, result.NextPageToken)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		result, err := repo.List(ctx, ListOptions{
+			FileFilter: FileFilter{
+				NamePattern:   "*.jpg",
+				MinSize:       20,
+				CreatedBefore: base.Add(4 * time.Hour),
+			},
+			SortBy:        SortByName,
+			SortAscending: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"c.jpg", "d.jpg"}, names(result.Files))
+
+		result, err = repo.List(ctx, ListOptions{FileFilter: FileFilter{NamePrefix: "b"}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"b.png"}, names(result.Files))
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		opts := ListOptions{PageSize: 2, SortBy: SortBySize, SortAscending: true}
+
+		var all []string
+		for {
+			result, err := repo.List(ctx, opts)
+			require.NoError(t, err)
+			all = append(all, names(result.Files)...)
+			if result.NextPageToken == "" {
+				break
+			}
+			opts.PageToken = result.NextPageToken
+		}
+		require.Equal(t, []string{"a.jpg", "b.png", "c.jpg", "d.jpg", "e.txt"}, all)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := repo.List(ctx, ListOptions{PageToken: "garbage"})
+		require.ErrorIs(t, err, ErrInvalidListOptions)
+
+		_, err = repo.List(ctx, ListOptions{FileFilter: FileFilter{NamePattern: "[a-"}})
+		require.ErrorIs(t, err, ErrInvalidListOptions)
+	})
+}
+
+func TestFileRepository_Stream(t *testing.T) {
+	repo := NewFileRepository(t.TempDir(), VersioningOptions{})
+	ctx := context.Background()
+
+	for i := 0; i < 7; i++ {
+		name := fmt.Sprintf("stream_%d.jpg", i)
+		require.NoError(t, repo.Save(ctx, &entity.File{Name: name}, bytes.NewReader([]byte("data")), SaveOptions{}))
+	}
+	require.NoError(t, repo.Save(ctx, &entity.File{Name: "other.txt"}, bytes.NewReader([]byte("data")), SaveOptions{}))
+
+	var batches []int
+	seen := map[string]bool{}
+	err := repo.Stream(ctx, FileFilter{NamePattern: "*.jpg"}, 3, func(files []*entity.File) error {
+		batches = append(batches, len(files))
+		for _, f := range files {
+			seen[f.Name] = true
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{3, 3, 1}, batches)
+	require.Len(t, seen, 7)
+
+	// Ошибка отправки прерывает обход
+	stopErr := fmt.Errorf("client gone")
+	err = repo.Stream(ctx, FileFilter{}, 2, func(files []*entity.File) error {
+		return stopErr
+	})
+	require.ErrorIs(t, err, stopErr)
+}
+
+func TestFileRepository_Folders(t *testing.T) {
+	repo := NewFileRepository(t.TempDir(), VersioningOptions{})
+	ctx := context.Background()
+
+	for _, name := range []string{"root.jpg", "project/a.jpg", "project/2024/b.jpg", "other/c.jpg"} {
+		require.NoError(t, repo.Save(ctx, &entity.File{Name: name}, bytes.NewReader([]byte(name)), SaveOptions{}))
+	}
+
+	names := func(result *ListResult) []string {
+		var names []string
+		for _, f := range result.Files {
+			names = append(names, f.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	t.Run("root lists files and folders", func(t *testing.T) {
+		result, err := repo.List(ctx, ListOptions{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"root.jpg"}, names(result))
+		require.Equal(t, []string{"other", "project"}, result.Folders)
+	})
+
+	t.Run("folder scope", func(t *testing.T) {
+		result, err := repo.List(ctx, ListOptions{FileFilter: FileFilter{Folder: "project"}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"project/a.jpg"}, names(result))
+		require.Equal(t, []string{"project/2024"}, result.Folders)
+
+		result, err = repo.List(ctx, ListOptions{FileFilter: FileFilter{Folder: "project", Recursive: true}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"project/2024/b.jpg", "project/a.jpg"}, names(result))
+
+		result, err = repo.List(ctx, ListOptions{FileFilter: FileFilter{Folder: "missing"}})
+		require.NoError(t, err)
+		require.Empty(t, result.Files)
+	})
+
+	t.Run("move between folders", func(t *testing.T) {
+		moved, err := repo.Rename(ctx, "project/2024/b.jpg", "archive/b.jpg", false)
+		require.NoError(t, err)
+		require.Equal(t, "archive/b.jpg", moved.Name)
+		require.NotEmpty(t, moved.Checksum)
+
+		_, _, err = repo.Get(ctx, "project/2024/b.jpg")
+		require.True(t, os.IsNotExist(err))
+
+		file, reader, err := repo.Get(ctx, "archive/b.jpg")
+		require.NoError(t, err)
+		data, _ := io.ReadAll(reader)
+		reader.Close()
+		require.Equal(t, []byte("project/2024/b.jpg"), data)
+		require.Equal(t, moved.Checksum, file.Checksum)
+	})
+
+	t.Run("folders are not files", func(t *testing.T) {
+		_, _, err := repo.Get(ctx, "project")
+		require.True(t, os.IsNotExist(err))
+		_, err = repo.Delete(ctx, "project", Precondition{})
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("paths outside storage are rejected", func(t *testing.T) {
+		for _, name := range []string{"../escape.txt", "/etc/passwd", ".meta/root.jpg.json"} {
+			err := repo.Save(ctx, &entity.File{Name: name}, bytes.NewReader(nil), SaveOptions{})
+			require.ErrorIs(t, err, ErrInvalidPath, name)
+		}
+	})
+}
+
+func TestFileRepository_RenameOverwrite(t *testing.T) {
+	storage := t.TempDir()
+	repo := NewFileRepository(storage, VersioningOptions{})
+	ctx := context.Background()
+
+	save := func(name, content string) {
+		require.NoError(t, repo.Save(ctx, &entity.File{Name: name}, bytes.NewReader([]byte(content)), SaveOptions{}))
+	}
+	read := func(name string) string {
+		_, reader, err := repo.Get(ctx, name)
+		require.NoError(t, err)
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	save("a.jpg", "first")
+	save("b.jpg", "second")
+
+	// Без overwrite оба файла остаются на месте
+	_, err := repo.Rename(ctx, "a.jpg", "b.jpg", false)
+	require.True(t, os.IsExist(err))
+	require.Equal(t, "first", read("a.jpg"))
+	require.Equal(t, "second", read("b.jpg"))
+
+	// Переименование в себя ничего не делает
+	_, err = repo.Rename(ctx, "a.jpg", "a.jpg", false)
+	require.NoError(t, err)
+	require.Equal(t, "first", read("a.jpg"))
+
+	file, err := repo.Rename(ctx, "a.jpg", "b.jpg", true)
+	require.NoError(t, err)
+	require.Equal(t, "first", read("b.jpg"))
+
+	stored, reader, err := repo.Get(ctx, "b.jpg")
+	require.NoError(t, err)
+	reader.Close()
+	require.Equal(t, file.Checksum, stored.Checksum)
+
+	_, _, err = repo.Get(ctx, "a.jpg")
+	require.True(t, os.IsNotExist(err))
+
+	_, err = repo.Rename(ctx, "a.jpg", "c.jpg", true)
+	require.True(t, os.IsNotExist(err))
+
+	t.Run("source without metadata", func(t *testing.T) {
+		// Файл, положенный в хранилище в обход сервиса, не получает
+		// метаданные замененного файла
+		require.NoError(t, repo.Save(ctx, &entity.File{Name: "d.jpg", Uploader: "alice", ContentType: "image/jpeg"},
+			bytes.NewReader([]byte("third")), SaveOptions{}))
+		require.NoError(t, os.WriteFile(filepath.Join(storage, "manual.jpg"), []byte("manual"), 0644))
+
+		_, err := repo.Rename(ctx, "manual.jpg", "d.jpg", true)
+		require.NoError(t, err)
+		require.Equal(t, "manual", read("d.jpg"))
+
+		stored, reader, err := repo.Get(ctx, "d.jpg")
+		require.NoError(t, err)
+		reader.Close()
+		require.Empty(t, stored.Uploader)
+		require.Empty(t, stored.ContentType)
+		require.Empty(t, stored.Checksum)
+	})
+
+	t.Run("content move fails", func(t *testing.T) {
+		// Метаданные переносятся первыми и возвращаются, если содержимое
+		// не удалось переместить: на месте назначения непустая папка
+		require.NoError(t, repo.Save(ctx, &entity.File{Name: "e.jpg", Uploader: "bob"},
+			bytes.NewReader([]byte("fourth")), SaveOptions{}))
+		require.NoError(t, os.MkdirAll(filepath.Join(storage, "f.jpg", "inner"), 0755))
+
+		_, err := repo.Rename(ctx, "e.jpg", "f.jpg", true)
+		require.Error(t, err)
+
+		stored, reader, err := repo.Get(ctx, "e.jpg")
+		require.NoError(t, err)
+		reader.Close()
+		require.Equal(t, "bob", stored.Uploader)
+		require.Equal(t, "fourth", read("e.jpg"))
+		_, err = os.Stat(repo.(*fileRepository).metadata.filePath("f.jpg"))
+		require.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestFileRepository_Copy(t *testing.T) {
+	storage := t.TempDir()
+	repo := NewFileRepository(storage, VersioningOptions{})
+	ctx := context.Background()
+
+	content := []byte("image content")
+	source := &entity.File{
+		Name:        "a.jpg",
+		CreatedAt:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Uploader:    "alice",
+		ContentType: "image/jpeg",
+	}
+	require.NoError(t, repo.Save(ctx, source, bytes.NewReader(content), SaveOptions{}))
+
+	copied, err := repo.Copy(ctx, "a.jpg", "copies/a.jpg", false)
+	require.NoError(t, err)
+	require.Equal(t, "copies/a.jpg", copied.Name)
+	require.Equal(t, source.Checksum, copied.Checksum)
+	require.Equal(t, "image/jpeg", copied.ContentType)
+	require.True(t, copied.CreatedAt.After(source.CreatedAt))
+	require.Empty(t, copied.Uploader)
+
+	file, reader, err := repo.Get(ctx, "copies/a.jpg")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	require.Equal(t, content, data)
+	require.Equal(t, copied.CreatedAt.Unix(), file.CreatedAt.Unix())
+
+	// Копия не зависит от исходного файла
+	_, err = repo.Delete(ctx, "a.jpg", Precondition{})
+	require.NoError(t, err)
+	_, reader, err = repo.Get(ctx, "copies/a.jpg")
+	require.NoError(t, err)
+	reader.Close()
+
+	_, err = repo.Copy(ctx, "copies/a.jpg", "copies/a.jpg", false)
+	require.True(t, os.IsExist(err))
+
+	require.NoError(t, repo.Save(ctx, &entity.File{Name: "b.jpg"}, bytes.NewReader([]byte("other")), SaveOptions{}))
+	_, err = repo.Copy(ctx, "copies/a.jpg", "b.jpg", false)
+	require.True(t, os.IsExist(err))
+
+	copied, err = repo.Copy(ctx, "copies/a.jpg", "b.jpg", true)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(content)), copied.Size)
+
+	_, err = repo.Copy(ctx, "missing.jpg", "c.jpg", false)
+	require.True(t, os.IsNotExist(err))
+
+	// Временные файлы не остаются в хранилище
+	leftovers, _ := filepath.Glob(filepath.Join(storage, ".*.tmp"))
+	require.Empty(t, leftovers)
+
+	t.Run("legacy file without metadata", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(storage, "legacy.jpg"), content, 0644))
+		copied, err := repo.Copy(ctx, "legacy.jpg", "legacy-copy.jpg", false)
+		require.NoError(t, err)
+		sum := sha256.Sum256(content)
+		require.Equal(t, hex.EncodeToString(sum[:]), copied.Checksum)
+	})
+
+	t.Run("source replaced while copying", func(t *testing.T) {
+		// Хеш и размер копии всегда относятся к ее содержимому
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				data := bytes.Repeat([]byte{byte(i)}, 1+i%1000)
+				require.NoError(t, repo.Save(ctx, &entity.File{Name: "busy.jpg"}, bytes.NewReader(data), SaveOptions{}))
+			}
+		}()
+		require.NoError(t, repo.Save(ctx, &entity.File{Name: "busy.jpg"}, bytes.NewReader(content), SaveOptions{}))
+
+		for i := 0; i < 50; i++ {
+			name := fmt.Sprintf("busy-%d.jpg", i)
+			copied, err := repo.Copy(ctx, "busy.jpg", name, false)
+			require.NoError(t, err)
+			_, reader, err := repo.Get(ctx, name)
+			require.NoError(t, err)
+			data, err := io.ReadAll(reader)
+			reader.Close()
+			require.NoError(t, err)
+			sum := sha256.Sum256(data)
+			require.Equal(t, hex.EncodeToString(sum[:]), copied.Checksum)
+			require.Equal(t, int64(len(data)), copied.Size)
+		}
+		close(done)
+		wg.Wait()
+	})
+}
+
+func TestFileRepository_Versions(t *testing.T) {
+	repo := NewFileRepository(t.TempDir(), VersioningOptions{Enabled: true, Retention: 3})
+	ctx := context.Background()
+
+	save := func(content string) {
+		require.NoError(t, repo.Save(ctx, &entity.File{Name: "docs/a.txt", Uploader: content}, bytes.NewReader([]byte(content)), SaveOptions{}))
+	}
+	readVersion := func(number int64) string {
+		_, reader, err := repo.GetVersion(ctx, "docs/a.txt", number)
+		require.NoError(t, err)
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(data)
+	}
+	numbers := func() []int64 {
+		versions, err := repo.ListVersions(ctx, "docs/a.txt")
+		require.NoError(t, err)
+		var numbers []int64
+		for _, v := range versions {
+			numbers = append(numbers, v.Number)
+		}
+		return numbers
+	}
+
+	save("v1")
+	require.Empty(t, numbers())
+
+	save("v2")
+	save("v3")
+	require.Equal(t, []int64{1, 2}, numbers())
+	require.Equal(t, "v1", readVersion(1))
+	require.Equal(t, "v2", readVersion(2))
+
+	versions, err := repo.ListVersions(ctx, "docs/a.txt")
+	require.NoError(t, err)
+	require.Equal(t, "v1", versions[0].Uploader)
+	require.Equal(t, int64(2), versions[0].Size)
+
+	// Сверх лимита удаляются самые старые версии
+	save("v4")
+	save("v5")
+	require.Equal(t, []int64{2, 3, 4}, numbers())
+
+	// Восстановление делает версию текущей, а замененное содержимое - новой версией
+	restored, err := repo.RestoreVersion(ctx, "docs/a.txt", 2)
+	require.NoError(t, err)
+	require.Equal(t, "v2", restored.Uploader)
+	require.Equal(t, []int64{3, 4, 5}, numbers())
+	require.Equal(t, "v5", readVersion(5))
+
+	_, reader, err := repo.Get(ctx, "docs/a.txt")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	require.Equal(t, "v2", string(data))
+
+	_, _, err = repo.GetVersion(ctx, "docs/a.txt", 1)
+	require.True(t, os.IsNotExist(err))
+	_, _, err = repo.GetVersion(ctx, "docs/a.txt", 0)
+	require.ErrorIs(t, err, ErrInvalidVersion)
+
+	removed, err := repo.PruneVersions(ctx, "docs/a.txt", 1)
+	require.NoError(t, err)
+	require.Equal(t, 2, removed)
+	require.Equal(t, []int64{5}, numbers())
+
+	// Служебный каталог версий не виден в списке файлов
+	result, err := repo.List(ctx, ListOptions{FileFilter: FileFilter{Recursive: true}})
+	require.NoError(t, err)
+	require.Len(t, result.Files, 1)
+
+	// История остается, пока файл лежит в корзине
+	_, err = repo.Delete(ctx, "docs/a.txt", Precondition{})
+	require.NoError(t, err)
+	require.Equal(t, []int64{5}, numbers())
+
+	_, err = repo.PurgeTrash(ctx, time.Now())
+	require.NoError(t, err)
+	require.Empty(t, numbers())
+}
+
+func TestFileRepository_VersioningDisabled(t *testing.T) {
+	repo := NewFileRepository(t.TempDir(), VersioningOptions{})
+	ctx := context.Background()
+
+	for _, content := range []string{"v1", "v2"} {
+		require.NoError(t, repo.Save(ctx, &entity.File{Name: "a.txt"}, bytes.NewReader([]byte(content)), SaveOptions{}))
+	}
+	require.NoError(t, repo.Save(ctx, &entity.File{Name: "b.txt"}, bytes.NewReader([]byte("b")), SaveOptions{}))
+	_, err := repo.Rename(ctx, "b.txt", "a.txt", true)
+	require.NoError(t, err)
+
+	versions, err := repo.ListVersions(ctx, "a.txt")
+	require.NoError(t, err)
+	require.Empty(t, versions)
+}
+
+func TestFileRepository_VersionsOnRenameAndCopy(t *testing.T) {
+	repo := NewFileRepository(t.TempDir(), VersioningOptions{Enabled: true})
+	ctx := context.Background()
+
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		require.NoError(t, repo.Save(ctx, &entity.File{Name: name}, bytes.NewReader([]byte(name)), SaveOptions{}))
+	}
+
+	_, err := repo.Rename(ctx, "b.txt", "a.txt", true)
+	require.NoError(t, err)
+	_, err = repo.Copy(ctx, "c.txt", "a.txt", true)
+	require.NoError(t, err)
+
+	versions, err := repo.ListVersions(ctx, "a.txt")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+
+	for i, expected := range []string{"a.txt", "b.txt"} {
+		_, reader, err := repo.GetVersion(ctx, "a.txt", versions[i].Number)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(reader)
+		reader.Close()
+		require.Equal(t, expected, string(data))
+	}
+}
+
+func TestFileRepository_Trash(t *testing.T) {
+	repo := NewFileRepository(t.TempDir(), VersioningOptions{})
+	ctx := context.Background()
+
+	save := func(name, content string) {
+		require.NoError(t, repo.Save(ctx, &entity.File{Name: name, ContentType: "text/plain"}, bytes.NewReader([]byte(content)), SaveOptions{}))
+	}
+	read := func(name string) string {
+		_, reader, err := repo.Get(ctx, name)
+		require.NoError(t, err)
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	save("docs/a.txt", "first")
+	original, _, err := repo.Get(ctx, "docs/a.txt")
+	require.NoError(t, err)
+
+	deleted, err := repo.Delete(ctx, "docs/a.txt", Precondition{})
+	require.NoError(t, err)
+	require.Equal(t, "docs/a.txt", deleted.Filename)
+
+	items, err := repo.ListTrash(ctx)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, deleted.ID, items[0].ID)
+	require.Equal(t, int64(5), items[0].Size)
+	require.Equal(t, original.Checksum, items[0].Checksum)
+
+	// Корзина не видна в списке файлов
+	result, err := repo.List(ctx, ListOptions{FileFilter: FileFilter{Recursive: true}})
+	require.NoError(t, err)
+	require.Empty(t, result.Files)
+
+	t.Run("restore keeps metadata", func(t *testing.T) {
+		restored, err := repo.RestoreTrash(ctx, deleted.ID, "")
+		require.NoError(t, err)
+		require.Equal(t, "docs/a.txt", restored.Name)
+		require.Equal(t, original.Checksum, restored.Checksum)
+		require.Equal(t, "text/plain", restored.ContentType)
+		require.Equal(t, original.CreatedAt.Unix(), restored.CreatedAt.Unix())
+		require.Equal(t, "first", read("docs/a.txt"))
+
+		_, err = repo.RestoreTrash(ctx, deleted.ID, "")
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("restore does not replace existing file", func(t *testing.T) {
+		item, err := repo.Delete(ctx, "docs/a.txt", Precondition{})
+		require.NoError(t, err)
+		save("docs/a.txt", "second")
+
+		_, err = repo.RestoreTrash(ctx, item.ID, "")
+		require.True(t, os.IsExist(err))
+		require.Equal(t, "second", read("docs/a.txt"))
+
+		restored, err := repo.RestoreTrash(ctx, item.ID, "docs/a (restored).txt")
+		require.NoError(t, err)
+		require.Equal(t, "docs/a (restored).txt", restored.Name)
+		require.Equal(t, "first", read("docs/a (restored).txt"))
+	})
+
+	t.Run("purge by age", func(t *testing.T) {
+		old, err := repo.Delete(ctx, "docs/a.txt", Precondition{})
+		require.NoError(t, err)
+		cutoff := time.Now()
+		time.Sleep(10 * time.Millisecond)
+		_, err = repo.Delete(ctx, "docs/a (restored).txt", Precondition{})
+		require.NoError(t, err)
+
+		removed, err := repo.PurgeTrash(ctx, cutoff.Add(time.Millisecond))
+		require.NoError(t, err)
+		require.Equal(t, 1, removed)
+
+		items, err := repo.ListTrash(ctx)
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		require.NotEqual(t, old.ID, items[0].ID)
+
+		removed, err = repo.PurgeTrash(ctx, time.Now())
+		require.NoError(t, err)
+		require.Equal(t, 1, removed)
+	})
+
+	_, err = repo.RestoreTrash(ctx, "../../etc/passwd", "")
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestFileRepository_ConflictPolicy(t *testing.T) {
+	repo := NewFileRepository(t.TempDir(), VersioningOptions{})
+	ctx := context.Background()
+
+	save := func(name, content string, policy ConflictPolicy) (*entity.File, error) {
+		file := &entity.File{Name: name}
+		return file, repo.Save(ctx, file, bytes.NewReader([]byte(content)), SaveOptions{Conflict: policy})
+	}
+	read := func(name string) string {
+		_, reader, err := repo.Get(ctx, name)
+		require.NoError(t, err)
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	_, err := save("docs/photo.jpg", "first", ConflictFail)
+	require.NoError(t, err)
+
+	_, err = save("docs/photo.jpg", "second", ConflictFail)
+	require.True(t, os.IsExist(err))
+	require.Equal(t, "first", read("docs/photo.jpg"))
+
+	renamed, err := save("docs/photo.jpg", "third", ConflictRename)
+	require.NoError(t, err)
+	require.Equal(t, "docs/photo (1).jpg", renamed.Name)
+	require.Equal(t, "third", read("docs/photo (1).jpg"))
+
+	renamed, err = save("docs/photo.jpg", "fourth", ConflictRename)
+	require.NoError(t, err)
+	require.Equal(t, "docs/photo (2).jpg", renamed.Name)
+	require.NotEmpty(t, renamed.Checksum)
+
+	stored, reader, err := repo.Get(ctx, "docs/photo (2).jpg")
+	require.NoError(t, err)
+	reader.Close()
+	require.Equal(t, renamed.Checksum, stored.Checksum)
+
+	_, err = save("docs/photo.jpg", "fifth", ConflictOverwrite)
+	require.NoError(t, err)
+	require.Equal(t, "fifth", read("docs/photo.jpg"))
+
+	result, err := repo.List(ctx, ListOptions{FileFilter: FileFilter{Folder: "docs"}})
+	require.NoError(t, err)
+	require.Len(t, result.Files, 3)
+}
+
+func TestFileRepository_ConcurrentSameName(t *testing.T) {
+	dir := t.TempDir()
+	repo := NewFileRepository(dir, VersioningOptions{})
+	ctx := context.Background()
+
+	// Каждая загрузка пишет свой временный файл, поэтому итоговое
+	// содержимое целиком принадлежит одной из них
+	contents := make(map[string]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		content := bytes.Repeat([]byte{byte('a' + i)}, 64*1024)
+		contents[string(content)] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.Save(ctx, &entity.File{Name: "same.bin"}, bytes.NewReader(content), SaveOptions{})
 			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	file, reader, err := repo.Get(ctx, "same.bin")
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	require.True(t, contents[string(data)])
+
+	sum := sha256.Sum256(data)
+	require.Equal(t, hex.EncodeToString(sum[:]), file.Checksum)
+
+	leftovers, _ := filepath.Glob(filepath.Join(dir, ".*.tmp"))
+	require.Empty(t, leftovers)
+}
+
+func TestFileRepository_Preconditions(t *testing.T) {
+	repo := NewFileRepository(t.TempDir(), VersioningOptions{})
+	ctx := context.Background()
+
+	save := func(content string, cond Precondition) (*entity.File, error) {
+		file := &entity.File{Name: "shared.txt"}
+		return file, repo.Save(ctx, file, bytes.NewReader([]byte(content)), SaveOptions{Precondition: cond})
+	}
+
+	// Создание только если файла еще нет
+	first, err := save("v1", Precondition{IfNoneMatch: "*"})
+	require.NoError(t, err)
+	_, err = save("v1 again", Precondition{IfNoneMatch: "*"})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+
+	stored, reader, err := repo.Get(ctx, "shared.txt")
+	require.NoError(t, err)
+	reader.Close()
+	require.Equal(t, first.ETag(), stored.ETag())
+
+	second, err := save("v2", Precondition{IfMatch: first.ETag()})
+	require.NoError(t, err)
+	require.NotEqual(t, first.ETag(), second.ETag())
+
+	// Клиент с устаревшим ETag не затирает чужие изменения
+	_, err = save("v3", Precondition{IfMatch: first.ETag()})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+
+	_, err = repo.Delete(ctx, "shared.txt", Precondition{IfMatch: first.ETag()})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	_, err = repo.Delete(ctx, "shared.txt", Precondition{IfNoneMatch: second.ETag()})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	_, err = repo.Delete(ctx, "shared.txt", Precondition{IfMatch: second.ETag()})
+	require.NoError(t, err)
+
+	_, err = save("v4", Precondition{IfMatch: "*"})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+}
+
+func TestFileRepository_PreconditionRace(t *testing.T) {
+	repo := NewFileRepository(t.TempDir(), VersioningOptions{})
+	ctx := context.Background()
+
+	base := &entity.File{Name: "counter.txt"}
+	require.NoError(t, repo.Save(ctx, base, bytes.NewReader([]byte("0")), SaveOptions{}))
+
+	// Все писатели видели одну и ту же версию, поэтому выиграть может только один
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := repo.Save(ctx, &entity.File{Name: "counter.txt"},
+				bytes.NewReader([]byte(fmt.Sprint(i+1))), SaveOptions{Precondition: Precondition{IfMatch: base.ETag()}})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+				return
+			}
+			require.ErrorIs(t, err, ErrPreconditionFailed)
 		}(i)
 	}
 	wg.Wait()
+	require.Equal(t, 1, succeeded)
+}
+
+func TestFileRepository_ServiceLayout(t *testing.T) {
+	storage := t.TempDir()
+	repo := NewFileRepository(storage, VersioningOptions{Enabled: true})
+	ctx := context.Background()
+
+	save := func(name, content, contentType string) {
+		file := &entity.File{Name: name, ContentType: contentType}
+		require.NoError(t, repo.Save(ctx, file, bytes.NewReader([]byte(content)), SaveOptions{}))
+	}
+	contentType := func(name string) string {
+		file, reader, err := repo.Get(ctx, name)
+		require.NoError(t, err)
+		reader.Close()
+		return file.ContentType
+	}
+
+	t.Run("metadata", func(t *testing.T) {
+		// Метаданные файла "a" и файлов папки "a.json" не пересекаются
+		save("a", "file", "text/plain")
+		save("a.json/b", "nested", "application/json")
+		require.Equal(t, "text/plain", contentType("a"))
+		require.Equal(t, "application/json", contentType("a.json/b"))
+	})
 
-	files, err := repo.List(ctx)
+	t.Run("versions", func(t *testing.T) {
+		// История удаленного файла "v" остается, и ее версия 1 не мешает файлу "v/1"
+		save("v", "first", "")
+		save("v", "second", "")
+		_, err := repo.Delete(ctx, "v", Precondition{})
+		require.NoError(t, err)
+
+		save("v/1", "first", "")
+		save("v/1", "second", "")
+		versions, err := repo.ListVersions(ctx, "v")
+		require.NoError(t, err)
+		require.Len(t, versions, 1)
+		versions, err = repo.ListVersions(ctx, "v/1")
+		require.NoError(t, err)
+		require.Len(t, versions, 1)
+	})
+
+	t.Run("failed publish restores metadata", func(t *testing.T) {
+		// Содержимое нельзя опубликовать поверх непустого каталога
+		save("dir/file.txt", "content", "")
+		err := repo.Save(ctx, &entity.File{Name: "dir", ContentType: "text/plain"}, bytes.NewReader([]byte("x")), SaveOptions{})
+		require.Error(t, err)
+		_, err = os.Stat(filepath.Join(storage, metadataDir, ".dir.json"))
+		require.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestFileRepository_LegacyLayout(t *testing.T) {
+	storage := t.TempDir()
+	ctx := context.Background()
+
+	// Метаданные и версии в раскладке, где последний сегмент - имя файла
+	write := func(path, content string) {
+		path = filepath.Join(storage, filepath.FromSlash(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	write("docs/report.txt", "current")
+	write(".meta/docs/report.txt.json", `{"sha256":"abc","content_type":"text/plain"}`)
+	write(".versions/docs/report.txt/1", "old")
+	write(".versions/docs/report.txt/1.json", `{"size":3,"content_type":"text/plain"}`)
+
+	repo := NewFileRepository(storage, VersioningOptions{Enabled: true})
+	file, reader, err := repo.Get(ctx, "docs/report.txt")
+	require.NoError(t, err)
+	reader.Close()
+	require.Equal(t, "abc", file.Checksum)
+	require.Equal(t, "text/plain", file.ContentType)
+
+	versions, err := repo.ListVersions(ctx, "docs/report.txt")
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	_, reader, err = repo.GetVersion(ctx, "docs/report.txt", 1)
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	require.Equal(t, "old", string(content))
+
+	// Перенос выполняется один раз
+	_, err = os.Stat(filepath.Join(storage, metadataDir, layoutMarker))
+	require.NoError(t, err)
+	repo = NewFileRepository(storage, VersioningOptions{Enabled: true})
+	versions, err = repo.ListVersions(ctx, "docs/report.txt")
 	require.NoError(t, err)
-	require.Len(t, files, 10)
+	require.Len(t, versions, 1)
 }
@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"container/heap"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+)
+
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+var ErrInvalidListOptions = errors.New("invalid list options")
+
+type SortField int
+
+const (
+	SortByCreatedAt SortField = iota
+	SortByName
+	SortBySize
+	SortByUpdatedAt
+)
+
+// FileFilter отбирает файлы для ListFiles и StreamFiles
+type FileFilter struct {
+	Folder    string // Папка вида "project/2024", пустая строка - корень
+	Recursive bool   // Включать файлы из вложенных папок
+
+	// Фильтры по имени применяются к имени файла без папки
+	NamePrefix  string
+	NamePattern string // Glob в формате path.Match
+
+	MinSize int64
+	MaxSize int64 // 0 - без ограничения
+
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+
+	// Фильтры по метаданным
+	ContentType string // Префикс типа содержимого, например "image/"
+	Uploader    string
+}
+
+type ListOptions struct {
+	FileFilter
+
+	PageSize  int
+	PageToken string
+
+	SortBy        SortField
+	SortAscending bool // По умолчанию новые/большие файлы идут первыми
+}
+
+type ListResult struct {
+	Files         []*entity.File
+	Folders       []string // Вложенные папки при нерекурсивном обходе
+	NextPageToken string
+
+	// Объем всех подходящих под фильтр файлов, а не только страницы:
+	// логический - сумма размеров, физический - сколько они занимают в
+	// хранилище (меньше логического при дедупликации)
+	LogicalBytes  int64
+	PhysicalBytes int64
+}
+
+// pageCursor - последний отданный элемент страницы. Следующая страница
+// начинается сразу после него, поэтому новые и удаленные файлы не сдвигают
+// выдачу, как это было бы со смещением.
+type pageCursor struct {
+	SortBy    SortField `json:"s"`
+	Ascending bool      `json:"a"`
+	Name      string    `json:"n"`
+	Size      int64     `json:"z,omitempty"`
+	CreatedAt time.Time `json:"c,omitempty"`
+	UpdatedAt time.Time `json:"u,omitempty"`
+}
+
+func (f *FileFilter) validate() error {
+	if f.MinSize < 0 || f.MaxSize < 0 || (f.MaxSize > 0 && f.MinSize > f.MaxSize) {
+		return fmt.Errorf("%w: bad size range", ErrInvalidListOptions)
+	}
+	if f.NamePattern != "" {
+		if _, err := path.Match(f.NamePattern, ""); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidListOptions, err)
+		}
+	}
+	return nil
+}
+
+func (o *ListOptions) validate() error {
+	if o.PageSize < 0 {
+		return fmt.Errorf("%w: negative page size", ErrInvalidListOptions)
+	}
+	if o.SortBy < SortByCreatedAt || o.SortBy > SortByUpdatedAt {
+		return fmt.Errorf("%w: unknown sort field %d", ErrInvalidListOptions, o.SortBy)
+	}
+	return o.FileFilter.validate()
+}
+
+func (o *ListOptions) pageSize() int {
+	if o.PageSize == 0 {
+		return DefaultPageSize
+	}
+	return min(o.PageSize, MaxPageSize)
+}
+
+// matchName проверяет фильтры по имени, которым не нужен stat файла
+func (f *FileFilter) matchName(name string) bool {
+	if !strings.HasPrefix(name, f.NamePrefix) {
+		return false
+	}
+	if f.NamePattern != "" {
+		if ok, _ := path.Match(f.NamePattern, name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *FileFilter) matchFile(file *entity.File) bool {
+	if file.Size < f.MinSize || (f.MaxSize > 0 && file.Size > f.MaxSize) {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && file.CreatedAt.Before(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !file.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	if !strings.HasPrefix(file.ContentType, f.ContentType) {
+		return false
+	}
+	if f.Uploader != "" && file.Uploader != f.Uploader {
+		return false
+	}
+	return true
+}
+
+// less задает порядок выдачи. При равенстве ключа сортировки порядок
+// определяется именем, чтобы курсор страниц был однозначным
+func (o *ListOptions) less(a, b *entity.File) bool {
+	var cmp int
+	switch o.SortBy {
+	case SortByName:
+		cmp = strings.Compare(a.Name, b.Name)
+	case SortBySize:
+		cmp = compareInt64(a.Size, b.Size)
+	case SortByUpdatedAt:
+		cmp = a.UpdatedAt.Compare(b.UpdatedAt)
+	default:
+		cmp = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if cmp == 0 {
+		cmp = strings.Compare(a.Name, b.Name)
+		if !o.SortAscending {
+			cmp = -cmp
+		}
+	}
+	if o.SortAscending {
+		return cmp < 0
+	}
+	return cmp > 0
+}
+
+// pageCollector отбирает страницу по ходу обхода: файлы до курсора
+// отбрасываются, из остальных хранятся только pageSize+1 первых в порядке
+// выдачи. Память и сортировка растут со страницей, а не с хранилищем
+type pageCollector struct {
+	opts   *ListOptions
+	cursor *entity.File // nil - первая страница
+	size   int
+	files  []*entity.File // Куча, на вершине - последний в порядке выдачи
+}
+
+func (o *ListOptions) newPageCollector() (*pageCollector, error) {
+	c := &pageCollector{opts: o, size: o.pageSize()}
+	if o.PageToken != "" {
+		cursor, err := o.decodeCursor()
+		if err != nil {
+			return nil, err
+		}
+		c.cursor = cursor
+	}
+	return c, nil
+}
+
+func (c *pageCollector) add(file *entity.File) {
+	if c.cursor != nil && !c.opts.less(c.cursor, file) {
+		return
+	}
+	heap.Push(c, file)
+	if len(c.files) > c.size+1 {
+		heap.Pop(c)
+	}
+}
+
+// result сортирует отобранные файлы и выставляет курсор следующей страницы
+func (c *pageCollector) result() (*ListResult, error) {
+	files := c.files
+	sort.Slice(files, func(i, j int) bool {
+		return c.opts.less(files[i], files[j])
+	})
+
+	result := &ListResult{Files: files}
+	if len(files) > c.size {
+		result.Files = files[:c.size]
+		token, err := c.opts.encodeCursor(result.Files[c.size-1])
+		if err != nil {
+			return nil, err
+		}
+		result.NextPageToken = token
+	}
+	return result, nil
+}
+
+func (c *pageCollector) Len() int           { return len(c.files) }
+func (c *pageCollector) Less(i, j int) bool { return c.opts.less(c.files[j], c.files[i]) }
+func (c *pageCollector) Swap(i, j int)      { c.files[i], c.files[j] = c.files[j], c.files[i] }
+func (c *pageCollector) Push(x any)         { c.files = append(c.files, x.(*entity.File)) }
+
+func (c *pageCollector) Pop() any {
+	last := c.files[len(c.files)-1]
+	c.files = c.files[:len(c.files)-1]
+	return last
+}
+
+func (o *ListOptions) encodeCursor(last *entity.File) (string, error) {
+	raw, err := json.Marshal(pageCursor{
+		SortBy:    o.SortBy,
+		Ascending: o.SortAscending,
+		Name:      last.Name,
+		Size:      last.Size,
+		CreatedAt: last.CreatedAt,
+		UpdatedAt: last.UpdatedAt,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func (o *ListOptions) decodeCursor() (*entity.File, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(o.PageToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed page token", ErrInvalidListOptions)
+	}
+
+	var cursor pageCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed page token", ErrInvalidListOptions)
+	}
+	// Токен от выдачи с другой сортировкой указывал бы на случайное место
+	if cursor.SortBy != o.SortBy || cursor.Ascending != o.SortAscending {
+		return nil, fmt.Errorf("%w: page token does not match sort order", ErrInvalidListOptions)
+	}
+
+	return &entity.File{
+		Name:      cursor.Name,
+		Size:      cursor.Size,
+		CreatedAt: cursor.CreatedAt,
+		UpdatedAt: cursor.UpdatedAt,
+	}, nil
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageCollector(t *testing.T) {
+	var files []*entity.File
+	for i := range 50 {
+		// Одинаковые размеры упорядочиваются по имени
+		files = append(files, &entity.File{Name: fmt.Sprintf("f%02d", i), Size: int64(i % 7)})
+	}
+
+	for _, ascending := range []bool{true, false} {
+		opts := ListOptions{PageSize: 6, SortBy: SortBySize, SortAscending: ascending}
+		var pages [][]*entity.File
+		for {
+			page, err := opts.newPageCollector()
+			require.NoError(t, err)
+			for _, i := range rand.Perm(len(files)) {
+				page.add(files[i])
+			}
+			// Хранится не больше страницы и одного файла для курсора
+			require.LessOrEqual(t, page.Len(), 7)
+
+			result, err := page.result()
+			require.NoError(t, err)
+			pages = append(pages, result.Files)
+			if result.NextPageToken == "" {
+				break
+			}
+			opts.PageToken = result.NextPageToken
+		}
+
+		var all []*entity.File
+		for _, page := range pages {
+			all = append(all, page...)
+		}
+		require.Len(t, pages, 9)
+		require.Len(t, all, len(files))
+		for i := 1; i < len(all); i++ {
+			require.True(t, opts.less(all[i-1], all[i]), "%s before %s", all[i-1].Name, all[i].Name)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"hash/fnv"
+	"slices"
+	"sync"
+)
+
+// nameLocks - блокировки по имени файла. Мьютексы распределены по именам
+// хешем, поэтому их число не растет вместе с числом файлов
+type nameLocks struct {
+	mu [256]sync.Mutex
+}
+
+// lock блокирует все переданные имена. Мьютексы берутся в порядке
+// индексов, поэтому операции над парами имен не блокируют друг друга навсегда
+func (l *nameLocks) lock(names ...string) func() {
+	indexes := make([]int, 0, len(names))
+	for _, name := range names {
+		h := fnv.New32a()
+		h.Write([]byte(name))
+		index := int(h.Sum32() % uint32(len(l.mu)))
+		if !slices.Contains(indexes, index) {
+			indexes = append(indexes, index)
+		}
+	}
+	slices.Sort(indexes)
+
+	for _, index := range indexes {
+		l.mu[index].Lock()
+	}
+	return func() {
+		for _, index := range indexes {
+			l.mu[index].Unlock()
+		}
+	}
+}
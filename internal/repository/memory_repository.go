@@ -0,0 +1,550 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+)
+
+// memoryObject - содержимое файла в памяти. Срез не меняется после записи,
+// поэтому копии, версии и записи корзины ссылаются на те же байты
+type memoryObject struct {
+	data []byte
+	meta fileMetadata
+}
+
+type memoryVersion struct {
+	memoryObject
+	number     int64
+	archivedAt time.Time
+}
+
+type memoryTrashItem struct {
+	memoryObject
+	filename  string
+	deletedAt time.Time
+}
+
+// memoryRepository хранит все в памяти процесса: для тестов и временных
+// кешей, данные теряются при перезапуске
+type memoryRepository struct {
+	mu         sync.RWMutex
+	files      map[string]*memoryObject
+	versions   map[string][]*memoryVersion
+	trash      map[string]*memoryTrashItem
+	versioning VersioningOptions
+}
+
+func NewMemoryRepository(versioning VersioningOptions) FileRepository {
+	return &memoryRepository{
+		files:      make(map[string]*memoryObject),
+		versions:   make(map[string][]*memoryVersion),
+		trash:      make(map[string]*memoryTrashItem),
+		versioning: versioning,
+	}
+}
+
+func (r *memoryRepository) Save(ctx context.Context, file *entity.File, data io.Reader, opts SaveOptions) error {
+	if err := validateName(file.Name); err != nil {
+		return err
+	}
+
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+	hash := sha256.Sum256(content)
+	checksum := hex.EncodeToString(hash[:])
+	if opts.Checksum != "" && !strings.EqualFold(opts.Checksum, checksum) {
+		return fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, opts.Checksum, checksum)
+	}
+
+	if file.CreatedAt.IsZero() {
+		file.CreatedAt = time.Now()
+	}
+	if file.UpdatedAt.IsZero() {
+		file.UpdatedAt = time.Now()
+	}
+	object := &memoryObject{
+		data: content,
+		meta: fileMetadata{
+			SHA256:       checksum,
+			CreatedAt:    file.CreatedAt,
+			UpdatedAt:    file.UpdatedAt,
+			DeclaredSize: file.DeclaredSize,
+			Uploader:     file.Uploader,
+			ContentType:  file.ContentType,
+			Image:        newImageMetadata(file.Image),
+		},
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := file.Name
+	previous := r.files[name]
+	var current *entity.File
+	if previous != nil {
+		current = r.file(name, previous)
+	}
+	if err := opts.Precondition.check(current); err != nil {
+		return err
+	}
+
+	if previous != nil {
+		switch opts.Conflict {
+		case ConflictFail:
+			return &os.PathError{Op: "save", Path: name, Err: os.ErrExist}
+		case ConflictRename:
+			if name, err = r.freeName(name); err != nil {
+				return err
+			}
+			previous = nil
+		default:
+			object.meta.CreatedAt = previous.meta.CreatedAt
+		}
+	}
+	r.put(name, object, previous)
+
+	file.Name = name
+	file.Size = int64(len(content))
+	file.Checksum = checksum
+	file.CreatedAt = object.meta.CreatedAt
+	return nil
+}
+
+// freeName подбирает первое свободное имя вида "photo (1).jpg"
+func (r *memoryRepository) freeName(name string) (string, error) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for i := 1; i <= maxRenameAttempts; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", stem, i, ext)
+		if r.files[candidate] == nil {
+			return candidate, nil
+		}
+	}
+	return "", &os.PathError{Op: "save", Path: name, Err: os.ErrExist}
+}
+
+// put делает object текущим содержимым name, previous при включенном
+// версионировании становится версией. Вызывается под r.mu
+func (r *memoryRepository) put(name string, object, previous *memoryObject) {
+	if previous != nil && r.versioning.Enabled {
+		versions := r.versions[name]
+		number := int64(1)
+		if len(versions) > 0 {
+			number = versions[len(versions)-1].number + 1
+		}
+		versions = append(versions, &memoryVersion{
+			memoryObject: *previous,
+			number:       number,
+			archivedAt:   time.Now(),
+		})
+		if retention := r.versioning.Retention; retention > 0 && len(versions) > retention {
+			versions = versions[len(versions)-retention:]
+		}
+		r.versions[name] = versions
+	}
+	r.files[name] = object
+}
+
+func (r *memoryRepository) Get(ctx context.Context, filename string) (*entity.File, io.ReadSeekCloser, error) {
+	if err := validateName(filename); err != nil {
+		return nil, nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	object := r.files[filename]
+	if object == nil {
+		return nil, nil, notExist("get", filename)
+	}
+	return r.file(filename, object), memoryReader{bytes.NewReader(object.data)}, nil
+}
+
+func (r *memoryRepository) Stat(ctx context.Context, filename string) (*entity.File, error) {
+	if err := validateName(filename); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	object := r.files[filename]
+	if object == nil {
+		return nil, notExist("stat", filename)
+	}
+	return r.file(filename, object), nil
+}
+
+func (r *memoryRepository) List(ctx context.Context, opts ListOptions) (*ListResult, error) {
+	return listFiles(opts, r.walk(ctx), false)
+}
+
+func (r *memoryRepository) Stream(ctx context.Context, filter FileFilter, batchSize int, fn func([]*entity.File) error) error {
+	return streamFiles(filter, batchSize, r.walk(ctx), fn)
+}
+
+// walk отбирает файлы из снимка карты, сделанного под блокировкой:
+// onFile может работать долго и не должен задерживать запись
+func (r *memoryRepository) walk(ctx context.Context) walkFunc {
+	return func(filter *FileFilter, readSize int, onFile func(*entity.File) error, onFolder func(string)) error {
+		prefix := ""
+		if folder := path.Clean(filter.Folder); folder != "." && folder != "" {
+			if err := validateName(filter.Folder); err != nil {
+				return err
+			}
+			prefix = folder + "/"
+		}
+
+		r.mu.RLock()
+		var files []*entity.File
+		folders := make(map[string]bool)
+		for name, object := range r.files {
+			rest, ok := strings.CutPrefix(name, prefix)
+			if !ok {
+				continue
+			}
+			if sub, _, nested := strings.Cut(rest, "/"); nested && !filter.Recursive {
+				folders[prefix+sub] = true
+				continue
+			}
+			if !filter.matchName(path.Base(name)) {
+				continue
+			}
+			if file := r.file(name, object); filter.matchFile(file) {
+				files = append(files, file)
+			}
+		}
+		r.mu.RUnlock()
+
+		for folder := range folders {
+			if onFolder != nil {
+				onFolder(folder)
+			}
+		}
+		for _, file := range files {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := onFile(file); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, filename string, cond Precondition) (*entity.TrashItem, error) {
+	if err := validateName(filename); err != nil {
+		return nil, err
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, fmt.Errorf("generate trash id failed: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	object := r.files[filename]
+	if object == nil {
+		return nil, notExist("delete", filename)
+	}
+	if err := cond.check(r.file(filename, object)); err != nil {
+		return nil, err
+	}
+
+	item := &memoryTrashItem{memoryObject: *object, filename: filename, deletedAt: time.Now()}
+	r.trash[id] = item
+	delete(r.files, filename)
+	return item.item(id), nil
+}
+
+func (r *memoryRepository) ListTrash(ctx context.Context) ([]*entity.TrashItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	items := make([]*entity.TrashItem, 0, len(r.trash))
+	for id, item := range r.trash {
+		items = append(items, item.item(id))
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	return items, nil
+}
+
+func (r *memoryRepository) RestoreTrash(ctx context.Context, id, destination string) (*entity.File, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item := r.trash[id]
+	if item == nil {
+		return nil, notExist("restore", id)
+	}
+	name := item.filename
+	if destination != "" {
+		name = destination
+	}
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+	if r.files[name] != nil {
+		return nil, &os.PathError{Op: "restore", Path: name, Err: os.ErrExist}
+	}
+
+	object := item.memoryObject
+	r.files[name] = &object
+	delete(r.trash, id)
+	return r.file(name, &object), nil
+}
+
+// PurgeTrash окончательно удаляет записи корзины, попавшие в нее раньше
+// deletedBefore, вместе с историей версий, если имя больше не используется
+func (r *memoryRepository) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := make(map[string]bool)
+	var purged []string
+	for id, item := range r.trash {
+		if !item.deletedAt.Before(deletedBefore) {
+			kept[item.filename] = true
+			continue
+		}
+		delete(r.trash, id)
+		purged = append(purged, item.filename)
+	}
+	for _, name := range purged {
+		if !kept[name] && r.files[name] == nil {
+			delete(r.versions, name)
+		}
+	}
+	return len(purged), nil
+}
+
+func (r *memoryRepository) ListVersions(ctx context.Context, filename string) ([]*entity.FileVersion, error) {
+	if err := validateName(filename); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var versions []*entity.FileVersion
+	for _, version := range r.versions[filename] {
+		versions = append(versions, version.version(filename))
+	}
+	return versions, nil
+}
+
+func (r *memoryRepository) GetVersion(ctx context.Context, filename string, number int64) (*entity.FileVersion, io.ReadSeekCloser, error) {
+	if err := validateName(filename); err != nil {
+		return nil, nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	version, err := r.findVersion(filename, number)
+	if err != nil {
+		return nil, nil, err
+	}
+	return version.version(filename), memoryReader{bytes.NewReader(version.data)}, nil
+}
+
+// RestoreVersion делает содержимое версии текущим. Сама версия остается
+// в истории, а заменяемое содержимое становится новой версией
+func (r *memoryRepository) RestoreVersion(ctx context.Context, filename string, number int64) (*entity.File, error) {
+	if err := validateName(filename); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	version, err := r.findVersion(filename, number)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	object := &memoryObject{
+		data: version.data,
+		meta: fileMetadata{
+			SHA256:      version.meta.SHA256,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			Uploader:    version.meta.Uploader,
+			ContentType: version.meta.ContentType,
+			Image:       version.meta.Image,
+		},
+	}
+	previous := r.files[filename]
+	if previous != nil {
+		object.meta.CreatedAt = previous.meta.CreatedAt
+	}
+	r.put(filename, object, previous)
+	return r.file(filename, object), nil
+}
+
+// PruneVersions оставляет keep последних версий и возвращает число удаленных
+func (r *memoryRepository) PruneVersions(ctx context.Context, filename string, keep int) (int, error) {
+	if err := validateName(filename); err != nil {
+		return 0, err
+	}
+	if keep < 0 {
+		return 0, fmt.Errorf("%w: negative keep %d", ErrInvalidVersion, keep)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions := r.versions[filename]
+	if len(versions) <= keep {
+		return 0, nil
+	}
+	removed := len(versions) - keep
+	if keep == 0 {
+		delete(r.versions, filename)
+	} else {
+		r.versions[filename] = versions[removed:]
+	}
+	return removed, nil
+}
+
+func (r *memoryRepository) Rename(ctx context.Context, from, to string, overwrite bool) (*entity.File, error) {
+	if err := validateName(from); err != nil {
+		return nil, err
+	}
+	if err := validateName(to); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	object := r.files[from]
+	if object == nil {
+		return nil, notExist("rename", from)
+	}
+	if from == to {
+		return r.file(to, object), nil
+	}
+	previous := r.files[to]
+	if previous != nil && !overwrite {
+		return nil, &os.PathError{Op: "rename", Path: to, Err: os.ErrExist}
+	}
+
+	delete(r.files, from)
+	r.put(to, object, previous)
+	return r.file(to, object), nil
+}
+
+// Copy создает новое имя для того же содержимого. Копия получает новые
+// время создания и изменения
+func (r *memoryRepository) Copy(ctx context.Context, from, to string, overwrite bool) (*entity.File, error) {
+	if err := validateName(from); err != nil {
+		return nil, err
+	}
+	if err := validateName(to); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	source := r.files[from]
+	if source == nil {
+		return nil, notExist("copy", from)
+	}
+	previous := r.files[to]
+	if previous != nil && !overwrite {
+		return nil, &os.PathError{Op: "copy", Path: to, Err: os.ErrExist}
+	}
+	if from == to {
+		return r.file(to, source), nil
+	}
+
+	now := time.Now()
+	object := &memoryObject{
+		data: source.data,
+		meta: fileMetadata{
+			SHA256:      source.meta.SHA256,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			ContentType: source.meta.ContentType,
+			Image:       source.meta.Image,
+		},
+	}
+	r.put(to, object, previous)
+	return r.file(to, object), nil
+}
+
+func (r *memoryRepository) findVersion(filename string, number int64) (*memoryVersion, error) {
+	if number <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidVersion, number)
+	}
+	for _, version := range r.versions[filename] {
+		if version.number == number {
+			return version, nil
+		}
+	}
+	return nil, notExist("get version", fmt.Sprintf("%s@%d", filename, number))
+}
+
+func (r *memoryRepository) file(name string, object *memoryObject) *entity.File {
+	file := &entity.File{Name: name, Size: int64(len(object.data))}
+	object.meta.apply(file)
+	return file
+}
+
+func (v *memoryVersion) version(filename string) *entity.FileVersion {
+	return &entity.FileVersion{
+		Filename:    filename,
+		Number:      v.number,
+		Size:        int64(len(v.data)),
+		Checksum:    v.meta.SHA256,
+		ContentType: v.meta.ContentType,
+		Uploader:    v.meta.Uploader,
+		UpdatedAt:   v.meta.UpdatedAt,
+		ArchivedAt:  v.archivedAt,
+		Image:       v.meta.Image.info(),
+	}
+}
+
+func (i *memoryTrashItem) item(id string) *entity.TrashItem {
+	return &entity.TrashItem{
+		ID:          id,
+		Filename:    i.filename,
+		Size:        int64(len(i.data)),
+		Checksum:    i.meta.SHA256,
+		ContentType: i.meta.ContentType,
+		DeletedAt:   i.deletedAt,
+	}
+}
+
+// memoryReader отдает содержимое без копирования, закрывать нечего
+type memoryReader struct {
+	*bytes.Reader
+}
+
+func (memoryReader) Close() error { return nil }
+
+func notExist(op, name string) error {
+	return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+}
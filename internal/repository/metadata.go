@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+)
+
+// Каталог с метаданными файлов внутри хранилища
+const metadataDir = ".meta"
+
+// fileMetadata хранится в отдельном JSON-файле рядом с содержимым
+type fileMetadata struct {
+	SHA256       string    `json:"sha256,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	DeclaredSize int64     `json:"declared_size,omitempty"`
+	Uploader     string    `json:"uploader,omitempty"`
+	ContentType  string    `json:"content_type,omitempty"`
+
+	Image *imageMetadata `json:"image,omitempty"`
+}
+
+// imageMetadata - свойства изображения, извлеченные при загрузке
+type imageMetadata struct {
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Format      string    `json:"format"`
+	ColorModel  string    `json:"color_model,omitempty"`
+	TakenAt     time.Time `json:"taken_at"`
+	Orientation int       `json:"orientation,omitempty"`
+	CameraModel string    `json:"camera_model,omitempty"`
+}
+
+func newImageMetadata(info *entity.ImageInfo) *imageMetadata {
+	if info == nil {
+		return nil
+	}
+	return &imageMetadata{
+		Width:       info.Width,
+		Height:      info.Height,
+		Format:      info.Format,
+		ColorModel:  info.ColorModel,
+		TakenAt:     info.TakenAt,
+		Orientation: info.Orientation,
+		CameraModel: info.CameraModel,
+	}
+}
+
+func (m *imageMetadata) info() *entity.ImageInfo {
+	if m == nil {
+		return nil
+	}
+	return &entity.ImageInfo{
+		Width:       m.Width,
+		Height:      m.Height,
+		Format:      m.Format,
+		ColorModel:  m.ColorModel,
+		TakenAt:     m.TakenAt,
+		Orientation: m.Orientation,
+		CameraModel: m.CameraModel,
+	}
+}
+
+// apply заполняет поля файла из метаданных. Для файлов без метаданных
+// остаются значения, полученные из файловой системы
+func (m *fileMetadata) apply(file *entity.File) {
+	file.Checksum = m.SHA256
+	if !m.CreatedAt.IsZero() {
+		file.CreatedAt = m.CreatedAt
+	}
+	if !m.UpdatedAt.IsZero() {
+		file.UpdatedAt = m.UpdatedAt
+	}
+	file.DeclaredSize = m.DeclaredSize
+	file.Uploader = m.Uploader
+	file.ContentType = m.ContentType
+	file.Image = m.Image.info()
+}
+
+type metadataStore struct {
+	path string
+}
+
+func newMetadataStore(storagePath string) (*metadataStore, error) {
+	path := filepath.Join(storagePath, metadataDir)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	// Прежняя раскладка: .meta/<имя>.json
+	err := migrateLayout(path, func(legacy string) string {
+		dir, base := filepath.Split(legacy)
+		if !strings.HasSuffix(base, ".json") {
+			return ""
+		}
+		return filepath.Join(dir, "."+base)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("migrate metadata layout failed: %w", err)
+	}
+	return &metadataStore{path: path}, nil
+}
+
+// load возвращает пустые метаданные, если файл был сохранен до появления
+// хранилища метаданных
+func (s *metadataStore) load(filename string) (*fileMetadata, error) {
+	raw, err := os.ReadFile(s.filePath(filename))
+	if os.IsNotExist(err) {
+		return &fileMetadata{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var meta fileMetadata
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, fmt.Errorf("corrupted metadata for %s: %w", filename, err)
+	}
+	return &meta, nil
+}
+
+// snapshot запоминает текущие метаданные файла и возвращает функцию,
+// которая возвращает их на место (или удаляет, если их не было)
+func (s *metadataStore) snapshot(filename string) (func() error, error) {
+	path := s.filePath(filename)
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return func() error { return s.remove(filename) }, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func() error { return writeFileAtomic(path, raw) }, nil
+}
+
+// save записывает метаданные атомарно через временный файл
+func (s *metadataStore) save(filename string, meta *fileMetadata) error {
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	path := s.filePath(filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, raw)
+}
+
+// writeFileAtomic заменяет файл целиком: читатель видит либо старое, либо
+// новое содержимое, но не наполовину записанное
+func writeFileAtomic(path string, raw []byte) error {
+	f, err := createTemp(path)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(raw)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+func (s *metadataStore) remove(filename string) error {
+	if err := os.Remove(s.filePath(filename)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// rename переносит метаданные вслед за файлом. Если у исходного файла их
+// нет, метаданные замененного файла назначения удаляются, чтобы они не
+// достались перенесенному содержимому
+func (s *metadataStore) rename(from, to string) error {
+	target := s.filePath(to)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	err := os.Rename(s.filePath(from), target)
+	if os.IsNotExist(err) {
+		return s.remove(to)
+	}
+	return err
+}
+
+func (s *metadataStore) filePath(filename string) string {
+	return hiddenLeaf(s.path, filename) + ".json"
+}
+
+// hiddenLeaf раскладывает служебные данные файла filename по папкам, как
+// само хранилище, но последний сегмент начинается с точки. В именах файлов
+// хранилища таких сегментов нет, поэтому данные файла "a" не пересекаются с
+// данными файлов из папок "a.json/" или "a/"
+func hiddenLeaf(root, filename string) string {
+	dir, base := path.Split(filename)
+	return filepath.Join(root, filepath.FromSlash(dir), "."+base)
+}
+
+// Отметка о том, что каталог служебных данных переведен на hiddenLeaf
+const layoutMarker = ".layout-v2"
+
+// migrateLayout один раз переносит служебные файлы из прежней раскладки, где
+// последний сегмент пути совпадал с именем файла хранилища. target
+// возвращает новый путь файла или "", если файл переносить не нужно.
+// Скрытые файлы и каталоги уже в новой раскладке и не просматриваются
+func migrateLayout(root string, target func(legacy string) string) error {
+	marker := filepath.Join(root, layoutMarker)
+	if _, err := os.Stat(marker); err == nil {
+		return nil
+	}
+
+	type move struct{ from, to string }
+	var moves []move
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if to := target(path); to != "" {
+				moves = append(moves, move{path, to})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, m := range moves {
+		if err := os.MkdirAll(filepath.Dir(m.to), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(m.from, m.to); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(marker, nil, 0644)
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+)
+
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// anyETag в условии совпадает с любой существующей версией файла
+const anyETag = "*"
+
+// Precondition - условия в духе HTTP If-Match / If-None-Match. Пустое поле
+// не проверяется
+type Precondition struct {
+	// Файл должен существовать и иметь этот ETag ("*" - любой)
+	IfMatch string
+	// Файл не должен иметь этот ETag ("*" - файл не должен существовать)
+	IfNoneMatch string
+}
+
+// check сверяет условие с текущим состоянием файла, current == nil - файла нет.
+// Вызывается под блокировкой имени, поэтому между проверкой и изменением
+// файл не может подменить другой запрос
+func (p Precondition) check(current *entity.File) error {
+	if p.IfMatch != "" {
+		if current == nil {
+			return fmt.Errorf("%w: file does not exist", ErrPreconditionFailed)
+		}
+		if p.IfMatch != anyETag && p.IfMatch != current.ETag() {
+			return fmt.Errorf("%w: etag is %s", ErrPreconditionFailed, current.ETag())
+		}
+	}
+	if p.IfNoneMatch != "" && current != nil {
+		if p.IfNoneMatch == anyETag || p.IfNoneMatch == current.ETag() {
+			return fmt.Errorf("%w: etag is %s", ErrPreconditionFailed, current.ETag())
+		}
+	}
+	return nil
+}
+
+func (p Precondition) isSet() bool {
+	return p.IfMatch != "" || p.IfNoneMatch != ""
+}
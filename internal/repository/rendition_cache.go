@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Каталог кеша преобразованных изображений внутри хранилища
+const renditionsDir = ".renditions"
+
+// RenditionCache хранит на диске готовые варианты изображений. Ключ - SHA-256
+// в hex от содержимого исходного файла и параметров преобразования, поэтому
+// после перезаписи файла старые варианты просто перестают запрашиваться и
+// вытесняются. Общий размер кеша ограничен, первыми вытесняются варианты,
+// которые дольше всего не запрашивались
+type RenditionCache interface {
+	// Get возвращает вариант или os.ErrNotExist
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+type renditionCache struct {
+	path     string
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // От недавно запрошенных к давно не запрашивавшимся
+	size    int64
+}
+
+type renditionEntry struct {
+	key  string
+	size int64
+}
+
+// NewRenditionCache открывает кеш и учитывает варианты, сохраненные до
+// перезапуска, в порядке времени последнего обращения
+func NewRenditionCache(storagePath string, maxBytes int64) (RenditionCache, error) {
+	c := &renditionCache{
+		path:     filepath.Join(storagePath, renditionsDir),
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+	if err := os.MkdirAll(c.path, 0755); err != nil {
+		return nil, err
+	}
+
+	type stored struct {
+		entry  renditionEntry
+		usedAt time.Time
+	}
+	var found []stored
+	err := filepath.WalkDir(c.path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isValidChecksum(d.Name()) {
+			return nil // Временные файлы оборванной записи тоже пропускаются
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		found = append(found, stored{renditionEntry{d.Name(), info.Size()}, info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load rendition cache failed: %w", err)
+	}
+
+	slices.SortFunc(found, func(a, b stored) int { return b.usedAt.Compare(a.usedAt) })
+	for _, s := range found {
+		c.entries[s.entry.key] = c.lru.PushBack(&s.entry)
+		c.size += s.entry.size
+	}
+	c.mu.Lock()
+	c.evictLocked()
+	c.mu.Unlock()
+	return c, nil
+}
+
+func (c *renditionCache) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := c.renditionPath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	element, ok := c.entries[key]
+	if ok {
+		c.lru.MoveToFront(element)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, &os.PathError{Op: "get", Path: key, Err: os.ErrNotExist}
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		// Вариант вытеснили параллельно или удалили в обход кеша
+		c.mu.Lock()
+		c.removeLocked(key)
+		c.mu.Unlock()
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Время изменения хранит порядок вытеснения между перезапусками
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return data, nil
+}
+
+func (c *renditionCache) Put(ctx context.Context, key string, data []byte) error {
+	path, err := c.renditionPath(key)
+	if err != nil {
+		return err
+	}
+	size := int64(len(data))
+	if size > c.maxBytes {
+		return nil // Вариант вытеснил бы весь кеш
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(key)
+	c.entries[key] = c.lru.PushFront(&renditionEntry{key: key, size: size})
+	c.size += size
+	c.evictLocked()
+	return nil
+}
+
+func (c *renditionCache) removeLocked(key string) {
+	element, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.lru.Remove(element)
+	delete(c.entries, key)
+	c.size -= element.Value.(*renditionEntry).size
+}
+
+func (c *renditionCache) evictLocked() {
+	for c.size > c.maxBytes {
+		entry := c.lru.Back().Value.(*renditionEntry)
+		c.removeLocked(entry.key)
+		// Если файл не удалился, после перезапуска он будет учтен и вытеснен снова
+		path, _ := c.renditionPath(entry.key)
+		os.Remove(path)
+	}
+}
+
+// renditionPath раскладывает варианты по подкаталогам так же, как превью
+func (c *renditionCache) renditionPath(key string) (string, error) {
+	if !isValidChecksum(key) {
+		return "", fmt.Errorf("%w: rendition key %q", ErrInvalidPath, key)
+	}
+	return filepath.Join(c.path, key[:2], key), nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenditionCache(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	first, second, third := strings.Repeat("a", 64), strings.Repeat("b", 64), strings.Repeat("c", 64)
+
+	cache, err := NewRenditionCache(dir, 10)
+	require.NoError(t, err)
+
+	_, err = cache.Get(ctx, first)
+	require.True(t, os.IsNotExist(err))
+
+	require.NoError(t, cache.Put(ctx, first, []byte("1111")))
+	require.NoError(t, cache.Put(ctx, second, []byte("2222")))
+	data, err := cache.Get(ctx, first)
+	require.NoError(t, err)
+	require.Equal(t, "1111", string(data))
+
+	// Места нет, вытесняется давно не запрашивавшийся second
+	require.NoError(t, cache.Put(ctx, third, []byte("3333")))
+	_, err = cache.Get(ctx, second)
+	require.True(t, os.IsNotExist(err))
+
+	// Вариант больше всего кеша не сохраняется
+	require.NoError(t, cache.Put(ctx, second, []byte("too large rendition")))
+	_, err = cache.Get(ctx, second)
+	require.True(t, os.IsNotExist(err))
+
+	require.ErrorIs(t, cache.Put(ctx, "../escape", nil), ErrInvalidPath)
+
+	// После перезапуска порядок вытеснения восстанавливается по времени
+	// последнего обращения
+	time.Sleep(10 * time.Millisecond)
+	_, err = cache.Get(ctx, first)
+	require.NoError(t, err)
+
+	cache, err = NewRenditionCache(dir, 10)
+	require.NoError(t, err)
+	require.NoError(t, cache.Put(ctx, second, []byte("2222")))
+	_, err = cache.Get(ctx, third)
+	require.True(t, os.IsNotExist(err))
+	_, err = cache.Get(ctx, first)
+	require.NoError(t, err)
+}
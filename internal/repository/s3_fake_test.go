@@ -0,0 +1,472 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 - минимальный S3-совместимый сервер в памяти: path-style адресация,
+// объекты, server-side copy, multipart-загрузки и ListObjectsV2. Подписи
+// запросов не проверяются, ограничение на размер метаданных - как у S3
+type fakeS3 struct {
+	mu      sync.Mutex
+	buckets map[string]bool
+	objects map[string]*fakeS3Object // "bucket/key"
+	uploads map[string]*fakeS3Upload
+	nextID  int
+	heads   int // Число HEAD-запросов к объектам
+}
+
+// Предел пользовательских метаданных объекта в S3
+const fakeS3MetadataLimit = 2 << 10
+
+type fakeS3Object struct {
+	data     []byte
+	header   http.Header // Content-Type и X-Amz-Meta-*
+	etag     string
+	modified time.Time
+}
+
+type fakeS3Upload struct {
+	object string
+	header http.Header
+	parts  map[int][]byte
+}
+
+// newTestS3Repository запускает fakeS3 и подключает к нему s3Repository
+func newTestS3Repository(t *testing.T, versioning VersioningOptions) *s3Repository {
+	repo, _ := newTestS3(t, versioning)
+	return repo
+}
+
+func newTestS3(t *testing.T, versioning VersioningOptions) (*s3Repository, *fakeS3) {
+	fake := &fakeS3{
+		buckets: make(map[string]bool),
+		objects: make(map[string]*fakeS3Object),
+		uploads: make(map[string]*fakeS3Upload),
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	repo, err := NewS3Repository(context.Background(), S3Options{
+		Endpoint:        strings.TrimPrefix(server.URL, "http://"),
+		Region:          "us-east-1",
+		Bucket:          "files",
+		Prefix:          "test",
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+		PathStyle:       true,
+		PartSize:        minS3PartSize,
+	}, versioning)
+	require.NoError(t, err)
+	return repo.(*s3Repository), fake
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+	query := req.URL.Query()
+	body, err := readS3Body(req)
+	if err != nil {
+		s.fail(w, req, http.StatusBadRequest, "IncompleteBody")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key == "" {
+		switch req.Method {
+		case http.MethodHead:
+			if !s.buckets[bucket] {
+				s.fail(w, req, http.StatusNotFound, "NoSuchBucket")
+			}
+		case http.MethodPut:
+			s.buckets[bucket] = true
+		case http.MethodGet:
+			s.list(w, req, bucket)
+		default:
+			s.fail(w, req, http.StatusMethodNotAllowed, "MethodNotAllowed")
+		}
+		return
+	}
+	if !s.buckets[bucket] {
+		s.fail(w, req, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	name := bucket + "/" + key
+	if metadataSize(req.Header) > fakeS3MetadataLimit {
+		s.fail(w, req, http.StatusBadRequest, "MetadataTooLarge")
+		return
+	}
+	if req.Method == http.MethodHead {
+		s.heads++
+	}
+
+	switch {
+	case req.Method == http.MethodPost && query.Has("uploads"):
+		s.nextID++
+		id := strconv.Itoa(s.nextID)
+		s.uploads[id] = &fakeS3Upload{object: name, header: objectHeader(req.Header), parts: make(map[int][]byte)}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string
+			Key      string
+			UploadID string `xml:"UploadId"`
+		}{Bucket: bucket, Key: key, UploadID: id})
+
+	case req.Method == http.MethodPut && query.Has("uploadId"):
+		upload, ok := s.uploads[query.Get("uploadId")]
+		if !ok {
+			s.fail(w, req, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		if source := req.Header.Get("X-Amz-Copy-Source"); source != "" {
+			data, ok := s.copySource(source, req.Header.Get("X-Amz-Copy-Source-Range"))
+			if !ok {
+				s.fail(w, req, http.StatusNotFound, "NoSuchKey")
+				return
+			}
+			upload.parts[number] = data
+			writeXML(w, struct {
+				XMLName      xml.Name `xml:"CopyPartResult"`
+				LastModified string
+				ETag         string
+			}{LastModified: time.Now().UTC().Format(time.RFC3339), ETag: quote(etag(data))})
+			return
+		}
+		upload.parts[number] = body
+		w.Header().Set("ETag", quote(etag(body)))
+
+	case req.Method == http.MethodPost && query.Has("uploadId"):
+		id := query.Get("uploadId")
+		upload, ok := s.uploads[id]
+		if !ok {
+			s.fail(w, req, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		var complete struct {
+			Parts []struct {
+				PartNumber int
+			} `xml:"Part"`
+		}
+		if err := xml.Unmarshal(body, &complete); err != nil {
+			s.fail(w, req, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		var data []byte
+		for _, part := range complete.Parts {
+			data = append(data, upload.parts[part.PartNumber]...)
+		}
+		delete(s.uploads, id)
+		object := s.put(upload.object, data, upload.header)
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Bucket  string
+			Key     string
+			ETag    string
+		}{Bucket: bucket, Key: key, ETag: quote(object.etag)})
+
+	case req.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(s.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+
+	case req.Method == http.MethodPut && req.Header.Get("X-Amz-Copy-Source") != "":
+		source, err := url.PathUnescape(strings.TrimPrefix(req.Header.Get("X-Amz-Copy-Source"), "/"))
+		if err != nil {
+			s.fail(w, req, http.StatusBadRequest, "InvalidArgument")
+			return
+		}
+		src, ok := s.objects[source]
+		if !ok {
+			s.fail(w, req, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		header := src.header.Clone()
+		if req.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+			header = objectHeader(req.Header)
+		}
+		object := s.put(name, bytes.Clone(src.data), header)
+		writeXML(w, struct {
+			XMLName      xml.Name `xml:"CopyObjectResult"`
+			LastModified string
+			ETag         string
+		}{LastModified: object.modified.Format(time.RFC3339), ETag: quote(object.etag)})
+
+	case req.Method == http.MethodPut:
+		s.put(name, body, objectHeader(req.Header))
+
+	case req.Method == http.MethodHead || req.Method == http.MethodGet:
+		object, ok := s.objects[name]
+		if !ok {
+			s.fail(w, req, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if match := req.Header.Get("If-Match"); match != "" && strings.Trim(match, `"`) != object.etag {
+			s.fail(w, req, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		for k, v := range object.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("ETag", quote(object.etag))
+		w.Header().Set("Last-Modified", object.modified.Format(http.TimeFormat))
+		w.Header().Set("Accept-Ranges", "bytes")
+
+		data := object.data
+		status := http.StatusOK
+		if rng := req.Header.Get("Range"); rng != "" {
+			start, end, ok := parseByteRange(rng, int64(len(data)))
+			if !ok {
+				s.fail(w, req, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+				return
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			data = data[start : end+1]
+			status = http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(status)
+		if req.Method == http.MethodGet {
+			w.Write(data)
+		}
+
+	case req.Method == http.MethodDelete:
+		delete(s.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.fail(w, req, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (s *fakeS3) put(name string, data []byte, header http.Header) *fakeS3Object {
+	object := &fakeS3Object{
+		data:     data,
+		header:   header,
+		etag:     etag(data),
+		modified: time.Now().UTC().Truncate(time.Second),
+	}
+	s.objects[name] = object
+	return object
+}
+
+func (s *fakeS3) copySource(source, byteRange string) ([]byte, bool) {
+	source, err := url.PathUnescape(strings.TrimPrefix(source, "/"))
+	if err != nil {
+		return nil, false
+	}
+	object, ok := s.objects[source]
+	if !ok {
+		return nil, false
+	}
+	if byteRange == "" {
+		return bytes.Clone(object.data), true
+	}
+	start, end, ok := parseByteRange(byteRange, int64(len(object.data)))
+	if !ok {
+		return nil, false
+	}
+	return bytes.Clone(object.data[start : end+1]), true
+}
+
+// list отвечает на ListObjectsV2. Токен продолжения - последний выданный ключ
+func (s *fakeS3) list(w http.ResponseWriter, req *http.Request, bucket string) {
+	query := req.URL.Query()
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	token := query.Get("continuation-token")
+	maxKeys, err := strconv.Atoi(query.Get("max-keys"))
+	if err != nil || maxKeys <= 0 || maxKeys > 1000 {
+		maxKeys = 1000
+	}
+
+	var keys []string
+	for name := range s.objects {
+		if key, ok := strings.CutPrefix(name, bucket+"/"); ok && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	type content struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int
+		StorageClass string
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Name                  string
+		Prefix                string
+		Delimiter             string
+		MaxKeys               int
+		KeyCount              int
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+		Contents              []content
+		CommonPrefixes        []commonPrefix
+	}{Name: bucket, Prefix: prefix, Delimiter: delimiter, MaxKeys: maxKeys}
+
+	last := ""
+	for _, key := range keys {
+		entry := key
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				entry = key[:len(prefix)+i+len(delimiter)]
+			}
+		}
+		if entry <= token || entry == last {
+			continue
+		}
+		if result.KeyCount == maxKeys {
+			result.IsTruncated = true
+			result.NextContinuationToken = last
+			break
+		}
+		if entry != key {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: entry})
+		} else {
+			object := s.objects[bucket+"/"+key]
+			result.Contents = append(result.Contents, content{
+				Key:          key,
+				LastModified: object.modified.Format(time.RFC3339),
+				ETag:         quote(object.etag),
+				Size:         len(object.data),
+				StorageClass: "STANDARD",
+			})
+		}
+		result.KeyCount++
+		last = entry
+	}
+	writeXML(w, result)
+}
+
+func (s *fakeS3) fail(w http.ResponseWriter, req *http.Request, status int, code string) {
+	if req.Method == http.MethodHead {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName   xml.Name `xml:"Error"`
+		Code      string
+		Message   string
+		Resource  string
+		RequestID string `xml:"RequestId"`
+	}{Code: code, Message: code, Resource: req.URL.Path, RequestID: "fake"})
+}
+
+// readS3Body читает тело запроса. Без TLS клиент подписывает тело по
+// частям (aws-chunked), и подписи частей нужно отрезать
+func readS3Body(req *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(req.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(req.Body)
+	}
+
+	reader := bufio.NewReader(req.Body)
+	var data []byte
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data, nil
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk...)
+		if _, err := reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// objectHeader оставляет заголовки, которые S3 хранит вместе с объектом
+func objectHeader(header http.Header) http.Header {
+	stored := make(http.Header)
+	for k, v := range header {
+		if k == "Content-Type" || strings.HasPrefix(k, "X-Amz-Meta-") {
+			stored[k] = v
+		}
+	}
+	return stored
+}
+
+// metadataSize считает размер пользовательских метаданных так же, как S3:
+// сумма длин ключей без префикса X-Amz-Meta- и значений
+func metadataSize(header http.Header) int {
+	size := 0
+	for k, values := range header {
+		if key, ok := strings.CutPrefix(k, "X-Amz-Meta-"); ok {
+			for _, v := range values {
+				size += len(key) + len(v)
+			}
+		}
+	}
+	return size
+}
+
+// parseByteRange разбирает "bytes=start-end" и "bytes=start-"
+func parseByteRange(value string, size int64) (int64, int64, bool) {
+	from, to, ok := strings.Cut(strings.TrimPrefix(value, "bytes="), "-")
+	if !ok {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(from, 10, 64)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+	end := size - 1
+	if to != "" {
+		if end, err = strconv.ParseInt(to, 10, 64); err != nil || end < start {
+			return 0, 0, false
+		}
+		end = min(end, size-1)
+	}
+	return start, end, true
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func quote(s string) string {
+	return `"` + s + `"`
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(v)
+}
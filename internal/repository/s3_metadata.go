@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// Метаданные файла обычно лежат в одном заголовке объекта, поэтому
+	// содержимое и метаданные меняются одной операцией
+	s3MetadataHeader = "X-Amz-Meta-File"
+	// S3 ограничивает пользовательские заголовки объекта 2 КиБ. Метаданные
+	// больше сохраняются отдельным неизменяемым объектом под префиксом
+	// meta/, а заголовок ссылается на него - замена по-прежнему атомарна
+	s3MetadataRefHeader = "X-Amz-Meta-File-Ref"
+	maxS3MetadataSize   = 2 << 10
+
+	// Сколько файлов помнит кеш метаданных для списков
+	s3MetadataCacheSize = 50000
+)
+
+// s3Sidecar - вынесенные из заголовка метаданные. Owner - ключ объекта,
+// которому они принадлежат: по нему очистка находит записи удаленных и
+// перезаписанных объектов
+type s3Sidecar struct {
+	Owner    string          `json:"owner"`
+	Metadata json.RawMessage `json:"metadata"`
+}
+
+// encodeMetadata возвращает пользовательские заголовки объекта key с
+// метаданными meta и id вынесенной записи, если они не поместились в
+// заголовок. Значения заголовков ограничены ASCII, поэтому метаданные
+// (имена, адреса загрузивших) передаются в base64
+func (r *s3Repository) encodeMetadata(ctx context.Context, key string, meta any) (map[string]string, string, error) {
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		return nil, "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(raw)
+	if len(s3MetadataHeader)+len(encoded) <= maxS3MetadataSize {
+		return map[string]string{s3MetadataHeader: encoded}, "", nil
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, "", fmt.Errorf("generate metadata id failed: %w", err)
+	}
+	body, err := json.Marshal(s3Sidecar{Owner: key, Metadata: raw})
+	if err != nil {
+		return nil, "", err
+	}
+	_, err = r.s3.PutObject(ctx, r.bucket, r.sidecarKey(id), bytes.NewReader(body), int64(len(body)), "", "",
+		minio.PutObjectOptions{ContentType: "application/json"})
+	if err != nil {
+		return nil, "", fmt.Errorf("save metadata failed: %w", err)
+	}
+	return map[string]string{s3MetadataRefHeader: id}, id, nil
+}
+
+// decodeMetadata оставляет v пустым для объектов, загруженных в бакет
+// в обход сервиса
+func (r *s3Repository) decodeMetadata(ctx context.Context, header http.Header, v any) error {
+	if id := header.Get(s3MetadataRefHeader); id != "" {
+		sidecar, err := r.sidecar(ctx, id)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(sidecar.Metadata, v)
+	}
+
+	encoded := header.Get(s3MetadataHeader)
+	if encoded == "" {
+		return nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func (r *s3Repository) sidecar(ctx context.Context, id string) (*s3Sidecar, error) {
+	if !isValidID(id) {
+		return nil, fmt.Errorf("invalid metadata reference %q", id)
+	}
+	object, _, _, err := r.s3.GetObject(ctx, r.bucket, r.sidecarKey(id), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s3Error("get metadata", id, err)
+	}
+	defer object.Close()
+
+	var sidecar s3Sidecar
+	if err := json.NewDecoder(object).Decode(&sidecar); err != nil {
+		return nil, s3Error("get metadata", id, err)
+	}
+	return &sidecar, nil
+}
+
+// purgeSidecars удаляет вынесенные метаданные объектов, которые с тех пор
+// удалили или перезаписали. Проверяются только записи старше olderThan,
+// чтобы не задеть метаданные объекта, который копируется прямо сейчас
+func (r *s3Repository) purgeSidecars(ctx context.Context, olderThan time.Time) error {
+	prefix := r.sidecarKey("")
+	return r.listKeys(ctx, prefix, "", MaxPageSize, func(object minio.ObjectInfo) error {
+		if !object.LastModified.Before(olderThan) {
+			return nil
+		}
+		id := strings.TrimPrefix(object.Key, prefix)
+		sidecar, err := r.sidecar(ctx, id)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		owner, err := r.s3.StatObject(ctx, r.bucket, sidecar.Owner, minio.StatObjectOptions{})
+		if err == nil && owner.Metadata.Get(s3MetadataRefHeader) == id {
+			return nil
+		}
+		if err != nil && !os.IsNotExist(s3Error("stat", sidecar.Owner, err)) {
+			return err
+		}
+		return r.s3.RemoveObject(ctx, r.bucket, object.Key, minio.RemoveObjectOptions{})
+	}, nil)
+}
+
+func (r *s3Repository) sidecarKey(id string) string {
+	return r.prefix + s3MetadataPrefix + id
+}
+
+// s3MetadataCache запоминает метаданные файлов, чтобы список не запрашивал
+// HEAD для каждого ключа. Запись действительна, пока ETag и время изменения
+// из ListObjectsV2 совпадают с запомненными. Объекты, измененные в обход
+// сервиса, получают новые ETag или время и перечитываются
+type s3MetadataCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]*list.Element
+	lru     *list.List // От недавно использованных к давно не использовавшимся
+}
+
+type s3CachedMetadata struct {
+	key      string
+	etag     string
+	modified time.Time
+	meta     fileMetadata
+}
+
+func newS3MetadataCache(max int) *s3MetadataCache {
+	return &s3MetadataCache{
+		max:     max,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func (c *s3MetadataCache) get(object minio.ObjectInfo) (*fileMetadata, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[object.Key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*s3CachedMetadata)
+	if entry.etag != trimETag(object.ETag) || !entry.modified.Equal(object.LastModified.Truncate(time.Second)) {
+		return nil, false
+	}
+	c.lru.MoveToFront(element)
+	meta := entry.meta
+	return &meta, true
+}
+
+func (c *s3MetadataCache) put(object minio.ObjectInfo, meta *fileMetadata) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &s3CachedMetadata{
+		key:      object.Key,
+		etag:     trimETag(object.ETag),
+		modified: object.LastModified.Truncate(time.Second),
+		meta:     *meta,
+	}
+	if element, ok := c.entries[object.Key]; ok {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return
+	}
+	c.entries[object.Key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.max {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*s3CachedMetadata).key)
+	}
+}
+
+func (c *s3MetadataCache) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.lru.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+// trimETag убирает кавычки: ListObjectsV2 отдает ETag в кавычках, а
+// HEAD и CopyObject - без них
+func trimETag(etag string) string {
+	return strings.Trim(etag, `"`)
+}
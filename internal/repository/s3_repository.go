@@ -0,0 +1,972 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Разделы бакета: текущие файлы, версии, корзина, загрузки, которые еще
+// не прошли проверку контрольной суммы, и метаданные, не поместившиеся
+// в заголовок объекта
+const (
+	s3FilesPrefix    = "files/"
+	s3VersionsPrefix = "versions/"
+	s3TrashPrefix    = "trash/"
+	s3UploadsPrefix  = "uploads/"
+	s3MetadataPrefix = "meta/"
+)
+
+const (
+	// DefaultS3PartSize - размер части multipart-загрузки по умолчанию
+	DefaultS3PartSize = 16 << 20
+	// minS3PartSize - меньше S3 не принимает (кроме последней части)
+	minS3PartSize = 5 << 20
+	// maxS3CopySize - предел одиночного CopyObject, объекты больше
+	// копируются по частям
+	maxS3CopySize = 5 << 30
+)
+
+type S3Options struct {
+	Endpoint        string // host:port без схемы
+	Region          string
+	Bucket          string
+	Prefix          string // Префикс ключей, если бакет общий с другими данными
+	AccessKeyID     string
+	SecretAccessKey string
+	UseSSL          bool
+	// Адресация bucket в пути (http://host/bucket/key) вместо поддомена.
+	// Нужна для MinIO и большинства S3-совместимых серверов
+	PathStyle bool
+	PartSize  int64 // Размер части multipart-загрузки, 0 - DefaultS3PartSize
+}
+
+// s3Repository хранит файлы в S3-совместимом хранилище. Объекты не
+// переименовываются, поэтому публикация, перенос в корзину и версии
+// выполняются копированием на стороне сервера. Блокировки имен действуют
+// в пределах процесса: с одним бакетом должен работать один экземпляр сервиса
+type s3Repository struct {
+	s3         minio.Core
+	bucket     string
+	prefix     string
+	partSize   int64
+	copySize   int64 // Объекты больше копируются по частям такого размера
+	versioning VersioningOptions
+	locks      nameLocks
+	metadata   *s3MetadataCache
+}
+
+func NewS3Repository(ctx context.Context, opts S3Options, versioning VersioningOptions) (FileRepository, error) {
+	lookup := minio.BucketLookupAuto
+	if opts.PathStyle {
+		lookup = minio.BucketLookupPath
+	}
+	client, err := minio.NewCore(opts.Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(opts.AccessKeyID, opts.SecretAccessKey, ""),
+		Secure:       opts.UseSSL,
+		Region:       opts.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create s3 client failed: %w", err)
+	}
+
+	exists, err := client.BucketExists(ctx, opts.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("check bucket %s failed: %w", opts.Bucket, err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, opts.Bucket, minio.MakeBucketOptions{Region: opts.Region}); err != nil {
+			return nil, fmt.Errorf("create bucket %s failed: %w", opts.Bucket, err)
+		}
+	}
+
+	partSize := opts.PartSize
+	if partSize == 0 {
+		partSize = DefaultS3PartSize
+	}
+	if partSize < minS3PartSize {
+		return nil, fmt.Errorf("s3 part size %d is less than %d", partSize, minS3PartSize)
+	}
+
+	prefix := strings.Trim(opts.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &s3Repository{
+		s3:         *client,
+		bucket:     opts.Bucket,
+		prefix:     prefix,
+		partSize:   partSize,
+		copySize:   maxS3CopySize,
+		versioning: versioning,
+		metadata:   newS3MetadataCache(s3MetadataCacheSize),
+	}, nil
+}
+
+// Save загружает содержимое во временный объект multipart-загрузкой,
+// одновременно считая SHA-256, и публикует его копированием только после
+// проверки контрольной суммы
+func (r *s3Repository) Save(ctx context.Context, file *entity.File, data io.Reader, opts SaveOptions) error {
+	if err := validateName(file.Name); err != nil {
+		return err
+	}
+	// Заведомо невыполнимое условие отклоняется до приема содержимого
+	if err := r.checkPrecondition(ctx, file.Name, opts.Precondition); err != nil {
+		return err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return fmt.Errorf("generate upload id failed: %w", err)
+	}
+	tempKey := r.prefix + s3UploadsPrefix + id
+	checksum, size, err := r.upload(ctx, tempKey, data)
+	if err != nil {
+		return err
+	}
+	defer r.s3.RemoveObject(context.WithoutCancel(ctx), r.bucket, tempKey, minio.RemoveObjectOptions{})
+
+	if opts.Checksum != "" && !strings.EqualFold(opts.Checksum, checksum) {
+		return fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, opts.Checksum, checksum)
+	}
+
+	if file.CreatedAt.IsZero() {
+		file.CreatedAt = time.Now()
+	}
+	if file.UpdatedAt.IsZero() {
+		file.UpdatedAt = time.Now()
+	}
+	meta := &fileMetadata{
+		SHA256:       checksum,
+		CreatedAt:    file.CreatedAt,
+		UpdatedAt:    file.UpdatedAt,
+		DeclaredSize: file.DeclaredSize,
+		Uploader:     file.Uploader,
+		ContentType:  file.ContentType,
+		Image:        newImageMetadata(file.Image),
+	}
+	publish := func(name string) func(previous *fileMetadata) error {
+		return func(previous *fileMetadata) error {
+			// При перезаписи сохраняется время создания исходного файла
+			if previous != nil && !previous.CreatedAt.IsZero() {
+				meta.CreatedAt = previous.CreatedAt
+			}
+			return r.copy(ctx, tempKey, r.fileKey(name), size, meta.ContentType, meta)
+		}
+	}
+
+	switch opts.Conflict {
+	case ConflictFail:
+		err = r.commit(ctx, file.Name, opts.Precondition, false, publish(file.Name))
+	case ConflictRename:
+		file.Name, err = r.commitUnique(ctx, file.Name, opts.Precondition, publish)
+	default:
+		err = r.commit(ctx, file.Name, opts.Precondition, true, publish(file.Name))
+	}
+	if err != nil {
+		return err
+	}
+
+	file.Size = size
+	file.Path = r.fileKey(file.Name)
+	file.Checksum = checksum
+	file.CreatedAt = meta.CreatedAt
+	return nil
+}
+
+// upload передает data частями по partSize и возвращает SHA-256 и размер.
+// При ошибке незавершенная загрузка отменяется, чтобы части не занимали место
+func (r *s3Repository) upload(ctx context.Context, key string, data io.Reader) (_ string, _ int64, err error) {
+	uploadID, err := r.s3.NewMultipartUpload(ctx, r.bucket, key, minio.PutObjectOptions{})
+	if err != nil {
+		return "", 0, fmt.Errorf("start multipart upload failed: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			r.s3.AbortMultipartUpload(context.WithoutCancel(ctx), r.bucket, key, uploadID)
+		}
+	}()
+
+	hash := sha256.New()
+	buf := make([]byte, r.partSize)
+	var parts []minio.CompletePart
+	var size int64
+	for number := 1; ; number++ {
+		n, readErr := io.ReadFull(data, buf)
+		if readErr == io.EOF && number > 1 {
+			break
+		}
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return "", 0, fmt.Errorf("write failed: %w", readErr)
+		}
+
+		hash.Write(buf[:n])
+		part, err := r.s3.PutObjectPart(ctx, r.bucket, key, uploadID, number, bytes.NewReader(buf[:n]), int64(n), minio.PutObjectPartOptions{})
+		if err != nil {
+			return "", 0, fmt.Errorf("upload part %d failed: %w", number, err)
+		}
+		parts = append(parts, minio.CompletePart{PartNumber: number, ETag: part.ETag})
+		size += int64(n)
+
+		if readErr != nil {
+			break // Последняя, неполная часть
+		}
+	}
+
+	if _, err := r.s3.CompleteMultipartUpload(ctx, r.bucket, key, uploadID, parts, minio.PutObjectOptions{}); err != nil {
+		return "", 0, fmt.Errorf("complete multipart upload failed: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// commit публикует файл name под блокировкой имени
+func (r *s3Repository) commit(ctx context.Context, name string, cond Precondition, overwrite bool, publish func(previous *fileMetadata) error) error {
+	unlock := r.locks.lock(name)
+	defer unlock()
+	return r.commitLocked(ctx, name, cond, overwrite, publish)
+}
+
+// commitLocked проверяет условие, сохраняет заменяемое содержимое как версию
+// и вызывает publish с метаданными текущего файла (nil - файла нет).
+// Вызывается под блокировкой имени
+func (r *s3Repository) commitLocked(ctx context.Context, name string, cond Precondition, overwrite bool, publish func(previous *fileMetadata) error) error {
+	current, meta, err := r.stat(ctx, name)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := cond.check(current); err != nil {
+		return err
+	}
+
+	archived := false
+	if current != nil {
+		if !overwrite {
+			return &os.PathError{Op: "save", Path: name, Err: os.ErrExist}
+		}
+		if archived, err = r.archive(ctx, current, meta); err != nil {
+			return fmt.Errorf("archive previous version failed: %w", err)
+		}
+	}
+	if err := publish(meta); err != nil {
+		return err
+	}
+	// Лишние версии удаляются только после публикации: новое содержимое
+	// может копироваться из версии, которая выходит за ограничение
+	if archived && r.versioning.Retention > 0 {
+		if _, err := r.pruneVersions(ctx, name, r.versioning.Retention); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commitUnique сохраняет файл под именем name, а если оно занято - под
+// первым свободным "photo (1).jpg", "photo (2).jpg" и т.д.
+func (r *s3Repository) commitUnique(ctx context.Context, name string, cond Precondition, publish func(name string) func(*fileMetadata) error) (string, error) {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+
+	for i := 0; i <= maxRenameAttempts; i++ {
+		candidate := name
+		if i > 0 {
+			// Условие относится к запрошенному имени, а не к подобранному
+			candidate = fmt.Sprintf("%s (%d)%s", stem, i, ext)
+			cond = Precondition{}
+		}
+
+		err := r.commit(ctx, candidate, cond, false, publish(candidate))
+		if err == nil {
+			return candidate, nil
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+	}
+	return "", &os.PathError{Op: "save", Path: name, Err: os.ErrExist}
+}
+
+func (r *s3Repository) checkPrecondition(ctx context.Context, name string, cond Precondition) error {
+	if !cond.isSet() {
+		return nil
+	}
+	current, _, err := r.stat(ctx, name)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return cond.check(current)
+}
+
+// Get открывает объект потоковым GetObject. Чтение привязано к ETag
+// объекта: если файл перезапишут во время скачивания, чтение завершится
+// ошибкой, а не смешает старое и новое содержимое
+func (r *s3Repository) Get(ctx context.Context, filename string) (*entity.File, io.ReadSeekCloser, error) {
+	file, _, err := r.stat(ctx, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	object, err := r.open(ctx, file.Path, file.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, object, nil
+}
+
+func (r *s3Repository) Stat(ctx context.Context, filename string) (*entity.File, error) {
+	file, _, err := r.stat(ctx, filename)
+	return file, err
+}
+
+func (r *s3Repository) open(ctx context.Context, key, name string) (io.ReadSeekCloser, error) {
+	info, err := r.s3.StatObject(ctx, r.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, s3Error("open", name, err)
+	}
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetMatchETag(info.ETag); err != nil {
+		return nil, err
+	}
+	object, err := r.s3.Client.GetObject(ctx, r.bucket, key, opts)
+	if err != nil {
+		return nil, s3Error("open", name, err)
+	}
+	return object, nil
+}
+
+func (r *s3Repository) List(ctx context.Context, opts ListOptions) (*ListResult, error) {
+	return listFiles(opts, r.walk(ctx), false)
+}
+
+func (r *s3Repository) Stream(ctx context.Context, filter FileFilter, batchSize int, fn func([]*entity.File) error) error {
+	return streamFiles(filter, batchSize, r.walk(ctx), fn)
+}
+
+// walk постранично обходит ключи через ListObjectsV2, по readSize ключей за
+// запрос. Без filter.Recursive ключи группируются по "/", и вложенные папки
+// приходят как общие префиксы
+func (r *s3Repository) walk(ctx context.Context) walkFunc {
+	return func(filter *FileFilter, readSize int, onFile func(*entity.File) error, onFolder func(string)) error {
+		root := r.fileKey("")
+		prefix := root
+		if folder := path.Clean(filter.Folder); folder != "." && folder != "" {
+			if err := validateName(filter.Folder); err != nil {
+				return err
+			}
+			prefix += folder + "/"
+		}
+		delimiter := "/"
+		if filter.Recursive {
+			delimiter = ""
+		}
+
+		return r.listKeys(ctx, prefix, delimiter, readSize, func(object minio.ObjectInfo) error {
+			name := strings.TrimPrefix(object.Key, root)
+			// Фильтры по имени отсекают лишние ключи до запроса метаданных
+			if !filter.matchName(path.Base(name)) {
+				return nil
+			}
+			// HEAD нужен только для объектов, которых нет в кеше
+			var file *entity.File
+			if meta, ok := r.metadata.get(object); ok {
+				file = r.file(name, object, meta)
+			} else {
+				var err error
+				file, _, err = r.stat(ctx, name)
+				if os.IsNotExist(err) {
+					return nil // Файл удалили во время обхода
+				}
+				if err != nil {
+					return err
+				}
+			}
+			if !filter.matchFile(file) {
+				return nil
+			}
+			return onFile(file)
+		}, func(folder string) {
+			if onFolder != nil {
+				onFolder(strings.TrimSuffix(strings.TrimPrefix(folder, root), "/"))
+			}
+		})
+	}
+}
+
+// listKeys передает ключи с префиксом prefix в onObject, а общие префиксы
+// (при заданном delimiter) - в onPrefix
+func (r *s3Repository) listKeys(ctx context.Context, prefix, delimiter string, maxKeys int, onObject func(minio.ObjectInfo) error, onPrefix func(string)) error {
+	token := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		result, err := r.s3.ListObjectsV2(r.bucket, prefix, "", token, delimiter, maxKeys)
+		if err != nil {
+			return fmt.Errorf("list objects failed: %w", err)
+		}
+		for _, common := range result.CommonPrefixes {
+			if onPrefix != nil {
+				onPrefix(common.Prefix)
+			}
+		}
+		for _, object := range result.Contents {
+			if err := onObject(object); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Delete переносит файл в корзину
+func (r *s3Repository) Delete(ctx context.Context, filename string, cond Precondition) (*entity.TrashItem, error) {
+	unlock := r.locks.lock(filename)
+	defer unlock()
+
+	file, meta, err := r.stat(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := cond.check(file); err != nil {
+		return nil, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, fmt.Errorf("generate trash id failed: %w", err)
+	}
+	info := trashInfo{
+		Filename:  filename,
+		Size:      file.Size,
+		DeletedAt: time.Now(),
+		Metadata:  *meta,
+	}
+	if err := r.copy(ctx, file.Path, r.trashKey(id), file.Size, meta.ContentType, info); err != nil {
+		return nil, fmt.Errorf("move to trash failed: %w", err)
+	}
+	if err := r.remove(ctx, file.Path); err != nil {
+		return nil, err
+	}
+	return info.item(id), nil
+}
+
+// ListTrash возвращает содержимое корзины, недавно удаленные файлы идут первыми
+func (r *s3Repository) ListTrash(ctx context.Context) ([]*entity.TrashItem, error) {
+	var items []*entity.TrashItem
+	err := r.listKeys(ctx, r.prefix+s3TrashPrefix, "/", MaxPageSize, func(object minio.ObjectInfo) error {
+		id := strings.TrimPrefix(object.Key, r.prefix+s3TrashPrefix)
+		info, err := r.trashInfo(ctx, id)
+		if os.IsNotExist(err) {
+			return nil // Запись восстановили или удалили во время обхода
+		}
+		if err != nil {
+			return err
+		}
+		items = append(items, info.item(id))
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	return items, nil
+}
+
+// RestoreTrash возвращает файл из корзины под исходным именем или под
+// destination, если оно задано. Существующий файл не заменяется
+func (r *s3Repository) RestoreTrash(ctx context.Context, id, destination string) (*entity.File, error) {
+	info, err := r.trashInfo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	name := info.Filename
+	if destination != "" {
+		name = destination
+	}
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	// Блокировка ключа корзины не дает восстановить одну запись дважды
+	unlock := r.locks.lock(name, r.trashKey(id))
+	defer unlock()
+
+	if info, err = r.trashInfo(ctx, id); err != nil {
+		return nil, err
+	}
+	err = r.commitLocked(ctx, name, Precondition{}, false, func(*fileMetadata) error {
+		return r.copy(ctx, r.trashKey(id), r.fileKey(name), info.Size, info.Metadata.ContentType, &info.Metadata)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.s3.RemoveObject(ctx, r.bucket, r.trashKey(id), minio.RemoveObjectOptions{}); err != nil {
+		return nil, err
+	}
+
+	file, _, err := r.stat(ctx, name)
+	return file, err
+}
+
+// PurgeTrash окончательно удаляет файлы, попавшие в корзину раньше
+// deletedBefore, вместе с историей версий, если имя больше не используется.
+// Заодно удаляются вынесенные метаданные объектов, которых уже нет
+func (r *s3Repository) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	items, err := r.ListTrash(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	kept := make(map[string]bool)
+	var purged []string
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return len(purged), err
+		}
+		if !item.DeletedAt.Before(deletedBefore) {
+			kept[item.Filename] = true
+			continue
+		}
+		if err := r.s3.RemoveObject(ctx, r.bucket, r.trashKey(item.ID), minio.RemoveObjectOptions{}); err != nil {
+			return len(purged), err
+		}
+		purged = append(purged, item.Filename)
+	}
+
+	for _, name := range purged {
+		if kept[name] {
+			continue
+		}
+		if err := r.pruneDeleted(ctx, name); err != nil {
+			return len(purged), err
+		}
+	}
+	return len(purged), r.purgeSidecars(ctx, deletedBefore)
+}
+
+// pruneDeleted удаляет историю версий name, если файла с этим именем нет
+func (r *s3Repository) pruneDeleted(ctx context.Context, name string) error {
+	unlock := r.locks.lock(name)
+	defer unlock()
+
+	if _, _, err := r.stat(ctx, name); !os.IsNotExist(err) {
+		return nil
+	}
+	_, err := r.pruneVersions(ctx, name, 0)
+	return err
+}
+
+func (r *s3Repository) trashInfo(ctx context.Context, id string) (*trashInfo, error) {
+	if !isValidID(id) {
+		return nil, &os.PathError{Op: "get trash item", Path: id, Err: os.ErrNotExist}
+	}
+	object, err := r.s3.StatObject(ctx, r.bucket, r.trashKey(id), minio.StatObjectOptions{})
+	if err != nil {
+		return nil, s3Error("get trash item", id, err)
+	}
+	var info trashInfo
+	if err := r.decodeMetadata(ctx, object.Metadata, &info); err != nil {
+		return nil, fmt.Errorf("corrupted trash item %s: %w", id, err)
+	}
+	return &info, nil
+}
+
+// ListVersions возвращает сохраненные версии файла от старых к новым
+func (r *s3Repository) ListVersions(ctx context.Context, filename string) ([]*entity.FileVersion, error) {
+	if err := validateName(filename); err != nil {
+		return nil, err
+	}
+
+	prefix := r.versionKey(filename, 0)
+	var versions []*entity.FileVersion
+	// С разделителем "/" версии файлов из вложенных папок приходят общими
+	// префиксами и не попадают в выдачу
+	err := r.listKeys(ctx, prefix, "/", MaxPageSize, func(object minio.ObjectInfo) error {
+		number, err := strconv.ParseInt(strings.TrimPrefix(object.Key, prefix), 10, 64)
+		if err != nil {
+			return nil
+		}
+		version, err := r.version(ctx, filename, number)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		versions = append(versions, version)
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Number < versions[j].Number
+	})
+	return versions, nil
+}
+
+func (r *s3Repository) GetVersion(ctx context.Context, filename string, number int64) (*entity.FileVersion, io.ReadSeekCloser, error) {
+	if err := validateName(filename); err != nil {
+		return nil, nil, err
+	}
+	version, err := r.version(ctx, filename, number)
+	if err != nil {
+		return nil, nil, err
+	}
+	object, err := r.open(ctx, version.Path, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	return version, object, nil
+}
+
+// RestoreVersion делает содержимое версии текущим. Сама версия остается
+// в истории, а заменяемое содержимое становится новой версией
+func (r *s3Repository) RestoreVersion(ctx context.Context, filename string, number int64) (*entity.File, error) {
+	if err := validateName(filename); err != nil {
+		return nil, err
+	}
+
+	unlock := r.locks.lock(filename)
+	defer unlock()
+
+	version, err := r.version(ctx, filename, number)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	meta := &fileMetadata{
+		SHA256:      version.Checksum,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Uploader:    version.Uploader,
+		ContentType: version.ContentType,
+		Image:       newImageMetadata(version.Image),
+	}
+	err = r.commitLocked(ctx, filename, Precondition{}, true, func(previous *fileMetadata) error {
+		if previous != nil && !previous.CreatedAt.IsZero() {
+			meta.CreatedAt = previous.CreatedAt
+		}
+		return r.copy(ctx, version.Path, r.fileKey(filename), version.Size, meta.ContentType, meta)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	file, _, err := r.stat(ctx, filename)
+	return file, err
+}
+
+// PruneVersions оставляет keep последних версий и возвращает число удаленных
+func (r *s3Repository) PruneVersions(ctx context.Context, filename string, keep int) (int, error) {
+	if err := validateName(filename); err != nil {
+		return 0, err
+	}
+	if keep < 0 {
+		return 0, fmt.Errorf("%w: negative keep %d", ErrInvalidVersion, keep)
+	}
+
+	unlock := r.locks.lock(filename)
+	defer unlock()
+
+	return r.pruneVersions(ctx, filename, keep)
+}
+
+// archive копирует текущее содержимое файла в новую версию, если
+// версионирование включено
+func (r *s3Repository) archive(ctx context.Context, current *entity.File, meta *fileMetadata) (bool, error) {
+	if !r.versioning.Enabled {
+		return false, nil
+	}
+	versions, err := r.ListVersions(ctx, current.Name)
+	if err != nil {
+		return false, err
+	}
+	number := int64(1)
+	if len(versions) > 0 {
+		number = versions[len(versions)-1].Number + 1
+	}
+
+	version := versionMetadata{
+		fileMetadata: *meta,
+		Size:         current.Size,
+		ArchivedAt:   time.Now(),
+	}
+	if err := r.copy(ctx, current.Path, r.versionKey(current.Name, number), current.Size, meta.ContentType, version); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *s3Repository) pruneVersions(ctx context.Context, filename string, keep int) (int, error) {
+	versions, err := r.ListVersions(ctx, filename)
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) <= keep {
+		return 0, nil
+	}
+
+	removed := 0
+	for _, version := range versions[:len(versions)-keep] {
+		if err := r.s3.RemoveObject(ctx, r.bucket, version.Path, minio.RemoveObjectOptions{}); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func (r *s3Repository) version(ctx context.Context, filename string, number int64) (*entity.FileVersion, error) {
+	if number <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidVersion, number)
+	}
+	key := r.versionKey(filename, number)
+	object, err := r.s3.StatObject(ctx, r.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, s3Error("get version", filename, err)
+	}
+	var meta versionMetadata
+	if err := r.decodeMetadata(ctx, object.Metadata, &meta); err != nil {
+		return nil, fmt.Errorf("corrupted metadata for %s version %d: %w", filename, number, err)
+	}
+
+	return &entity.FileVersion{
+		Filename:    filename,
+		Number:      number,
+		Size:        object.Size,
+		Checksum:    meta.SHA256,
+		ContentType: meta.ContentType,
+		Uploader:    meta.Uploader,
+		UpdatedAt:   meta.UpdatedAt,
+		ArchivedAt:  meta.ArchivedAt,
+		Image:       meta.Image.info(),
+		Path:        key,
+	}, nil
+}
+
+// Rename копирует объект под новым ключом и удаляет исходный. Без overwrite
+// существующий файл назначения не заменяется, и возвращается ошибка os.ErrExist
+func (r *s3Repository) Rename(ctx context.Context, from, to string, overwrite bool) (*entity.File, error) {
+	if err := validateName(to); err != nil {
+		return nil, err
+	}
+
+	unlock := r.locks.lock(from, to)
+	defer unlock()
+
+	file, meta, err := r.stat(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	if from == to {
+		return file, nil
+	}
+
+	err = r.commitLocked(ctx, to, Precondition{}, overwrite, func(*fileMetadata) error {
+		return r.copy(ctx, file.Path, r.fileKey(to), file.Size, meta.ContentType, meta)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.remove(ctx, file.Path); err != nil {
+		return nil, err
+	}
+
+	file.Name = to
+	file.Path = r.fileKey(to)
+	return file, nil
+}
+
+// Copy копирует объект на стороне сервера. Копия получает новые время
+// создания и изменения, контрольная сумма и тип содержимого переносятся
+// из исходного файла
+func (r *s3Repository) Copy(ctx context.Context, from, to string, overwrite bool) (*entity.File, error) {
+	if err := validateName(to); err != nil {
+		return nil, err
+	}
+
+	unlock := r.locks.lock(from, to)
+	defer unlock()
+
+	source, _, err := r.stat(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	if from == to {
+		if !overwrite {
+			return nil, &os.PathError{Op: "copy", Path: to, Err: os.ErrExist}
+		}
+		return source, nil
+	}
+
+	now := time.Now()
+	meta := &fileMetadata{
+		SHA256:      source.Checksum,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		ContentType: source.ContentType,
+		Image:       newImageMetadata(source.Image),
+	}
+	err = r.commitLocked(ctx, to, Precondition{}, overwrite, func(*fileMetadata) error {
+		return r.copy(ctx, source.Path, r.fileKey(to), source.Size, meta.ContentType, meta)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.File{
+		Name:        to,
+		Size:        source.Size,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Path:        r.fileKey(to),
+		Checksum:    source.Checksum,
+		ContentType: source.ContentType,
+		Image:       source.Image,
+	}, nil
+}
+
+// copy копирует объект на стороне S3, заменяя его метаданные на meta.
+// Одиночный CopyObject ограничен 5 ГиБ, объекты больше копируются по частям
+func (r *s3Repository) copy(ctx context.Context, src, dst string, size int64, contentType string, meta any) (err error) {
+	userMetadata, sidecar, err := r.encodeMetadata(ctx, dst, meta)
+	if err != nil {
+		return err
+	}
+	if sidecar != "" {
+		defer func() {
+			if err != nil {
+				r.s3.RemoveObject(context.WithoutCancel(ctx), r.bucket, r.sidecarKey(sidecar), minio.RemoveObjectOptions{})
+			}
+		}()
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	r.metadata.forget(dst)
+
+	if size <= r.copySize {
+		headers := map[string]string{
+			"X-Amz-Metadata-Directive": "REPLACE",
+			"Content-Type":             contentType,
+		}
+		for k, v := range userMetadata {
+			headers[k] = v
+		}
+		object, err := r.s3.CopyObject(ctx, r.bucket, src, r.bucket, dst, headers, minio.CopySrcOptions{}, minio.PutObjectOptions{})
+		if err != nil {
+			return err
+		}
+		// Метаданные нового файла сразу попадают в кеш списков
+		if meta, ok := meta.(*fileMetadata); ok && strings.HasPrefix(dst, r.fileKey("")) {
+			r.metadata.put(object, meta)
+		}
+		return nil
+	}
+
+	uploadID, err := r.s3.NewMultipartUpload(ctx, r.bucket, dst, minio.PutObjectOptions{
+		ContentType:  contentType,
+		UserMetadata: userMetadata,
+	})
+	if err != nil {
+		return err
+	}
+	var parts []minio.CompletePart
+	for offset, number := int64(0), 1; offset < size; offset, number = offset+r.copySize, number+1 {
+		length := min(r.copySize, size-offset)
+		part, err := r.s3.CopyObjectPart(ctx, r.bucket, src, r.bucket, dst, uploadID, number, offset, length, nil)
+		if err != nil {
+			r.s3.AbortMultipartUpload(context.WithoutCancel(ctx), r.bucket, dst, uploadID)
+			return err
+		}
+		parts = append(parts, part)
+	}
+	if _, err := r.s3.CompleteMultipartUpload(ctx, r.bucket, dst, uploadID, parts, minio.PutObjectOptions{}); err != nil {
+		r.s3.AbortMultipartUpload(context.WithoutCancel(ctx), r.bucket, dst, uploadID)
+		return err
+	}
+	return nil
+}
+
+// remove удаляет объект файла и забывает его метаданные
+func (r *s3Repository) remove(ctx context.Context, key string) error {
+	r.metadata.forget(key)
+	return r.s3.RemoveObject(ctx, r.bucket, key, minio.RemoveObjectOptions{})
+}
+
+// stat возвращает файл и его метаданные по заголовкам объекта
+func (r *s3Repository) stat(ctx context.Context, filename string) (*entity.File, *fileMetadata, error) {
+	if err := validateName(filename); err != nil {
+		return nil, nil, err
+	}
+
+	object, err := r.s3.StatObject(ctx, r.bucket, r.fileKey(filename), minio.StatObjectOptions{})
+	if err != nil {
+		return nil, nil, s3Error("stat", filename, err)
+	}
+	var meta fileMetadata
+	if err := r.decodeMetadata(ctx, object.Metadata, &meta); err != nil {
+		return nil, nil, fmt.Errorf("corrupted metadata for %s: %w", filename, err)
+	}
+	r.metadata.put(object, &meta)
+	return r.file(filename, object, &meta), &meta, nil
+}
+
+// file собирает описание файла из сведений об объекте и его метаданных
+func (r *s3Repository) file(filename string, object minio.ObjectInfo, meta *fileMetadata) *entity.File {
+	file := &entity.File{
+		Name:      filename,
+		Size:      object.Size,
+		CreatedAt: object.LastModified,
+		UpdatedAt: object.LastModified,
+		Path:      object.Key,
+	}
+	meta.apply(file)
+	return file
+}
+
+func (r *s3Repository) fileKey(filename string) string {
+	return r.prefix + s3FilesPrefix + filename
+}
+
+// versionKey возвращает ключ версии, для number == 0 - префикс всех версий файла
+func (r *s3Repository) versionKey(filename string, number int64) string {
+	key := r.prefix + s3VersionsPrefix + filename + "/"
+	if number > 0 {
+		key += strconv.FormatInt(number, 10)
+	}
+	return key
+}
+
+func (r *s3Repository) trashKey(id string) string {
+	return r.prefix + s3TrashPrefix + id
+}
+
+// s3Error приводит "объект не найден" к os.ErrNotExist, как у остальных хранилищ
+func s3Error(op, name string, err error) error {
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NotFound":
+		return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	return err
+}
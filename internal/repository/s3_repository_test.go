@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3Repository_Multipart(t *testing.T) {
+	repo := newTestS3Repository(t, VersioningOptions{})
+	ctx := context.Background()
+
+	// Две полные части и остаток
+	content := make([]byte, 2*minS3PartSize+1024)
+	_, err := rand.Read(content)
+	require.NoError(t, err)
+
+	file := &entity.File{Name: "big.bin"}
+	require.NoError(t, repo.Save(ctx, file, bytes.NewReader(content), SaveOptions{}))
+	require.Equal(t, int64(len(content)), file.Size)
+
+	_, reader, err := repo.Get(ctx, "big.bin")
+	require.NoError(t, err)
+	defer reader.Close()
+	_, err = reader.Seek(minS3PartSize, io.SeekStart)
+	require.NoError(t, err)
+	tail, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, content[minS3PartSize:], tail)
+
+	// Копирование по частям для объектов больше предела CopyObject
+	repo.copySize = minS3PartSize
+	copied, err := repo.Copy(ctx, "big.bin", "copy.bin", false)
+	require.NoError(t, err)
+	require.Equal(t, file.Checksum, copied.Checksum)
+
+	_, reader, err = repo.Get(ctx, "copy.bin")
+	require.NoError(t, err)
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, content, data)
+
+	t.Run("temporary objects are removed", func(t *testing.T) {
+		err := repo.Save(ctx, &entity.File{Name: "bad.bin"}, bytes.NewReader(content), SaveOptions{Checksum: "0000"})
+		require.ErrorIs(t, err, ErrChecksumMismatch)
+
+		var keys []string
+		err = repo.listKeys(ctx, repo.prefix, "", MaxPageSize, func(object minio.ObjectInfo) error {
+			keys = append(keys, object.Key)
+			return nil
+		}, nil)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{repo.fileKey("big.bin"), repo.fileKey("copy.bin")}, keys)
+	})
+}
+
+func TestS3Repository_Paging(t *testing.T) {
+	repo := newTestS3Repository(t, VersioningOptions{})
+	ctx := context.Background()
+
+	for i := 0; i < 7; i++ {
+		file := &entity.File{Name: fmt.Sprintf("docs/%02d.txt", i)}
+		require.NoError(t, repo.Save(ctx, file, bytes.NewReader([]byte("x")), SaveOptions{}))
+	}
+	for i := 0; i < 3; i++ {
+		file := &entity.File{Name: fmt.Sprintf("docs/sub%d/a.txt", i)}
+		require.NoError(t, repo.Save(ctx, file, bytes.NewReader([]byte("x")), SaveOptions{}))
+	}
+
+	// Размер пачки задает max-keys, поэтому обход идет несколькими запросами
+	var names []string
+	err := repo.Stream(ctx, FileFilter{Folder: "docs"}, 2, func(files []*entity.File) error {
+		for _, file := range files {
+			names = append(names, file.Name)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, names, 7)
+	require.Equal(t, "docs/00.txt", names[0])
+
+	result, err := repo.List(ctx, ListOptions{FileFilter: FileFilter{Folder: "docs"}})
+	require.NoError(t, err)
+	require.Len(t, result.Files, 7)
+	require.Equal(t, []string{"docs/sub0", "docs/sub1", "docs/sub2"}, result.Folders)
+
+	result, err = repo.List(ctx, ListOptions{FileFilter: FileFilter{Folder: "docs", Recursive: true}})
+	require.NoError(t, err)
+	require.Len(t, result.Files, 10)
+}
+
+func TestS3Repository_LargeMetadata(t *testing.T) {
+	repo, _ := newTestS3(t, VersioningOptions{Enabled: true})
+	ctx := context.Background()
+
+	// S3 не принимает пользовательские заголовки больше 2 КиБ
+	_, err := repo.s3.PutObject(ctx, repo.bucket, repo.prefix+"direct", bytes.NewReader(nil), 0, "", "",
+		minio.PutObjectOptions{UserMetadata: map[string]string{"File": strings.Repeat("x", 3000)}})
+	require.Error(t, err)
+
+	sidecars := func() []string {
+		var keys []string
+		err := repo.listKeys(ctx, repo.sidecarKey(""), "", MaxPageSize, func(object minio.ObjectInfo) error {
+			keys = append(keys, object.Key)
+			return nil
+		}, nil)
+		require.NoError(t, err)
+		return keys
+	}
+
+	uploader := strings.Repeat("u", 3000)
+	file := &entity.File{Name: "doc.txt", Uploader: uploader, ContentType: "text/plain"}
+	require.NoError(t, repo.Save(ctx, file, bytes.NewReader([]byte("v1")), SaveOptions{}))
+	require.Len(t, sidecars(), 1)
+
+	stored, reader, err := repo.Get(ctx, "doc.txt")
+	require.NoError(t, err)
+	reader.Close()
+	require.Equal(t, uploader, stored.Uploader)
+	require.Equal(t, file.Checksum, stored.Checksum)
+
+	// Версия, корзина и восстановление получают свои копии метаданных
+	require.NoError(t, repo.Save(ctx, &entity.File{Name: "doc.txt"}, bytes.NewReader([]byte("v2")), SaveOptions{}))
+	versions, err := repo.ListVersions(ctx, "doc.txt")
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	require.Equal(t, uploader, versions[0].Uploader)
+
+	_, err = repo.RestoreVersion(ctx, "doc.txt", 1)
+	require.NoError(t, err)
+	item, err := repo.Delete(ctx, "doc.txt", Precondition{})
+	require.NoError(t, err)
+	restored, err := repo.RestoreTrash(ctx, item.ID, "")
+	require.NoError(t, err)
+	require.Equal(t, uploader, restored.Uploader)
+
+	// Очистка удаляет только метаданные объектов, которых больше нет
+	_, err = repo.PurgeTrash(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.Len(t, sidecars(), 2) // Текущий файл и версия 1
+	stored, reader, err = repo.Get(ctx, "doc.txt")
+	require.NoError(t, err)
+	reader.Close()
+	require.Equal(t, uploader, stored.Uploader)
+
+	_, err = repo.PruneVersions(ctx, "doc.txt", 0)
+	require.NoError(t, err)
+	_, err = repo.Delete(ctx, "doc.txt", Precondition{})
+	require.NoError(t, err)
+	_, err = repo.PurgeTrash(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.Empty(t, sidecars())
+}
+
+func TestS3Repository_ListMetadataCache(t *testing.T) {
+	repo, fake := newTestS3(t, VersioningOptions{})
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		file := &entity.File{Name: fmt.Sprintf("%d.txt", i), Uploader: "alice"}
+		require.NoError(t, repo.Save(ctx, file, bytes.NewReader([]byte("x")), SaveOptions{}))
+	}
+	heads := func() int {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		return fake.heads
+	}
+
+	// Метаданные записанных сервисом файлов берутся из кеша
+	before := heads()
+	result, err := repo.List(ctx, ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Files, 5)
+	require.Equal(t, before, heads())
+
+	// Объект, измененный в обход сервиса, перечитывается
+	fake.mu.Lock()
+	object := fake.objects["files/"+repo.fileKey("0.txt")]
+	object.data = []byte("changed")
+	object.etag = etag(object.data)
+	object.header.Del(s3MetadataHeader)
+	fake.mu.Unlock()
+
+	result, err = repo.List(ctx, ListOptions{})
+	require.NoError(t, err)
+	require.Equal(t, before+1, heads())
+	for _, file := range result.Files {
+		if file.Name == "0.txt" {
+			require.Empty(t, file.Uploader)
+			require.Equal(t, int64(len("changed")), file.Size)
+		} else {
+			require.Equal(t, "alice", file.Uploader)
+		}
+	}
+}
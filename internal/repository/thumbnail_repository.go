@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Каталог превью изображений внутри хранилища
+const thumbnailsDir = ".thumbnails"
+
+// ThumbnailRepository хранит превью по SHA-256 исходного содержимого, а не
+// по имени файла: переименование и копирование не требуют пересоздания,
+// а превью перезаписанного файла сразу перестают находиться
+type ThumbnailRepository interface {
+	Save(ctx context.Context, checksum string, size int, data []byte) error
+	// Open возвращает превью и его размер в байтах или os.ErrNotExist
+	Open(ctx context.Context, checksum string, size int) (io.ReadCloser, int64, error)
+	// Delete удаляет превью всех размеров для содержимого checksum
+	Delete(ctx context.Context, checksum string) error
+}
+
+type thumbnailRepository struct {
+	path string
+}
+
+func NewThumbnailRepository(storagePath string) ThumbnailRepository {
+	path := filepath.Join(storagePath, thumbnailsDir)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		panic(err)
+	}
+	return &thumbnailRepository{path: path}
+}
+
+func (r *thumbnailRepository) Save(ctx context.Context, checksum string, size int, data []byte) error {
+	path, err := r.thumbnailPath(checksum, size)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Превью одного содержимого может создаваться параллельно в фоне и по
+	// запросу, поэтому файл заменяется атомарно
+	return writeFileAtomic(path, data)
+}
+
+func (r *thumbnailRepository) Open(ctx context.Context, checksum string, size int) (io.ReadCloser, int64, error) {
+	path, err := r.thumbnailPath(checksum, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+func (r *thumbnailRepository) Delete(ctx context.Context, checksum string) error {
+	if !isValidChecksum(checksum) {
+		return fmt.Errorf("%w: checksum %q", ErrInvalidPath, checksum)
+	}
+	paths, err := filepath.Glob(filepath.Join(r.path, checksum[:2], checksum+"-*"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// thumbnailPath раскладывает превью по подкаталогам из первых двух символов
+// хеша, чтобы в одном каталоге не оказалось слишком много файлов
+func (r *thumbnailRepository) thumbnailPath(checksum string, size int) (string, error) {
+	if !isValidChecksum(checksum) || size <= 0 {
+		return "", fmt.Errorf("%w: thumbnail %q of size %d", ErrInvalidPath, checksum, size)
+	}
+	return filepath.Join(r.path, checksum[:2], checksum+"-"+strconv.Itoa(size)), nil
+}
+
+// Хеш используется в путях, поэтому принимаем только SHA-256 в hex
+func isValidChecksum(checksum string) bool {
+	if len(checksum) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(checksum)
+	return err == nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestThumbnailRepository(t *testing.T) {
+	repo := NewThumbnailRepository(t.TempDir())
+	ctx := context.Background()
+	checksum := strings.Repeat("ab", 32)
+	other := strings.Repeat("cd", 32)
+
+	require.NoError(t, repo.Save(ctx, checksum, 128, []byte("small")))
+	require.NoError(t, repo.Save(ctx, checksum, 512, []byte("large")))
+	require.NoError(t, repo.Save(ctx, other, 128, []byte("other")))
+
+	reader, size, err := repo.Open(ctx, checksum, 512)
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	require.Equal(t, "large", string(data))
+	require.Equal(t, int64(5), size)
+
+	require.NoError(t, repo.Delete(ctx, checksum))
+	_, _, err = repo.Open(ctx, checksum, 128)
+	require.True(t, os.IsNotExist(err))
+	reader, _, err = repo.Open(ctx, other, 128)
+	require.NoError(t, err)
+	reader.Close()
+
+	// Хеш попадает в путь, поэтому произвольные строки отклоняются
+	require.ErrorIs(t, repo.Save(ctx, "../../escape", 128, nil), ErrInvalidPath)
+	_, _, err = repo.Open(ctx, checksum, 0)
+	require.ErrorIs(t, err, ErrInvalidPath)
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+)
+
+// Каталог корзины внутри хранилища
+const trashDir = ".trash"
+
+// trashInfo хранится рядом с содержимым удаленного файла
+type trashInfo struct {
+	Filename  string       `json:"filename"`
+	Size      int64        `json:"size"`
+	DeletedAt time.Time    `json:"deleted_at"`
+	Metadata  fileMetadata `json:"metadata"`
+}
+
+// trashStore хранит удаленные файлы в .trash/<id>, сведения о них - в <id>.json
+type trashStore struct {
+	path string
+}
+
+func newTrashStore(storagePath string) (*trashStore, error) {
+	path := filepath.Join(storagePath, trashDir)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	return &trashStore{path: path}, nil
+}
+
+// put переносит файл в корзину
+func (s *trashStore) put(file *entity.File, meta *fileMetadata) (*entity.TrashItem, error) {
+	return s.add(file, meta, func(contentPath string) error {
+		return os.Rename(file.Path, contentPath)
+	})
+}
+
+// add записывает сведения об удаленном файле, а move переносит в корзину его
+// содержимое (nil - содержимое хранится вне корзины). Сведения записываются
+// до переноса, чтобы содержимое в корзине не оставалось без исходного имени
+func (s *trashStore) add(file *entity.File, meta *fileMetadata, move func(contentPath string) error) (*entity.TrashItem, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, fmt.Errorf("generate trash id failed: %w", err)
+	}
+
+	info := trashInfo{
+		Filename:  file.Name,
+		Size:      file.Size,
+		DeletedAt: time.Now(),
+		Metadata:  *meta,
+	}
+	raw, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(s.infoPath(id), raw, 0644); err != nil {
+		return nil, err
+	}
+	if move != nil {
+		if err := move(s.contentPath(id)); err != nil {
+			os.Remove(s.infoPath(id))
+			return nil, err
+		}
+	}
+	return info.item(id), nil
+}
+
+func (s *trashStore) get(id string) (*trashInfo, error) {
+	if !isValidID(id) {
+		return nil, &os.PathError{Op: "get trash item", Path: id, Err: os.ErrNotExist}
+	}
+
+	raw, err := os.ReadFile(s.infoPath(id))
+	if err != nil {
+		return nil, err
+	}
+	var info trashInfo
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return nil, fmt.Errorf("corrupted trash item %s: %w", id, err)
+	}
+	return &info, nil
+}
+
+// list возвращает содержимое корзины, недавно удаленные файлы идут первыми
+func (s *trashStore) list() ([]*entity.TrashItem, error) {
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*entity.TrashItem
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !isValidID(id) {
+			continue
+		}
+		info, err := s.get(id)
+		if os.IsNotExist(err) {
+			continue // Запись восстановили или удалили во время обхода
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, info.item(id))
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	return items, nil
+}
+
+// remove окончательно удаляет запись корзины
+func (s *trashStore) remove(id string) error {
+	if err := os.Remove(s.contentPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(s.infoPath(id))
+}
+
+func (s *trashStore) contentPath(id string) string {
+	return filepath.Join(s.path, id)
+}
+
+func (s *trashStore) infoPath(id string) string {
+	return filepath.Join(s.path, id+".json")
+}
+
+func (i *trashInfo) item(id string) *entity.TrashItem {
+	return &entity.TrashItem{
+		ID:          id,
+		Filename:    i.Filename,
+		Size:        i.Size,
+		Checksum:    i.Metadata.SHA256,
+		ContentType: i.Metadata.ContentType,
+		DeletedAt:   i.DeletedAt,
+	}
+}
@@ -0,0 +1,311 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+)
+
+// Каталог незавершенных загрузок внутри хранилища
+const uploadSessionsDir = ".uploads"
+
+var (
+	ErrInvalidOffset = errors.New("invalid offset")
+	ErrSizeExceeded  = errors.New("upload exceeds declared size")
+)
+
+type UploadSessionRepository interface {
+	Create(ctx context.Context, session *entity.UploadSession) error
+	Get(ctx context.Context, id string) (*entity.UploadSession, error)
+	Write(ctx context.Context, id string, offset int64, data io.Reader) (*entity.UploadSession, error)
+	// Commit передает данные сессии в fn и удаляет сессию, если fn завершилась
+	// без ошибки. Пока fn работает, запись в сессию и повторный Commit ждут,
+	// а после удаления получают os.ErrNotExist
+	Commit(ctx context.Context, id string, fn func(session *entity.UploadSession, data io.Reader) error) error
+	Delete(ctx context.Context, id string) error
+	// DeleteExpired удаляет сессии, в которые не писали с updatedBefore
+	DeleteExpired(ctx context.Context, updatedBefore time.Time) (int, error)
+}
+
+type uploadSessionRepository struct {
+	path  string
+	locks sync.Map // id -> *sync.Mutex
+}
+
+// sessionInfo хранится рядом с данными сессии и переживает перезапуск сервера
+type sessionInfo struct {
+	Filename  string `json:"filename"`
+	Size      int64  `json:"size"`
+	Checksum  string `json:"sha256,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+func NewUploadSessionRepository(storagePath string) UploadSessionRepository {
+	path := filepath.Join(storagePath, uploadSessionsDir)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		panic(err)
+	}
+	return &uploadSessionRepository{path: path}
+}
+
+func (r *uploadSessionRepository) Create(ctx context.Context, session *entity.UploadSession) error {
+	id, err := newID()
+	if err != nil {
+		return fmt.Errorf("generate session id failed: %w", err)
+	}
+
+	info, err := json.Marshal(sessionInfo{
+		Filename:  session.Filename,
+		Size:      session.Size,
+		Checksum:  session.Checksum,
+		CreatedAt: session.CreatedAt.UnixNano(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(r.partPath(id), nil, 0644); err != nil {
+		return fmt.Errorf("create session data failed: %w", err)
+	}
+	if err := os.WriteFile(r.infoPath(id), info, 0644); err != nil {
+		os.Remove(r.partPath(id))
+		return fmt.Errorf("create session info failed: %w", err)
+	}
+
+	session.ID = id
+	session.CommittedSize = 0
+	session.UpdatedAt = session.CreatedAt
+	return nil
+}
+
+func (r *uploadSessionRepository) Get(ctx context.Context, id string) (*entity.UploadSession, error) {
+	if !isValidID(id) {
+		return nil, &os.PathError{Op: "get session", Path: id, Err: os.ErrNotExist}
+	}
+
+	raw, err := os.ReadFile(r.infoPath(id))
+	if err != nil {
+		return nil, err
+	}
+
+	var info sessionInfo
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return nil, fmt.Errorf("corrupted session %s: %w", id, err)
+	}
+
+	stat, err := os.Stat(r.partPath(id))
+	if err != nil {
+		return nil, err
+	}
+
+	session := &entity.UploadSession{
+		ID:            id,
+		Filename:      info.Filename,
+		Size:          info.Size,
+		Checksum:      info.Checksum,
+		CommittedSize: stat.Size(),
+		UpdatedAt:     stat.ModTime(),
+	}
+	session.CreatedAt = session.UpdatedAt
+	if info.CreatedAt != 0 {
+		session.CreatedAt = time.Unix(0, info.CreatedAt)
+	}
+	return session, nil
+}
+
+// Write дописывает данные с позиции offset. Offset может быть меньше уже
+// принятого размера (клиент повторяет чанк после обрыва), но не больше:
+// иначе в файле образовалась бы дыра. Данные за пределами заявленного
+// размера отклоняются вместе со всем чанком.
+func (r *uploadSessionRepository) Write(ctx context.Context, id string, offset int64, data io.Reader) (*entity.UploadSession, error) {
+	session, unlock, err := r.lockSession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if offset < 0 || offset > session.CommittedSize {
+		return session, fmt.Errorf("%w: got %d, committed %d", ErrInvalidOffset, offset, session.CommittedSize)
+	}
+
+	f, err := os.OpenFile(r.partPath(id), os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := f.Truncate(offset); err != nil {
+		return nil, fmt.Errorf("truncate failed: %w", err)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seek failed: %w", err)
+	}
+
+	if session.Size > 0 {
+		// Байт сверх размера достаточно, чтобы отклонить чанк
+		data = io.LimitReader(data, session.Size-offset+1)
+	}
+	written, copyErr := io.Copy(f, data)
+	if session.Size > 0 && offset+written > session.Size {
+		if err := f.Truncate(offset); err != nil {
+			return nil, fmt.Errorf("truncate failed: %w", err)
+		}
+		return nil, fmt.Errorf("%w: chunk at %d ends past %d bytes", ErrSizeExceeded, offset, session.Size)
+	}
+	// Принятым считается только то, что дошло до диска
+	if err := f.Sync(); err != nil {
+		return nil, fmt.Errorf("sync failed: %w", err)
+	}
+	if copyErr != nil {
+		return nil, fmt.Errorf("write failed: %w", copyErr)
+	}
+
+	session.CommittedSize = offset + written
+	if stat, err := f.Stat(); err == nil {
+		session.UpdatedAt = stat.ModTime()
+	}
+	return session, nil
+}
+
+func (r *uploadSessionRepository) Commit(ctx context.Context, id string, fn func(session *entity.UploadSession, data io.Reader) error) error {
+	session, unlock, err := r.lockSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.Open(r.partPath(id))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := fn(session, f); err != nil {
+		return err
+	}
+	// Файл уже сохранен, поэтому ошибка удаления сессии не возвращается:
+	// оставшуюся сессию удалит DeleteExpired
+	r.remove(id)
+	return nil
+}
+
+func (r *uploadSessionRepository) Delete(ctx context.Context, id string) error {
+	if !isValidID(id) {
+		return &os.PathError{Op: "delete session", Path: id, Err: os.ErrNotExist}
+	}
+
+	unlock := r.lock(id)
+	defer unlock()
+	err := r.remove(id)
+	if os.IsNotExist(err) {
+		r.locks.Delete(id)
+	}
+	return err
+}
+
+func (r *uploadSessionRepository) DeleteExpired(ctx context.Context, updatedBefore time.Time) (int, error) {
+	entries, err := os.ReadDir(r.path)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !isValidID(id) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+
+		session, unlock, err := r.lockSession(ctx, id)
+		if err == nil {
+			if session.UpdatedAt.Before(updatedBefore) {
+				if err = r.remove(id); err == nil {
+					removed++
+				}
+			}
+			unlock()
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// remove удаляет файлы сессии, вызывается под блокировкой сессии
+func (r *uploadSessionRepository) remove(id string) error {
+	if err := os.Remove(r.infoPath(id)); err != nil {
+		return err
+	}
+	if err := os.Remove(r.partPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	// Ожидающие блокировку увидят, что сессии больше нет
+	r.locks.Delete(id)
+	return nil
+}
+
+// lockSession блокирует сессию и читает ее. Блокировки несуществующих
+// сессий не остаются в карте, иначе запросы с чужими или устаревшими id
+// занимали бы память без ограничения
+func (r *uploadSessionRepository) lockSession(ctx context.Context, id string) (*entity.UploadSession, func(), error) {
+	if !isValidID(id) {
+		return nil, nil, &os.PathError{Op: "get session", Path: id, Err: os.ErrNotExist}
+	}
+	unlock := r.lock(id)
+	session, err := r.Get(ctx, id)
+	if err != nil {
+		if os.IsNotExist(err) {
+			r.locks.Delete(id)
+		}
+		unlock()
+		return nil, nil, err
+	}
+	return session, unlock, nil
+}
+
+func (r *uploadSessionRepository) lock(id string) func() {
+	mu, _ := r.locks.LoadOrStore(id, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+func (r *uploadSessionRepository) partPath(id string) string {
+	return filepath.Join(r.path, id+".part")
+}
+
+func (r *uploadSessionRepository) infoPath(id string) string {
+	return filepath.Join(r.path, id+".json")
+}
+
+// newID генерирует идентификатор сессии загрузки или записи корзины
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Идентификатор используется в путях, поэтому принимаем только hex
+func isValidID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
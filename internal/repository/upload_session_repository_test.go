@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadSessionRepository_Resume(t *testing.T) {
+	dir := t.TempDir()
+	repo := NewUploadSessionRepository(dir)
+	ctx := context.Background()
+
+	session := &entity.UploadSession{Filename: "big.bin", Size: 9, CreatedAt: time.Now()}
+	require.NoError(t, repo.Create(ctx, session))
+	require.NotEmpty(t, session.ID)
+
+	// Соединение оборвалось посреди чанка: принятая часть остается на диске
+	_, err := repo.Write(ctx, session.ID, 0, &failingReader{data: []byte("abcd"), err: context.Canceled})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// После перезапуска сервера сессия восстанавливается с диска
+	repo = NewUploadSessionRepository(dir)
+	got, err := repo.Get(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, "big.bin", got.Filename)
+	require.Equal(t, int64(4), got.CommittedSize)
+
+	// Дыры в данных недопустимы
+	_, err = repo.Write(ctx, session.ID, 6, bytes.NewReader([]byte("xyz")))
+	require.ErrorIs(t, err, ErrInvalidOffset)
+
+	// Повтор последнего чанка перезаписывает хвост
+	_, err = repo.Write(ctx, session.ID, 3, bytes.NewReader([]byte("defgh")))
+	require.NoError(t, err)
+	got, err = repo.Write(ctx, session.ID, 8, bytes.NewReader([]byte("i")))
+	require.NoError(t, err)
+	require.Equal(t, int64(9), got.CommittedSize)
+
+	// Данные за пределами заявленного размера отклоняются целиком
+	_, err = repo.Write(ctx, session.ID, 7, bytes.NewReader([]byte("hij")))
+	require.ErrorIs(t, err, ErrSizeExceeded)
+	got, err = repo.Get(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), got.CommittedSize)
+	_, err = repo.Write(ctx, session.ID, 7, bytes.NewReader([]byte("hi")))
+	require.NoError(t, err)
+
+	// Ошибка fn оставляет сессию на месте
+	require.Error(t, repo.Commit(ctx, session.ID, func(*entity.UploadSession, io.Reader) error {
+		return errors.New("save failed")
+	}))
+	var data []byte
+	require.NoError(t, repo.Commit(ctx, session.ID, func(s *entity.UploadSession, r io.Reader) error {
+		require.Equal(t, int64(9), s.CommittedSize)
+		data, err = io.ReadAll(r)
+		return err
+	}))
+	require.Equal(t, []byte("abcdefghi"), data)
+
+	_, err = repo.Get(ctx, session.ID)
+	require.True(t, os.IsNotExist(err))
+	err = repo.Commit(ctx, session.ID, func(*entity.UploadSession, io.Reader) error { return nil })
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestUploadSessionRepository_CommitBlocksWrites(t *testing.T) {
+	repo := NewUploadSessionRepository(t.TempDir())
+	ctx := context.Background()
+
+	session := &entity.UploadSession{Filename: "big.bin", CreatedAt: time.Now()}
+	require.NoError(t, repo.Create(ctx, session))
+	_, err := repo.Write(ctx, session.ID, 0, bytes.NewReader([]byte("abc")))
+	require.NoError(t, err)
+
+	written := make(chan error)
+	err = repo.Commit(ctx, session.ID, func(_ *entity.UploadSession, r io.Reader) error {
+		go func() {
+			_, err := repo.Write(ctx, session.ID, 0, bytes.NewReader([]byte("xyz")))
+			written <- err
+		}()
+		// Запись ждет завершения Commit и не портит сохраняемые данные
+		time.Sleep(50 * time.Millisecond)
+		data, err := io.ReadAll(r)
+		require.Equal(t, []byte("abc"), data)
+		return err
+	})
+	require.NoError(t, err)
+	require.True(t, os.IsNotExist(<-written))
+}
+
+func TestUploadSessionRepository_DeleteExpired(t *testing.T) {
+	dir := t.TempDir()
+	repo := NewUploadSessionRepository(dir)
+	ctx := context.Background()
+
+	stale := &entity.UploadSession{Filename: "stale.bin", CreatedAt: time.Now()}
+	require.NoError(t, repo.Create(ctx, stale))
+	fresh := &entity.UploadSession{Filename: "fresh.bin", CreatedAt: time.Now()}
+	require.NoError(t, repo.Create(ctx, fresh))
+
+	// Время последней записи - время изменения данных сессии
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, uploadSessionsDir, stale.ID+".part"), old, old))
+
+	removed, err := repo.DeleteExpired(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+
+	_, err = repo.Get(ctx, stale.ID)
+	require.True(t, os.IsNotExist(err))
+	_, err = repo.Get(ctx, fresh.ID)
+	require.NoError(t, err)
+}
+
+func TestUploadSessionRepository_InvalidID(t *testing.T) {
+	repo := NewUploadSessionRepository(t.TempDir())
+
+	_, err := repo.Get(context.Background(), "../../etc/passwd")
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestUploadSessionRepository_NoLocksForMissing(t *testing.T) {
+	dir := t.TempDir()
+	repo := NewUploadSessionRepository(dir)
+	ctx := context.Background()
+
+	missing, err := newID()
+	require.NoError(t, err)
+	for _, id := range []string{missing, "../../etc/passwd"} {
+		_, err := repo.Write(ctx, id, 0, bytes.NewReader([]byte("data")))
+		require.True(t, os.IsNotExist(err))
+		err = repo.Commit(ctx, id, func(*entity.UploadSession, io.Reader) error { return nil })
+		require.True(t, os.IsNotExist(err))
+		require.True(t, os.IsNotExist(repo.Delete(ctx, id)))
+	}
+
+	stale := &entity.UploadSession{Filename: "stale.bin", CreatedAt: time.Now()}
+	require.NoError(t, repo.Create(ctx, stale))
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, uploadSessionsDir, stale.ID+".part"), old, old))
+	removed, err := repo.DeleteExpired(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+
+	locks := 0
+	repo.(*uploadSessionRepository).locks.Range(func(any, any) bool {
+		locks++
+		return true
+	})
+	require.Zero(t, locks)
+}
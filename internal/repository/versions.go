@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+)
+
+// Каталог с предыдущими версиями файлов внутри хранилища
+const versionsDir = ".versions"
+
+var ErrInvalidVersion = errors.New("invalid version")
+
+type VersioningOptions struct {
+	// Сохранять предыдущее содержимое при перезаписи файла
+	Enabled bool
+	// Сколько последних версий хранить, 0 - без ограничения
+	Retention int
+}
+
+// versionMetadata - метаданные содержимого на момент, когда оно стало версией
+type versionMetadata struct {
+	fileMetadata
+	Size       int64     `json:"size"`
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+// versionStore хранит версии файла "folder/name" в
+// .versions/folder/.name/<номер>, метаданные версии лежат рядом в
+// <номер>.json
+type versionStore struct {
+	path string
+	opts VersioningOptions
+}
+
+func newVersionStore(storagePath string, opts VersioningOptions) (*versionStore, error) {
+	path := filepath.Join(storagePath, versionsDir)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	// Прежняя раскладка: .versions/<имя>/<номер>
+	err := migrateLayout(path, func(legacy string) string {
+		dir, base := filepath.Split(legacy)
+		dir = filepath.Clean(dir)
+		if _, err := strconv.ParseInt(strings.TrimSuffix(base, ".json"), 10, 64); err != nil || dir == path {
+			return ""
+		}
+		return filepath.Join(filepath.Dir(dir), "."+filepath.Base(dir), base)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("migrate versions layout failed: %w", err)
+	}
+	return &versionStore{path: path, opts: opts}, nil
+}
+
+// archive делает текущее содержимое файла новой версией. Содержимое
+// связывается жесткой ссылкой, поэтому файл не копируется, а последующий
+// rename поверх него не затрагивает версию
+func (s *versionStore) archive(filename, path string, meta *fileMetadata) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil // Перезаписывать нечего
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = s.add(filename, meta, info.Size(), func(contentPath string) error {
+		return os.Link(path, contentPath)
+	})
+	return err
+}
+
+// add записывает метаданные новой версии, а link размещает ее содержимое
+// (nil - содержимое хранится вне каталога версий). Возвращает версии,
+// удаленные по ограничению на их число
+func (s *versionStore) add(filename string, meta *fileMetadata, size int64, link func(contentPath string) error) ([]*entity.FileVersion, error) {
+	versions, err := s.list(filename)
+	if err != nil {
+		return nil, err
+	}
+	number := int64(1)
+	if len(versions) > 0 {
+		number = versions[len(versions)-1].Number + 1
+	}
+
+	if err := os.MkdirAll(s.dir(filename), 0755); err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(versionMetadata{
+		fileMetadata: *meta,
+		Size:         size,
+		ArchivedAt:   time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(s.metaPath(filename, number), raw, 0644); err != nil {
+		return nil, err
+	}
+	if link != nil {
+		if err := link(s.contentPath(filename, number)); err != nil {
+			os.Remove(s.metaPath(filename, number))
+			return nil, err
+		}
+	}
+
+	if s.opts.Retention > 0 {
+		return s.prune(filename, s.opts.Retention)
+	}
+	return nil, nil
+}
+
+// list возвращает версии файла от старых к новым
+func (s *versionStore) list(filename string) ([]*entity.FileVersion, error) {
+	entries, err := os.ReadDir(s.dir(filename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []*entity.FileVersion
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		number, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		version, err := s.get(filename, number)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Number < versions[j].Number
+	})
+	return versions, nil
+}
+
+func (s *versionStore) get(filename string, number int64) (*entity.FileVersion, error) {
+	if number <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidVersion, number)
+	}
+
+	raw, err := os.ReadFile(s.metaPath(filename, number))
+	if err != nil {
+		return nil, err
+	}
+	var meta versionMetadata
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, fmt.Errorf("corrupted metadata for %s version %d: %w", filename, number, err)
+	}
+
+	return &entity.FileVersion{
+		Filename:    filename,
+		Number:      number,
+		Size:        meta.Size,
+		Checksum:    meta.SHA256,
+		ContentType: meta.ContentType,
+		Uploader:    meta.Uploader,
+		UpdatedAt:   meta.UpdatedAt,
+		ArchivedAt:  meta.ArchivedAt,
+		Image:       meta.Image.info(),
+		Path:        s.contentPath(filename, number),
+	}, nil
+}
+
+// prune оставляет keep последних версий и возвращает удаленные
+func (s *versionStore) prune(filename string, keep int) ([]*entity.FileVersion, error) {
+	versions, err := s.list(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) <= keep {
+		return nil, nil
+	}
+
+	var removed []*entity.FileVersion
+	for _, version := range versions[:len(versions)-keep] {
+		if err := s.remove(filename, version.Number); err != nil {
+			return removed, err
+		}
+		removed = append(removed, version)
+	}
+	if keep == 0 {
+		os.Remove(s.dir(filename))
+	}
+	return removed, nil
+}
+
+func (s *versionStore) remove(filename string, number int64) error {
+	if err := os.Remove(s.contentPath(filename, number)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(s.metaPath(filename, number)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *versionStore) dir(filename string) string {
+	return hiddenLeaf(s.path, filename)
+}
+
+func (s *versionStore) contentPath(filename string, number int64) string {
+	return filepath.Join(s.dir(filename), strconv.FormatInt(number, 10))
+}
+
+func (s *versionStore) metaPath(filename string, number int64) string {
+	return s.contentPath(filename, number) + ".json"
+}
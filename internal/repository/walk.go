@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+)
+
+// walkFunc обходит файлы хранилища, подходящие под filter. Подходящие файлы
+// передаются в onFile, подпапки при нерекурсивном обходе - в onFolder
+type walkFunc func(filter *FileFilter, readSize int, onFile func(*entity.File) error, onFolder func(string)) error
+
+// listFiles собирает страницу списка файлов. dedup - одинаковое содержимое
+// хранится один раз, и физический объем считается по уникальным хешам.
+//
+// Любая страница обходит все подходящие под фильтр файлы: сортировка бывает
+// по времени и размеру, а ListResult содержит объем всех подходящих файлов.
+// В памяти при этом держится только страница. Без индекса это обход дерева
+// каталогов с чтением метаданных каждого файла, поэтому для больших
+// локальных хранилищ нужен индекс (storage.local.index)
+func listFiles(opts ListOptions, walk walkFunc, dedup bool) (*ListResult, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	page, err := opts.newPageCollector()
+	if err != nil {
+		return nil, err
+	}
+
+	var folders []string
+	var logical, physical int64
+	// Содержимое запоминается только при дедупликации, иначе список всего
+	// хранилища держал бы в памяти контрольную сумму каждого файла
+	var blobs map[string]bool
+	if dedup {
+		blobs = make(map[string]bool)
+	}
+	err = walk(&opts.FileFilter, MaxPageSize, func(file *entity.File) error {
+		page.add(file)
+		logical += file.Size
+		switch {
+		case !dedup || file.Checksum == "":
+			physical += file.Size
+		case !blobs[file.Checksum]:
+			physical += file.Size
+			blobs[file.Checksum] = true
+		}
+		return nil
+	}, func(folder string) {
+		folders = append(folders, folder)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := page.result()
+	if err != nil {
+		return nil, err
+	}
+	result.LogicalBytes = logical
+	result.PhysicalBytes = physical
+	// Подкаталоги отдаются один раз, вместе с первой страницей
+	if opts.PageToken == "" {
+		sort.Strings(folders)
+		result.Folders = folders
+	}
+	return result, nil
+}
+
+// streamFiles передает найденные файлы в fn пачками по batchSize, не
+// загружая весь список в память
+func streamFiles(filter FileFilter, batchSize int, walk walkFunc, fn func([]*entity.File) error) error {
+	if err := filter.validate(); err != nil {
+		return err
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultPageSize
+	}
+	batchSize = min(batchSize, MaxPageSize)
+
+	batch := make([]*entity.File, 0, batchSize)
+	err := walk(&filter, batchSize, func(file *entity.File) error {
+		batch = append(batch, file)
+		if len(batch) < batchSize {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		batch = make([]*entity.File, 0, batchSize)
+		return nil
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+// walkTree обходит папку filter.Folder внутри каталога base (с подпапками,
+// если filter.Recursive), читая каталоги порциями по readSize записей.
+// match превращает запись каталога в файл или возвращает nil, если запись
+// не подходит. Служебные каталоги (с точки) пропускаются
+func walkTree(ctx context.Context, base string, filter *FileFilter, readSize int,
+	match func(name string, entry os.DirEntry) (*entity.File, error),
+	onFile func(*entity.File) error, onFolder func(string)) error {
+	root := filepath.Clean(filepath.FromSlash(filter.Folder))
+	if root == "." {
+		root = ""
+	} else if err := validateName(filter.Folder); err != nil {
+		return err
+	}
+
+	pending := []string{root}
+	for len(pending) > 0 {
+		folder := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		dir, err := os.Open(filepath.Join(base, folder))
+		if os.IsNotExist(err) && folder == root {
+			return nil // Пустая папка
+		}
+		if err != nil {
+			return err
+		}
+
+		for {
+			if err := ctx.Err(); err != nil {
+				dir.Close()
+				return err
+			}
+
+			entries, readErr := dir.ReadDir(readSize)
+			for _, entry := range entries {
+				if strings.HasPrefix(entry.Name(), ".") {
+					continue
+				}
+				name := filepath.ToSlash(filepath.Join(folder, entry.Name()))
+
+				if entry.IsDir() {
+					if filter.Recursive {
+						pending = append(pending, filepath.Join(folder, entry.Name()))
+					} else if onFolder != nil {
+						onFolder(name)
+					}
+					continue
+				}
+
+				file, err := match(name, entry)
+				if err != nil {
+					dir.Close()
+					return err
+				}
+				if file == nil {
+					continue
+				}
+				if err := onFile(file); err != nil {
+					dir.Close()
+					return err
+				}
+			}
+			if readErr == io.EOF {
+				break
+			}
+			if readErr != nil {
+				dir.Close()
+				return readErr
+			}
+		}
+		dir.Close()
+	}
+	return nil
+}
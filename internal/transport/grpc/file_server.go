@@ -3,12 +3,17 @@ package grpc
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"os"
 
 	"github.com/keenoobi/grpc-file-manager/api/proto"
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	"github.com/keenoobi/grpc-file-manager/internal/imaging"
+	"github.com/keenoobi/grpc-file-manager/internal/repository"
 	"github.com/keenoobi/grpc-file-manager/internal/usecase"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -38,62 +43,145 @@ func (s *fileServiceServer) UploadFile(stream proto.FileService_UploadFileServer
 		return status.Errorf(codes.InvalidArgument, "filename is required")
 	}
 
-	// Создаем буфер для накопления данных
-	var buf bytes.Buffer
-
-	// Читаем оставшиеся сообщения (чанки данных)
-	for {
-		req, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return status.Errorf(codes.Unknown, "cannot receive chunk: %v", err)
-		}
-
-		chunk := req.GetChunk()
-		if chunk == nil {
-			continue
-		}
+	if _, ok := proto.ConflictPolicy_name[int32(metadata.GetConflictPolicy())]; !ok {
+		return status.Errorf(codes.InvalidArgument, "unknown conflict policy %d", metadata.GetConflictPolicy())
+	}
+	if _, ok := proto.StripMetadata_name[int32(metadata.GetStripMetadata())]; !ok {
+		return status.Errorf(codes.InvalidArgument, "unknown strip metadata policy %d", metadata.GetStripMetadata())
+	}
 
-		if _, err := buf.Write(chunk); err != nil {
-			return status.Errorf(codes.Internal, "cannot write chunk: %v", err)
+	// Чанки передаются в репозиторий по мере поступления, без накопления в памяти
+	reader := &uploadReader{stream: stream}
+	opts := usecase.UploadOptions{
+		Checksum:    metadata.GetSha256(),
+		Size:        int64(metadata.GetSize()),
+		Uploader:    metadata.GetUploader(),
+		ContentType: metadata.GetContentType(),
+		Conflict:    repository.ConflictPolicy(metadata.GetConflictPolicy()),
+		Strip:       imaging.StripPolicy(metadata.GetStripMetadata()),
+		Precondition: repository.Precondition{
+			IfMatch:     metadata.GetIfMatch(),
+			IfNoneMatch: metadata.GetIfNoneMatch(),
+		},
+	}
+	if metadata.GetCreatedAt() != nil {
+		opts.CreatedAt = metadata.GetCreatedAt().AsTime()
+	}
+	if opts.Uploader == "" {
+		if p, ok := peer.FromContext(stream.Context()); ok {
+			opts.Uploader = p.Addr.String()
 		}
 	}
 
-	// Сохраняем файл
-	file, err := s.fileUseCase.UploadFile(stream.Context(), filename, &buf)
+	file, err := s.fileUseCase.UploadFile(stream.Context(), filename, reader, opts)
 	if err != nil {
+		if reader.err != nil {
+			if ctxErr := stream.Context().Err(); ctxErr != nil {
+				return status.FromContextError(ctxErr).Err()
+			}
+			return status.Errorf(codes.Unknown, "cannot receive chunk: %v", reader.err)
+		}
+		if errors.Is(err, usecase.ErrInvalidFilename) || errors.Is(err, usecase.ErrContentNotAllowed) ||
+			errors.Is(err, imaging.ErrMalformedImage) {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, repository.ErrChecksumMismatch) {
+			return status.Error(codes.DataLoss, err.Error())
+		}
+		if os.IsExist(err) {
+			return status.Errorf(codes.AlreadyExists, "file %s already exists", filename)
+		}
+		if errors.Is(err, repository.ErrPreconditionFailed) {
+			return status.Error(codes.FailedPrecondition, err.Error())
+		}
 		return status.Errorf(codes.Internal, "cannot save file: %v", err)
 	}
 
 	// Отправляем ответ
 	return stream.SendAndClose(&proto.UploadFileResponse{
-		Filename:  file.Name,
-		Size:      uint64(file.Size),
-		CreatedAt: timestamppb.New(file.CreatedAt),
+		Filename:         file.Name,
+		Size:             uint64(file.Size),
+		CreatedAt:        timestamppb.New(file.CreatedAt),
+		Sha256:           file.Checksum,
+		Etag:             file.ETag(),
+		StrippedMetadata: file.StrippedMetadata,
 	})
 }
 
+// uploadReader читает чанки из стрима по одному, поэтому в памяти
+// одновременно находится не больше одного чанка
+type uploadReader struct {
+	stream proto.FileService_UploadFileServer
+	chunk  []byte
+	err    error
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		req, err := r.stream.Recv()
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		if err != nil {
+			r.err = err
+			return 0, err
+		}
+		r.chunk = req.GetChunk()
+	}
+
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return n, nil
+}
+
 func (s *fileServiceServer) DownloadFile(req *proto.DownloadFileRequest, stream proto.FileService_DownloadFileServer) error {
-	file, reader, err := s.fileUseCase.DownloadFile(stream.Context(), req.GetFilename())
+	offset, length := int64(req.GetOffset()), int64(req.GetLength())
+	opts := usecase.DownloadOptions{
+		Offset:      offset,
+		Length:      length,
+		Version:     int64(req.GetVersion()),
+		IfNoneMatch: req.GetIfNoneMatch(),
+	}
+	if req.GetIfModifiedSince() != nil {
+		opts.IfModifiedSince = req.GetIfModifiedSince().AsTime()
+	}
+
+	file, reader, err := s.fileUseCase.DownloadFile(stream.Context(), req.GetFilename(), opts)
+	if errors.Is(err, usecase.ErrNotModified) {
+		// Копия клиента актуальна: только метаданные, без содержимого
+		metadata := downloadMetadata(file, req)
+		metadata.NotModified = true
+		if err := stream.Send(&proto.DownloadFileResponse{
+			Content: &proto.DownloadFileResponse_Metadata{Metadata: metadata},
+		}); err != nil {
+			return status.Errorf(codes.Internal, "cannot send metadata: %v", err)
+		}
+		return nil
+	}
 	if err != nil {
 		if os.IsNotExist(err) {
 			return status.Error(codes.NotFound, "file not found")
 		}
+		if errors.Is(err, usecase.ErrInvalidFilename) {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, usecase.ErrInvalidRange) {
+			return status.Error(codes.OutOfRange, err.Error())
+		}
 		return status.Errorf(codes.Internal, "cannot read file: %v", err)
 	}
 	defer reader.Close()
 
+	if length == 0 || length > file.Size-offset {
+		length = file.Size - offset
+	}
+
 	// Отправляем метаданные первым сообщением
+	metadata := downloadMetadata(file, req)
+	metadata.Offset = uint64(offset)
+	metadata.Length = uint64(length)
 	if err := stream.Send(&proto.DownloadFileResponse{
-		Content: &proto.DownloadFileResponse_Metadata{
-			Metadata: &proto.FileMetadata{
-				Filename:  file.Name,
-				Size:      uint64(file.Size),
-				CreatedAt: timestamppb.New(file.CreatedAt),
-			},
-		},
+		Content: &proto.DownloadFileResponse_Metadata{Metadata: metadata},
 	}); err != nil {
 		return status.Errorf(codes.Internal, "cannot send metadata: %v", err)
 	}
@@ -119,23 +207,461 @@ func (s *fileServiceServer) DownloadFile(req *proto.DownloadFileRequest, stream
 	return nil
 }
 
+func (s *fileServiceServer) GetThumbnail(req *proto.GetThumbnailRequest, stream proto.FileService_GetThumbnailServer) error {
+	thumbnail, reader, err := s.fileUseCase.GetThumbnail(stream.Context(), req.GetFilename(), int(req.GetSize()))
+	if err != nil {
+		return imageError("cannot get thumbnail", err)
+	}
+	defer reader.Close()
+
+	if err := stream.Send(&proto.GetThumbnailResponse{
+		Content: &proto.GetThumbnailResponse_Metadata{Metadata: &proto.ThumbnailMetadata{
+			Filename:    thumbnail.Filename,
+			Size:        uint32(thumbnail.MaxSize),
+			ContentType: thumbnail.ContentType,
+			Length:      uint64(thumbnail.Size),
+			Etag:        thumbnail.Checksum,
+		}},
+	}); err != nil {
+		return status.Errorf(codes.Internal, "cannot send metadata: %v", err)
+	}
+
+	return sendImage(reader, func(chunk []byte) error {
+		return stream.Send(&proto.GetThumbnailResponse{
+			Content: &proto.GetThumbnailResponse_Chunk{Chunk: chunk},
+		})
+	})
+}
+
+func (s *fileServiceServer) TransformImage(req *proto.TransformImageRequest, stream proto.FileService_TransformImageServer) error {
+	format, ok := imageFormats[req.GetFormat()]
+	if !ok {
+		return status.Errorf(codes.InvalidArgument, "unknown image format %v", req.GetFormat())
+	}
+	rendition, reader, err := s.fileUseCase.TransformImage(stream.Context(), req.GetFilename(), usecase.TransformOptions{
+		Width:        int(req.GetWidth()),
+		Height:       int(req.GetHeight()),
+		AspectWidth:  int(req.GetAspectWidth()),
+		AspectHeight: int(req.GetAspectHeight()),
+		Format:       format,
+		Quality:      int(req.GetQuality()),
+	})
+	if err != nil {
+		return imageError("cannot transform image", err)
+	}
+	defer reader.Close()
+
+	if err := stream.Send(&proto.TransformImageResponse{
+		Content: &proto.TransformImageResponse_Metadata{Metadata: &proto.RenditionMetadata{
+			Filename:    rendition.Filename,
+			Width:       uint32(rendition.Width),
+			Height:      uint32(rendition.Height),
+			ContentType: rendition.ContentType,
+			Length:      uint64(rendition.Size),
+			Etag:        rendition.Checksum,
+		}},
+	}); err != nil {
+		return status.Errorf(codes.Internal, "cannot send metadata: %v", err)
+	}
+
+	return sendImage(reader, func(chunk []byte) error {
+		return stream.Send(&proto.TransformImageResponse{
+			Content: &proto.TransformImageResponse_Chunk{Chunk: chunk},
+		})
+	})
+}
+
+var imageFormats = map[proto.ImageFormat]string{
+	proto.ImageFormat_IMAGE_FORMAT_UNSPECIFIED: "",
+	proto.ImageFormat_IMAGE_FORMAT_JPEG:        imaging.FormatJPEG,
+	proto.ImageFormat_IMAGE_FORMAT_PNG:         imaging.FormatPNG,
+	proto.ImageFormat_IMAGE_FORMAT_GIF:         imaging.FormatGIF,
+}
+
+// imageError переводит ошибки получения превью и вариантов изображения в коды gRPC
+func imageError(action string, err error) error {
+	switch {
+	case os.IsNotExist(err):
+		return status.Error(codes.NotFound, "file not found")
+	case errors.Is(err, usecase.ErrInvalidFilename), errors.Is(err, usecase.ErrInvalidThumbnailSize),
+		errors.Is(err, usecase.ErrInvalidTransform):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, usecase.ErrNotImage), errors.Is(err, imaging.ErrTooManyPixels),
+		errors.Is(err, usecase.ErrThumbnailsDisabled), errors.Is(err, usecase.ErrTransformsDisabled):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Errorf(codes.Internal, "%s: %v", action, err)
+	}
+}
+
+// sendImage отправляет изображение чанками по 32 КиБ
+func sendImage(reader io.Reader, send func(chunk []byte) error) error {
+	buffer := make([]byte, 32*1024)
+	for {
+		n, err := reader.Read(buffer)
+		if n > 0 {
+			if err := send(buffer[:n]); err != nil {
+				return status.Errorf(codes.Internal, "cannot send chunk: %v", err)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "cannot read chunk: %v", err)
+		}
+	}
+}
+
+func downloadMetadata(file *entity.File, req *proto.DownloadFileRequest) *proto.FileMetadata {
+	return &proto.FileMetadata{
+		Filename:    file.Name,
+		Size:        uint64(file.Size),
+		CreatedAt:   timestamppb.New(file.CreatedAt),
+		Sha256:      file.Checksum,
+		ContentType: file.ContentType,
+		Uploader:    file.Uploader,
+		UpdatedAt:   timestamppb.New(file.UpdatedAt),
+		Version:     req.GetVersion(),
+		Etag:        file.ETag(),
+		Image:       toProtoImageInfo(file.Image),
+	}
+}
+
 func (s *fileServiceServer) ListFiles(ctx context.Context, req *proto.ListFilesRequest) (*proto.ListFilesResponse, error) {
-	files, err := s.fileUseCase.ListFiles(ctx)
+	opts := repository.ListOptions{
+		FileFilter: repository.FileFilter{
+			Folder:      req.GetFolder(),
+			Recursive:   req.GetRecursive(),
+			NamePrefix:  req.GetNamePrefix(),
+			NamePattern: req.GetNamePattern(),
+			MinSize:     int64(req.GetMinSize()),
+			MaxSize:     int64(req.GetMaxSize()),
+			ContentType: req.GetContentType(),
+			Uploader:    req.GetUploader(),
+		},
+		PageSize:      int(req.GetPageSize()),
+		PageToken:     req.GetPageToken(),
+		SortBy:        repository.SortField(req.GetSortBy()),
+		SortAscending: req.GetSortDirection() == proto.SortDirection_SORT_DIRECTION_ASC,
+	}
+	if req.GetCreatedAfter() != nil {
+		opts.CreatedAfter = req.GetCreatedAfter().AsTime()
+	}
+	if req.GetCreatedBefore() != nil {
+		opts.CreatedBefore = req.GetCreatedBefore().AsTime()
+	}
+
+	result, err := s.fileUseCase.ListFiles(ctx, opts)
 	if err != nil {
+		if errors.Is(err, repository.ErrInvalidListOptions) || errors.Is(err, usecase.ErrInvalidFilename) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, status.Errorf(codes.Internal, "cannot list files: %v", err)
 	}
 
 	response := &proto.ListFilesResponse{
-		Files: make([]*proto.FileInfo, len(files)),
+		Files:         make([]*proto.FileInfo, len(result.Files)),
+		NextPageToken: result.NextPageToken,
+		Folders:       result.Folders,
+		LogicalBytes:  uint64(result.LogicalBytes),
+		PhysicalBytes: uint64(result.PhysicalBytes),
 	}
 
-	for i, file := range files {
-		response.Files[i] = &proto.FileInfo{
-			Filename:  file.Name,
-			CreatedAt: timestamppb.New(file.CreatedAt),
-			UpdatedAt: timestamppb.New(file.UpdatedAt),
-		}
+	for i, file := range result.Files {
+		response.Files[i] = toProtoFileInfo(file)
 	}
 
 	return response, nil
 }
+
+func (s *fileServiceServer) StreamFiles(req *proto.StreamFilesRequest, stream proto.FileService_StreamFilesServer) error {
+	filter := repository.FileFilter{
+		Folder:      req.GetFolder(),
+		Recursive:   req.GetRecursive(),
+		NamePrefix:  req.GetNamePrefix(),
+		NamePattern: req.GetNamePattern(),
+		MinSize:     int64(req.GetMinSize()),
+		MaxSize:     int64(req.GetMaxSize()),
+		ContentType: req.GetContentType(),
+		Uploader:    req.GetUploader(),
+	}
+	if req.GetCreatedAfter() != nil {
+		filter.CreatedAfter = req.GetCreatedAfter().AsTime()
+	}
+	if req.GetCreatedBefore() != nil {
+		filter.CreatedBefore = req.GetCreatedBefore().AsTime()
+	}
+
+	var sendErr error
+	err := s.fileUseCase.StreamFiles(stream.Context(), filter, int(req.GetBatchSize()), func(files []*entity.File) error {
+		response := &proto.StreamFilesResponse{Files: make([]*proto.FileInfo, len(files))}
+		for i, file := range files {
+			response.Files[i] = toProtoFileInfo(file)
+		}
+		sendErr = stream.Send(response)
+		return sendErr
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidListOptions) || errors.Is(err, usecase.ErrInvalidFilename) {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		if sendErr != nil {
+			return status.Errorf(codes.Internal, "cannot send files: %v", sendErr)
+		}
+		if ctxErr := stream.Context().Err(); ctxErr != nil {
+			return status.FromContextError(ctxErr).Err()
+		}
+		return status.Errorf(codes.Internal, "cannot list files: %v", err)
+	}
+	return nil
+}
+
+func toProtoFileInfo(file *entity.File) *proto.FileInfo {
+	return &proto.FileInfo{
+		Filename:    file.Name,
+		CreatedAt:   timestamppb.New(file.CreatedAt),
+		UpdatedAt:   timestamppb.New(file.UpdatedAt),
+		Sha256:      file.Checksum,
+		Size:        uint64(file.Size),
+		ContentType: file.ContentType,
+		Uploader:    file.Uploader,
+		Etag:        file.ETag(),
+		Image:       toProtoImageInfo(file.Image),
+	}
+}
+
+func toProtoImageInfo(info *entity.ImageInfo) *proto.ImageInfo {
+	if info == nil {
+		return nil
+	}
+	image := &proto.ImageInfo{
+		Width:       uint32(info.Width),
+		Height:      uint32(info.Height),
+		Format:      info.Format,
+		ColorModel:  info.ColorModel,
+		Orientation: uint32(info.Orientation),
+		CameraModel: info.CameraModel,
+	}
+	if !info.TakenAt.IsZero() {
+		image.TakenAt = timestamppb.New(info.TakenAt)
+	}
+	return image
+}
+
+func (s *fileServiceServer) DeleteFile(ctx context.Context, req *proto.DeleteFileRequest) (*proto.DeleteFileResponse, error) {
+	cond := repository.Precondition{IfMatch: req.GetIfMatch(), IfNoneMatch: req.GetIfNoneMatch()}
+	item, err := s.fileUseCase.DeleteFile(ctx, req.GetFilename(), cond)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidFilename) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if os.IsNotExist(err) {
+			return nil, status.Error(codes.NotFound, "file not found")
+		}
+		if errors.Is(err, repository.ErrPreconditionFailed) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "cannot delete file: %v", err)
+	}
+
+	return &proto.DeleteFileResponse{TrashId: item.ID}, nil
+}
+
+func (s *fileServiceServer) ListTrash(ctx context.Context, req *proto.ListTrashRequest) (*proto.ListTrashResponse, error) {
+	items, err := s.fileUseCase.ListTrash(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot list trash: %v", err)
+	}
+
+	resp := &proto.ListTrashResponse{Items: make([]*proto.TrashItem, 0, len(items))}
+	for _, item := range items {
+		resp.Items = append(resp.Items, &proto.TrashItem{
+			TrashId:     item.ID,
+			Filename:    item.Filename,
+			Size:        uint64(item.Size),
+			Sha256:      item.Checksum,
+			ContentType: item.ContentType,
+			DeletedAt:   timestamppb.New(item.DeletedAt),
+		})
+	}
+	return resp, nil
+}
+
+func (s *fileServiceServer) RestoreFile(ctx context.Context, req *proto.RestoreFileRequest) (*proto.RestoreFileResponse, error) {
+	file, err := s.fileUseCase.RestoreFile(ctx, req.GetTrashId(), req.GetDestination())
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidFilename):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case os.IsNotExist(err):
+			return nil, status.Error(codes.NotFound, "trash item not found")
+		case os.IsExist(err):
+			return nil, status.Error(codes.AlreadyExists, "file with this name already exists")
+		default:
+			return nil, status.Errorf(codes.Internal, "cannot restore file: %v", err)
+		}
+	}
+
+	return &proto.RestoreFileResponse{File: toProtoFileInfo(file)}, nil
+}
+
+func (s *fileServiceServer) EmptyTrash(ctx context.Context, req *proto.EmptyTrashRequest) (*proto.EmptyTrashResponse, error) {
+	removed, err := s.fileUseCase.EmptyTrash(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot empty trash: %v", err)
+	}
+
+	return &proto.EmptyTrashResponse{Removed: uint32(removed)}, nil
+}
+
+func (s *fileServiceServer) RenameFile(ctx context.Context, req *proto.RenameFileRequest) (*proto.RenameFileResponse, error) {
+	file, err := s.fileUseCase.RenameFile(ctx, req.GetSource(), req.GetDestination(), req.GetOverwrite())
+	if err != nil {
+		return nil, transferError("rename", req.GetDestination(), err)
+	}
+
+	return &proto.RenameFileResponse{File: toProtoFileInfo(file)}, nil
+}
+
+func (s *fileServiceServer) CopyFile(ctx context.Context, req *proto.CopyFileRequest) (*proto.CopyFileResponse, error) {
+	file, err := s.fileUseCase.CopyFile(ctx, req.GetSource(), req.GetDestination(), req.GetOverwrite())
+	if err != nil {
+		return nil, transferError("copy", req.GetDestination(), err)
+	}
+
+	return &proto.CopyFileResponse{File: toProtoFileInfo(file)}, nil
+}
+
+func (s *fileServiceServer) ListFileVersions(ctx context.Context, req *proto.ListFileVersionsRequest) (*proto.ListFileVersionsResponse, error) {
+	versions, err := s.fileUseCase.ListFileVersions(ctx, req.GetFilename())
+	if err != nil {
+		return nil, versionError(err)
+	}
+
+	resp := &proto.ListFileVersionsResponse{Versions: make([]*proto.FileVersion, 0, len(versions))}
+	for _, version := range versions {
+		resp.Versions = append(resp.Versions, &proto.FileVersion{
+			Version:     uint64(version.Number),
+			Size:        uint64(version.Size),
+			Sha256:      version.Checksum,
+			ContentType: version.ContentType,
+			Uploader:    version.Uploader,
+			UpdatedAt:   timestamppb.New(version.UpdatedAt),
+			ArchivedAt:  timestamppb.New(version.ArchivedAt),
+		})
+	}
+	return resp, nil
+}
+
+func (s *fileServiceServer) RestoreFileVersion(ctx context.Context, req *proto.RestoreFileVersionRequest) (*proto.RestoreFileVersionResponse, error) {
+	file, err := s.fileUseCase.RestoreFileVersion(ctx, req.GetFilename(), int64(req.GetVersion()))
+	if err != nil {
+		return nil, versionError(err)
+	}
+
+	return &proto.RestoreFileVersionResponse{File: toProtoFileInfo(file)}, nil
+}
+
+func (s *fileServiceServer) PruneFileVersions(ctx context.Context, req *proto.PruneFileVersionsRequest) (*proto.PruneFileVersionsResponse, error) {
+	removed, err := s.fileUseCase.PruneFileVersions(ctx, req.GetFilename(), int(req.GetKeep()))
+	if err != nil {
+		return nil, versionError(err)
+	}
+
+	return &proto.PruneFileVersionsResponse{Removed: uint32(removed)}, nil
+}
+
+func versionError(err error) error {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidFilename), errors.Is(err, repository.ErrInvalidVersion):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case os.IsNotExist(err):
+		return status.Error(codes.NotFound, "version not found")
+	case errors.Is(err, repository.ErrChecksumMismatch):
+		return status.Error(codes.DataLoss, err.Error())
+	default:
+		return status.Errorf(codes.Internal, "version operation failed: %v", err)
+	}
+}
+
+// transferError переводит ошибки переименования и копирования в коды gRPC
+func transferError(op, destination string, err error) error {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidFilename):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case os.IsNotExist(err):
+		return status.Error(codes.NotFound, "file not found")
+	case os.IsExist(err):
+		return status.Errorf(codes.AlreadyExists, "file %s already exists", destination)
+	default:
+		return status.Errorf(codes.Internal, "cannot %s file: %v", op, err)
+	}
+}
+
+func (s *fileServiceServer) CreateUploadSession(ctx context.Context, req *proto.CreateUploadSessionRequest) (*proto.UploadSession, error) {
+	session, err := s.fileUseCase.CreateUploadSession(ctx, req.GetFilename(), int64(req.GetSize()), req.GetSha256())
+	if err != nil {
+		return nil, uploadSessionError(err)
+	}
+	return toProtoUploadSession(session), nil
+}
+
+func (s *fileServiceServer) UploadSessionChunk(ctx context.Context, req *proto.UploadSessionChunkRequest) (*proto.UploadSession, error) {
+	session, err := s.fileUseCase.WriteUploadSession(ctx, req.GetSessionId(), int64(req.GetOffset()), bytes.NewReader(req.GetChunk()))
+	if err != nil {
+		return nil, uploadSessionError(err)
+	}
+	return toProtoUploadSession(session), nil
+}
+
+func (s *fileServiceServer) GetUploadSession(ctx context.Context, req *proto.GetUploadSessionRequest) (*proto.UploadSession, error) {
+	session, err := s.fileUseCase.GetUploadSession(ctx, req.GetSessionId())
+	if err != nil {
+		return nil, uploadSessionError(err)
+	}
+	return toProtoUploadSession(session), nil
+}
+
+func (s *fileServiceServer) FinalizeUploadSession(ctx context.Context, req *proto.FinalizeUploadSessionRequest) (*proto.UploadFileResponse, error) {
+	file, err := s.fileUseCase.FinalizeUploadSession(ctx, req.GetSessionId())
+	if err != nil {
+		return nil, uploadSessionError(err)
+	}
+
+	return &proto.UploadFileResponse{
+		Filename:         file.Name,
+		Size:             uint64(file.Size),
+		CreatedAt:        timestamppb.New(file.CreatedAt),
+		Sha256:           file.Checksum,
+		Etag:             file.ETag(),
+		StrippedMetadata: file.StrippedMetadata,
+	}, nil
+}
+
+func uploadSessionError(err error) error {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidFilename), errors.Is(err, usecase.ErrContentNotAllowed),
+		errors.Is(err, imaging.ErrMalformedImage), errors.Is(err, repository.ErrSizeExceeded):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, repository.ErrInvalidOffset), errors.Is(err, usecase.ErrUploadIncomplete):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, repository.ErrChecksumMismatch):
+		return status.Error(codes.DataLoss, err.Error())
+	case os.IsNotExist(err), errors.Is(err, os.ErrNotExist):
+		return status.Error(codes.NotFound, "upload session not found")
+	default:
+		return status.Errorf(codes.Internal, "upload session failed: %v", err)
+	}
+}
+
+func toProtoUploadSession(session *entity.UploadSession) *proto.UploadSession {
+	return &proto.UploadSession{
+		SessionId:     session.ID,
+		Filename:      session.Filename,
+		Size:          uint64(session.Size),
+		CommittedSize: uint64(session.CommittedSize),
+		CreatedAt:     timestamppb.New(session.CreatedAt),
+		UpdatedAt:     timestamppb.New(session.UpdatedAt),
+	}
+}
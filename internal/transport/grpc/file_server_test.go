@@ -2,34 +2,138 @@ package grpc
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"os"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/keenoobi/grpc-file-manager/api/proto"
 	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	"github.com/keenoobi/grpc-file-manager/internal/imaging"
+	"github.com/keenoobi/grpc-file-manager/internal/repository"
+	"github.com/keenoobi/grpc-file-manager/internal/usecase"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type MockFileUseCase struct {
 	mock.Mock
 }
 
-func (m *MockFileUseCase) UploadFile(ctx context.Context, filename string, data io.Reader) (*entity.File, error) {
-	args := m.Called(ctx, filename, data)
+func (m *MockFileUseCase) UploadFile(ctx context.Context, filename string, data io.Reader, opts usecase.UploadOptions) (*entity.File, error) {
+	args := m.Called(ctx, filename, data, opts)
 	return args.Get(0).(*entity.File), args.Error(1)
 }
 
-func (m *MockFileUseCase) DownloadFile(ctx context.Context, filename string) (*entity.File, io.ReadCloser, error) {
-	args := m.Called(ctx, filename)
+func (m *MockFileUseCase) DownloadFile(ctx context.Context, filename string, opts usecase.DownloadOptions) (*entity.File, io.ReadCloser, error) {
+	args := m.Called(ctx, filename, opts)
 	return args.Get(0).(*entity.File), args.Get(1).(io.ReadCloser), args.Error(2)
 }
 
-func (m *MockFileUseCase) ListFiles(ctx context.Context) ([]*entity.File, error) {
+func (m *MockFileUseCase) ListFiles(ctx context.Context, opts repository.ListOptions) (*repository.ListResult, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).(*repository.ListResult), args.Error(1)
+}
+
+func (m *MockFileUseCase) StreamFiles(ctx context.Context, filter repository.FileFilter, batchSize int, fn func([]*entity.File) error) error {
+	args := m.Called(ctx, filter, batchSize, fn)
+	return args.Error(0)
+}
+
+func (m *MockFileUseCase) RenameFile(ctx context.Context, from, to string, overwrite bool) (*entity.File, error) {
+	args := m.Called(ctx, from, to, overwrite)
+	return args.Get(0).(*entity.File), args.Error(1)
+}
+
+func (m *MockFileUseCase) CopyFile(ctx context.Context, from, to string, overwrite bool) (*entity.File, error) {
+	args := m.Called(ctx, from, to, overwrite)
+	return args.Get(0).(*entity.File), args.Error(1)
+}
+
+func (m *MockFileUseCase) ListFileVersions(ctx context.Context, filename string) ([]*entity.FileVersion, error) {
+	args := m.Called(ctx, filename)
+	return args.Get(0).([]*entity.FileVersion), args.Error(1)
+}
+
+func (m *MockFileUseCase) RestoreFileVersion(ctx context.Context, filename string, version int64) (*entity.File, error) {
+	args := m.Called(ctx, filename, version)
+	return args.Get(0).(*entity.File), args.Error(1)
+}
+
+func (m *MockFileUseCase) PruneFileVersions(ctx context.Context, filename string, keep int) (int, error) {
+	args := m.Called(ctx, filename, keep)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockFileUseCase) DeleteFile(ctx context.Context, filename string, cond repository.Precondition) (*entity.TrashItem, error) {
+	args := m.Called(ctx, filename, cond)
+	return args.Get(0).(*entity.TrashItem), args.Error(1)
+}
+
+func (m *MockFileUseCase) ListTrash(ctx context.Context) ([]*entity.TrashItem, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*entity.TrashItem), args.Error(1)
+}
+
+func (m *MockFileUseCase) RestoreFile(ctx context.Context, trashID, destination string) (*entity.File, error) {
+	args := m.Called(ctx, trashID, destination)
+	return args.Get(0).(*entity.File), args.Error(1)
+}
+
+func (m *MockFileUseCase) EmptyTrash(ctx context.Context) (int, error) {
 	args := m.Called(ctx)
-	return args.Get(0).([]*entity.File), args.Error(1)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockFileUseCase) PurgeTrash(ctx context.Context, olderThan time.Duration) (int, error) {
+	args := m.Called(ctx, olderThan)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockFileUseCase) CreateUploadSession(ctx context.Context, filename string, size int64, checksum string) (*entity.UploadSession, error) {
+	args := m.Called(ctx, filename, size, checksum)
+	return args.Get(0).(*entity.UploadSession), args.Error(1)
+}
+
+func (m *MockFileUseCase) WriteUploadSession(ctx context.Context, id string, offset int64, data io.Reader) (*entity.UploadSession, error) {
+	args := m.Called(ctx, id, offset, data)
+	return args.Get(0).(*entity.UploadSession), args.Error(1)
+}
+
+func (m *MockFileUseCase) GetUploadSession(ctx context.Context, id string) (*entity.UploadSession, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.UploadSession), args.Error(1)
+}
+
+func (m *MockFileUseCase) FinalizeUploadSession(ctx context.Context, id string) (*entity.File, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.File), args.Error(1)
+}
+
+func (m *MockFileUseCase) PurgeUploadSessions(ctx context.Context, olderThan time.Duration) (int, error) {
+	args := m.Called(ctx, olderThan)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockFileUseCase) GetThumbnail(ctx context.Context, filename string, size int) (*entity.Thumbnail, io.ReadCloser, error) {
+	args := m.Called(ctx, filename, size)
+	return args.Get(0).(*entity.Thumbnail), args.Get(1).(io.ReadCloser), args.Error(2)
+}
+
+func (m *MockFileUseCase) TransformImage(ctx context.Context, filename string, opts usecase.TransformOptions) (*entity.Rendition, io.ReadCloser, error) {
+	args := m.Called(ctx, filename, opts)
+	return args.Get(0).(*entity.Rendition), args.Get(1).(io.ReadCloser), args.Error(2)
+}
+
+func (m *MockFileUseCase) Close() error {
+	args := m.Called()
+	return args.Error(0)
 }
 
 type mockUploadStream struct {
@@ -39,6 +143,7 @@ type mockUploadStream struct {
 	index        int
 	lastResponse *proto.UploadFileResponse
 	sendErr      error
+	recvErr      error
 }
 
 func (m *mockUploadStream) Context() context.Context {
@@ -50,6 +155,9 @@ func (m *mockUploadStream) Context() context.Context {
 
 func (m *mockUploadStream) Recv() (*proto.UploadFileRequest, error) {
 	if m.index >= len(m.reqs) {
+		if m.recvErr != nil {
+			return nil, m.recvErr
+		}
 		return nil, io.EOF
 	}
 	req := m.reqs[m.index]
@@ -72,7 +180,14 @@ func TestUploadFile_Success(t *testing.T) {
 		CreatedAt: time.Now(),
 	}
 
-	mockUC.On("UploadFile", mock.Anything, "test.txt", mock.Anything).Return(mockFile, nil)
+	var received []byte
+	mockUC.On("UploadFile", mock.Anything, "test.txt", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			data, err := io.ReadAll(args.Get(2).(io.Reader))
+			require.NoError(t, err)
+			received = data
+		}).
+		Return(mockFile, nil)
 
 	mockStream := &mockUploadStream{
 		reqs: []*proto.UploadFileRequest{
@@ -94,32 +209,220 @@ func TestUploadFile_Success(t *testing.T) {
 	require.Equal(t, "test.txt", mockStream.lastResponse.Filename)
 	require.Equal(t, uint64(4), mockStream.lastResponse.Size)
 
-	mockUC.AssertCalled(t, "UploadFile", mock.Anything, "test.txt", mock.AnythingOfType("*bytes.Buffer"))
+	mockUC.AssertCalled(t, "UploadFile", mock.Anything, "test.txt", mock.AnythingOfType("*grpc.uploadReader"), usecase.UploadOptions{})
+	require.Equal(t, []byte("data"), received)
+
+	mockUC.AssertExpectations(t)
+}
+
+func TestUploadFile_ClientDisconnect(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+
+	mockUC.On("UploadFile", mock.Anything, "test.txt", mock.Anything, mock.Anything).
+		Return((*entity.File)(nil), errors.New("write failed")).
+		Run(func(args mock.Arguments) {
+			_, err := io.ReadAll(args.Get(2).(io.Reader))
+			require.Error(t, err)
+		})
+
+	mockStream := &mockUploadStream{
+		reqs: []*proto.UploadFileRequest{
+			{
+				Data: &proto.UploadFileRequest_Metadata{
+					Metadata: &proto.FileMetadata{Filename: "test.txt"},
+				},
+			},
+			{
+				Data: &proto.UploadFileRequest_Chunk{Chunk: []byte("data")},
+			},
+		},
+		recvErr: status.Error(codes.Unavailable, "connection reset"),
+	}
+
+	err := server.UploadFile(mockStream)
+	require.Error(t, err)
+	require.Equal(t, codes.Unknown, status.Code(err))
+	require.Nil(t, mockStream.lastResponse)
+}
+
+func TestUploadFile_ChecksumMismatch(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+
+	mockUC.On("UploadFile", mock.Anything, "test.txt", mock.Anything, usecase.UploadOptions{Checksum: "deadbeef"}).
+		Return((*entity.File)(nil), fmt.Errorf("%w: expected deadbeef", repository.ErrChecksumMismatch))
+
+	mockStream := &mockUploadStream{
+		reqs: []*proto.UploadFileRequest{
+			{
+				Data: &proto.UploadFileRequest_Metadata{
+					Metadata: &proto.FileMetadata{Filename: "test.txt", Sha256: "deadbeef"},
+				},
+			},
+		},
+	}
+
+	err := server.UploadFile(mockStream)
+	require.Equal(t, codes.DataLoss, status.Code(err))
+	mockUC.AssertExpectations(t)
+}
+
+func TestUploadFile_ConflictPolicy(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+
+	mockUC.On("UploadFile", mock.Anything, "photo.jpg", mock.Anything, usecase.UploadOptions{Conflict: repository.ConflictRename}).
+		Return(&entity.File{Name: "photo (1).jpg", Size: 4}, nil)
+	mockUC.On("UploadFile", mock.Anything, "photo.jpg", mock.Anything, usecase.UploadOptions{Conflict: repository.ConflictFail}).
+		Return((*entity.File)(nil), &os.LinkError{Op: "link", Err: os.ErrExist})
+
+	upload := func(policy proto.ConflictPolicy) (*mockUploadStream, error) {
+		stream := &mockUploadStream{
+			reqs: []*proto.UploadFileRequest{{
+				Data: &proto.UploadFileRequest_Metadata{
+					Metadata: &proto.FileMetadata{Filename: "photo.jpg", ConflictPolicy: policy},
+				},
+			}},
+		}
+		return stream, server.UploadFile(stream)
+	}
+
+	stream, err := upload(proto.ConflictPolicy_CONFLICT_POLICY_RENAME)
+	require.NoError(t, err)
+	require.Equal(t, "photo (1).jpg", stream.lastResponse.Filename)
+
+	_, err = upload(proto.ConflictPolicy_CONFLICT_POLICY_FAIL_IF_EXISTS)
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = upload(proto.ConflictPolicy(42))
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	mockUC.AssertExpectations(t)
+}
+
+func TestUploadFile_Precondition(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+
+	cond := repository.Precondition{IfMatch: "abc"}
+	mockUC.On("UploadFile", mock.Anything, "shared.txt", mock.Anything, usecase.UploadOptions{Precondition: cond}).
+		Return((*entity.File)(nil), fmt.Errorf("%w: etag is def", repository.ErrPreconditionFailed))
+
+	mockStream := &mockUploadStream{
+		reqs: []*proto.UploadFileRequest{{
+			Data: &proto.UploadFileRequest_Metadata{
+				Metadata: &proto.FileMetadata{Filename: "shared.txt", IfMatch: "abc"},
+			},
+		}},
+	}
+
+	err := server.UploadFile(mockStream)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	mockUC.AssertExpectations(t)
+}
+
+func TestUploadFile_StripMetadata(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+
+	mockUC.On("UploadFile", mock.Anything, "photo.jpg", mock.Anything, usecase.UploadOptions{Strip: imaging.StripAll}).
+		Return(&entity.File{Name: "photo.jpg", StrippedMetadata: []string{imaging.StrippedEXIF, imaging.StrippedXMP}}, nil)
+	mockUC.On("UploadFile", mock.Anything, "broken.jpg", mock.Anything, usecase.UploadOptions{}).
+		Return((*entity.File)(nil), fmt.Errorf("write failed: %w: unexpected end of data", imaging.ErrMalformedImage))
 
-	args := mockUC.Calls[0].Arguments
-	r := args.Get(2).(io.Reader)
-	data, err := io.ReadAll(r)
+	upload := func(filename string, policy proto.StripMetadata) (*mockUploadStream, error) {
+		stream := &mockUploadStream{
+			reqs: []*proto.UploadFileRequest{{
+				Data: &proto.UploadFileRequest_Metadata{
+					Metadata: &proto.FileMetadata{Filename: filename, StripMetadata: policy},
+				},
+			}},
+		}
+		return stream, server.UploadFile(stream)
+	}
+
+	stream, err := upload("photo.jpg", proto.StripMetadata_STRIP_METADATA_ALL)
 	require.NoError(t, err)
-	require.Equal(t, []byte("data"), data)
+	require.Equal(t, []string{"exif", "xmp"}, stream.lastResponse.StrippedMetadata)
+
+	_, err = upload("broken.jpg", proto.StripMetadata_STRIP_METADATA_DEFAULT)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
 
+	_, err = upload("photo.jpg", proto.StripMetadata(42))
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
 	mockUC.AssertExpectations(t)
 }
+
+func TestUploadFile_ContentNotAllowed(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+
+	mockUC.On("UploadFile", mock.Anything, "cat.jpg", mock.Anything, usecase.UploadOptions{}).
+		Return((*entity.File)(nil), fmt.Errorf("%w: application/octet-stream", usecase.ErrContentNotAllowed))
+
+	mockStream := &mockUploadStream{
+		reqs: []*proto.UploadFileRequest{{
+			Data: &proto.UploadFileRequest_Metadata{
+				Metadata: &proto.FileMetadata{Filename: "cat.jpg"},
+			},
+		}},
+	}
+
+	err := server.UploadFile(mockStream)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Nil(t, mockStream.lastResponse)
+	mockUC.AssertExpectations(t)
+}
+
 func TestDownloadFile_Success(t *testing.T) {
 	mockUC := new(MockFileUseCase)
 	server := NewFileServiceServer(mockUC)
 
+	takenAt := time.Date(2024, 5, 1, 10, 20, 30, 0, time.UTC)
 	mockFile := &entity.File{
 		Name:      "test.txt",
 		Size:      4,
 		CreatedAt: time.Now(),
+		Image:     &entity.ImageInfo{Width: 2, Height: 1, Format: "png", TakenAt: takenAt, Orientation: 6, CameraModel: "Camera X"},
 	}
 	mockReader := io.NopCloser(strings.NewReader("data"))
-	mockUC.On("DownloadFile", mock.Anything, "test.txt").Return(mockFile, mockReader, nil)
+	mockUC.On("DownloadFile", mock.Anything, "test.txt", usecase.DownloadOptions{}).Return(mockFile, mockReader, nil)
 
 	mockStream := &mockDownloadStream{}
 	err := server.DownloadFile(&proto.DownloadFileRequest{Filename: "test.txt"}, mockStream)
 	require.NoError(t, err)
 	require.Len(t, mockStream.responses, 2)
+
+	image := mockStream.responses[0].GetMetadata().GetImage()
+	require.Equal(t, uint32(6), image.GetOrientation())
+	require.Equal(t, "Camera X", image.GetCameraModel())
+	require.True(t, takenAt.Equal(image.GetTakenAt().AsTime()))
+	mockUC.AssertExpectations(t)
+}
+
+func TestDownloadFile_Range(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+
+	mockFile := &entity.File{Name: "test.txt", Size: 10, CreatedAt: time.Now()}
+	mockUC.On("DownloadFile", mock.Anything, "test.txt", usecase.DownloadOptions{Offset: 8, Length: 5}).
+		Return(mockFile, io.NopCloser(strings.NewReader("89")), nil)
+	mockUC.On("DownloadFile", mock.Anything, "test.txt", usecase.DownloadOptions{Offset: 20}).
+		Return((*entity.File)(nil), io.NopCloser(nil), usecase.ErrInvalidRange)
+
+	mockStream := &mockDownloadStream{}
+	err := server.DownloadFile(&proto.DownloadFileRequest{Filename: "test.txt", Offset: 8, Length: 5}, mockStream)
+	require.NoError(t, err)
+	require.Len(t, mockStream.responses, 2)
+
+	metadata := mockStream.responses[0].GetMetadata()
+	require.Equal(t, uint64(10), metadata.GetSize())
+	require.Equal(t, uint64(8), metadata.GetOffset())
+	require.Equal(t, uint64(2), metadata.GetLength())
+	require.Equal(t, []byte("89"), mockStream.responses[1].GetChunk())
+
+	err = server.DownloadFile(&proto.DownloadFileRequest{Filename: "test.txt", Offset: 20}, &mockDownloadStream{})
+	require.Equal(t, codes.OutOfRange, status.Code(err))
 	mockUC.AssertExpectations(t)
 }
 
@@ -147,12 +450,352 @@ func TestListFiles_Success(t *testing.T) {
 
 	mockFiles := []*entity.File{
 		{Name: "file1.txt", CreatedAt: time.Now(), UpdatedAt: time.Now()},
-		{Name: "file2.txt", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{Name: "file2.txt", CreatedAt: time.Now(), UpdatedAt: time.Now(), Image: &entity.ImageInfo{Width: 640, Height: 480, Format: "jpeg"}},
 	}
-	mockUC.On("ListFiles", mock.Anything).Return(mockFiles, nil)
+	expectedOpts := repository.ListOptions{
+		FileFilter:    repository.FileFilter{NamePattern: "*.txt"},
+		PageSize:      2,
+		SortBy:        repository.SortByName,
+		SortAscending: true,
+	}
+	mockUC.On("ListFiles", mock.Anything, expectedOpts).
+		Return(&repository.ListResult{Files: mockFiles, NextPageToken: "next", LogicalBytes: 300, PhysicalBytes: 200}, nil)
 
-	resp, err := server.ListFiles(context.Background(), &proto.ListFilesRequest{})
+	resp, err := server.ListFiles(context.Background(), &proto.ListFilesRequest{
+		PageSize:      2,
+		NamePattern:   "*.txt",
+		SortBy:        proto.SortField_SORT_FIELD_NAME,
+		SortDirection: proto.SortDirection_SORT_DIRECTION_ASC,
+	})
 	require.NoError(t, err)
 	require.Len(t, resp.Files, 2)
+	require.Nil(t, resp.Files[0].Image)
+	require.Equal(t, uint32(640), resp.Files[1].GetImage().GetWidth())
+	require.Equal(t, "jpeg", resp.Files[1].GetImage().GetFormat())
+	require.Nil(t, resp.Files[1].GetImage().GetTakenAt(), "no EXIF capture time")
+	require.Equal(t, "next", resp.NextPageToken)
+	require.Equal(t, uint64(300), resp.LogicalBytes)
+	require.Equal(t, uint64(200), resp.PhysicalBytes)
+	mockUC.AssertExpectations(t)
+}
+
+func TestDeleteFile(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+
+	mockUC.On("DeleteFile", mock.Anything, "test.txt", repository.Precondition{}).Return(&entity.TrashItem{ID: "trash-id"}, nil)
+	mockUC.On("DeleteFile", mock.Anything, "missing.txt", repository.Precondition{}).Return((*entity.TrashItem)(nil), os.ErrNotExist)
+	mockUC.On("DeleteFile", mock.Anything, "../test.txt", repository.Precondition{}).Return((*entity.TrashItem)(nil), usecase.ErrInvalidFilename)
+	mockUC.On("DeleteFile", mock.Anything, "shared.txt", repository.Precondition{IfMatch: "abc"}).
+		Return((*entity.TrashItem)(nil), fmt.Errorf("%w: etag is def", repository.ErrPreconditionFailed))
+
+	resp, err := server.DeleteFile(context.Background(), &proto.DeleteFileRequest{Filename: "test.txt"})
+	require.NoError(t, err)
+	require.Equal(t, "trash-id", resp.TrashId)
+
+	_, err = server.DeleteFile(context.Background(), &proto.DeleteFileRequest{Filename: "missing.txt"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = server.DeleteFile(context.Background(), &proto.DeleteFileRequest{Filename: "../test.txt"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = server.DeleteFile(context.Background(), &proto.DeleteFileRequest{Filename: "shared.txt", IfMatch: "abc"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	mockUC.AssertExpectations(t)
+}
+
+func TestUploadSessionChunk_InvalidOffset(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+
+	mockUC.On("WriteUploadSession", mock.Anything, "session", int64(10), mock.Anything).
+		Return((*entity.UploadSession)(nil), fmt.Errorf("%w: got 10, committed 4", repository.ErrInvalidOffset))
+
+	_, err := server.UploadSessionChunk(context.Background(), &proto.UploadSessionChunkRequest{
+		SessionId: "session",
+		Offset:    10,
+		Chunk:     []byte("data"),
+	})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	mockUC.AssertExpectations(t)
+}
+
+type mockStreamFilesStream struct {
+	proto.FileService_StreamFilesServer
+	responses []*proto.StreamFilesResponse
+}
+
+func (m *mockStreamFilesStream) Context() context.Context {
+	return context.Background()
+}
+
+func (m *mockStreamFilesStream) Send(resp *proto.StreamFilesResponse) error {
+	m.responses = append(m.responses, resp)
+	return nil
+}
+
+func TestStreamFiles(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+
+	mockUC.On("StreamFiles", mock.Anything, repository.FileFilter{NamePrefix: "img"}, 2, mock.Anything).
+		Run(func(args mock.Arguments) {
+			fn := args.Get(3).(func([]*entity.File) error)
+			require.NoError(t, fn([]*entity.File{{Name: "img1"}, {Name: "img2"}}))
+			require.NoError(t, fn([]*entity.File{{Name: "img3"}}))
+		}).
+		Return(nil)
+
+	mockStream := &mockStreamFilesStream{}
+	err := server.StreamFiles(&proto.StreamFilesRequest{BatchSize: 2, NamePrefix: "img"}, mockStream)
+	require.NoError(t, err)
+	require.Len(t, mockStream.responses, 2)
+	require.Len(t, mockStream.responses[0].Files, 2)
+	require.Equal(t, "img3", mockStream.responses[1].Files[0].Filename)
 	mockUC.AssertExpectations(t)
 }
+
+func TestRenameFile(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+
+	mockUC.On("RenameFile", mock.Anything, "a.jpg", "photos/a.jpg", false).
+		Return(&entity.File{Name: "photos/a.jpg", Size: 4}, nil)
+	mockUC.On("RenameFile", mock.Anything, "missing.jpg", "photos/missing.jpg", false).
+		Return((*entity.File)(nil), os.ErrNotExist)
+	mockUC.On("RenameFile", mock.Anything, "b.jpg", "photos/a.jpg", false).
+		Return((*entity.File)(nil), &os.LinkError{Op: "link", Old: "b.jpg", New: "photos/a.jpg", Err: os.ErrExist})
+
+	resp, err := server.RenameFile(context.Background(), &proto.RenameFileRequest{Source: "a.jpg", Destination: "photos/a.jpg"})
+	require.NoError(t, err)
+	require.Equal(t, "photos/a.jpg", resp.File.Filename)
+
+	_, err = server.RenameFile(context.Background(), &proto.RenameFileRequest{Source: "missing.jpg", Destination: "photos/missing.jpg"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = server.RenameFile(context.Background(), &proto.RenameFileRequest{Source: "b.jpg", Destination: "photos/a.jpg"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+	mockUC.AssertExpectations(t)
+}
+
+func TestCopyFile(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+
+	mockUC.On("CopyFile", mock.Anything, "a.jpg", "b.jpg", true).
+		Return(&entity.File{Name: "b.jpg", Size: 4, Checksum: "abc"}, nil)
+	mockUC.On("CopyFile", mock.Anything, "a.jpg", "c.jpg", false).
+		Return((*entity.File)(nil), &os.LinkError{Op: "link", Old: "a.jpg", New: "c.jpg", Err: os.ErrExist})
+
+	resp, err := server.CopyFile(context.Background(), &proto.CopyFileRequest{Source: "a.jpg", Destination: "b.jpg", Overwrite: true})
+	require.NoError(t, err)
+	require.Equal(t, "b.jpg", resp.File.Filename)
+	require.Equal(t, "abc", resp.File.Sha256)
+
+	_, err = server.CopyFile(context.Background(), &proto.CopyFileRequest{Source: "a.jpg", Destination: "c.jpg"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+	mockUC.AssertExpectations(t)
+}
+
+func TestFileVersions(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+	ctx := context.Background()
+
+	archivedAt := time.Now()
+	mockUC.On("ListFileVersions", mock.Anything, "a.jpg").Return([]*entity.FileVersion{
+		{Filename: "a.jpg", Number: 1, Size: 3, Checksum: "abc", ArchivedAt: archivedAt},
+	}, nil)
+	mockUC.On("RestoreFileVersion", mock.Anything, "a.jpg", int64(7)).
+		Return((*entity.File)(nil), &os.PathError{Op: "open", Path: "7.json", Err: os.ErrNotExist})
+	mockUC.On("PruneFileVersions", mock.Anything, "a.jpg", 2).Return(3, nil)
+	mockUC.On("DownloadFile", mock.Anything, "a.jpg", usecase.DownloadOptions{Version: 1}).
+		Return(&entity.File{Name: "a.jpg", Size: 3}, io.NopCloser(strings.NewReader("old")), nil)
+
+	list, err := server.ListFileVersions(ctx, &proto.ListFileVersionsRequest{Filename: "a.jpg"})
+	require.NoError(t, err)
+	require.Len(t, list.Versions, 1)
+	require.Equal(t, uint64(1), list.Versions[0].Version)
+	require.Equal(t, "abc", list.Versions[0].Sha256)
+
+	_, err = server.RestoreFileVersion(ctx, &proto.RestoreFileVersionRequest{Filename: "a.jpg", Version: 7})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	pruned, err := server.PruneFileVersions(ctx, &proto.PruneFileVersionsRequest{Filename: "a.jpg", Keep: 2})
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), pruned.Removed)
+
+	stream := &mockDownloadStream{}
+	require.NoError(t, server.DownloadFile(&proto.DownloadFileRequest{Filename: "a.jpg", Version: 1}, stream))
+	require.Equal(t, uint64(1), stream.responses[0].GetMetadata().GetVersion())
+	require.Equal(t, []byte("old"), stream.responses[1].GetChunk())
+	mockUC.AssertExpectations(t)
+}
+
+func TestTrash(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+	ctx := context.Background()
+
+	mockUC.On("ListTrash", mock.Anything).Return([]*entity.TrashItem{
+		{ID: "1", Filename: "a.jpg", Size: 3, DeletedAt: time.Now()},
+	}, nil)
+	mockUC.On("RestoreFile", mock.Anything, "1", "").Return(&entity.File{Name: "a.jpg"}, nil)
+	mockUC.On("RestoreFile", mock.Anything, "2", "").
+		Return((*entity.File)(nil), &os.LinkError{Op: "link", Err: os.ErrExist})
+	mockUC.On("RestoreFile", mock.Anything, "3", "").
+		Return((*entity.File)(nil), &os.PathError{Op: "get trash item", Path: "3", Err: os.ErrNotExist})
+	mockUC.On("EmptyTrash", mock.Anything).Return(5, nil)
+
+	list, err := server.ListTrash(ctx, &proto.ListTrashRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	require.Equal(t, "a.jpg", list.Items[0].Filename)
+
+	restored, err := server.RestoreFile(ctx, &proto.RestoreFileRequest{TrashId: "1"})
+	require.NoError(t, err)
+	require.Equal(t, "a.jpg", restored.File.Filename)
+
+	_, err = server.RestoreFile(ctx, &proto.RestoreFileRequest{TrashId: "2"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = server.RestoreFile(ctx, &proto.RestoreFileRequest{TrashId: "3"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	emptied, err := server.EmptyTrash(ctx, &proto.EmptyTrashRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uint32(5), emptied.Removed)
+	mockUC.AssertExpectations(t)
+}
+
+func TestDownloadFile_NotModified(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	file := &entity.File{Name: "a.jpg", Size: 4, Checksum: "abc", UpdatedAt: since}
+	mockUC.On("DownloadFile", mock.Anything, "a.jpg", usecase.DownloadOptions{IfNoneMatch: "abc"}).
+		Return(file, io.NopCloser(nil), usecase.ErrNotModified)
+	mockUC.On("DownloadFile", mock.Anything, "a.jpg", usecase.DownloadOptions{IfModifiedSince: since}).
+		Return(file, io.NopCloser(nil), usecase.ErrNotModified)
+
+	for _, req := range []*proto.DownloadFileRequest{
+		{Filename: "a.jpg", IfNoneMatch: "abc"},
+		{Filename: "a.jpg", IfModifiedSince: timestamppb.New(since)},
+	} {
+		stream := &mockDownloadStream{}
+		require.NoError(t, server.DownloadFile(req, stream))
+		require.Len(t, stream.responses, 1)
+
+		metadata := stream.responses[0].GetMetadata()
+		require.True(t, metadata.GetNotModified())
+		require.Equal(t, "abc", metadata.GetEtag())
+		require.Equal(t, uint64(4), metadata.GetSize())
+	}
+	mockUC.AssertExpectations(t)
+}
+
+func TestGetThumbnail(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+
+	thumbnail := &entity.Thumbnail{Filename: "photo.jpg", MaxSize: 128, ContentType: "image/jpeg", Size: 5, Checksum: "abc"}
+	mockUC.On("GetThumbnail", mock.Anything, "photo.jpg", 128).
+		Return(thumbnail, io.NopCloser(strings.NewReader("thumb")), nil)
+	mockUC.On("GetThumbnail", mock.Anything, "photo.jpg", 100).
+		Return((*entity.Thumbnail)(nil), io.NopCloser(nil), usecase.ErrInvalidThumbnailSize)
+	mockUC.On("GetThumbnail", mock.Anything, "notes.txt", 128).
+		Return((*entity.Thumbnail)(nil), io.NopCloser(nil), usecase.ErrNotImage)
+	mockUC.On("GetThumbnail", mock.Anything, "missing.jpg", 128).
+		Return((*entity.Thumbnail)(nil), io.NopCloser(nil), os.ErrNotExist)
+
+	mockStream := &mockThumbnailStream{}
+	err := server.GetThumbnail(&proto.GetThumbnailRequest{Filename: "photo.jpg", Size: 128}, mockStream)
+	require.NoError(t, err)
+	require.Len(t, mockStream.responses, 2)
+
+	metadata := mockStream.responses[0].GetMetadata()
+	require.Equal(t, "image/jpeg", metadata.GetContentType())
+	require.Equal(t, uint32(128), metadata.GetSize())
+	require.Equal(t, uint64(5), metadata.GetLength())
+	require.Equal(t, "abc", metadata.GetEtag())
+	require.Equal(t, []byte("thumb"), mockStream.responses[1].GetChunk())
+
+	err = server.GetThumbnail(&proto.GetThumbnailRequest{Filename: "photo.jpg", Size: 100}, &mockThumbnailStream{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	err = server.GetThumbnail(&proto.GetThumbnailRequest{Filename: "notes.txt", Size: 128}, &mockThumbnailStream{})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	err = server.GetThumbnail(&proto.GetThumbnailRequest{Filename: "missing.jpg", Size: 128}, &mockThumbnailStream{})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	mockUC.AssertExpectations(t)
+}
+
+type mockThumbnailStream struct {
+	proto.FileService_GetThumbnailServer
+	responses []*proto.GetThumbnailResponse
+}
+
+func (m *mockThumbnailStream) Context() context.Context {
+	return context.Background()
+}
+
+func (m *mockThumbnailStream) Send(resp *proto.GetThumbnailResponse) error {
+	m.responses = append(m.responses, resp)
+	return nil
+}
+
+func TestTransformImage(t *testing.T) {
+	mockUC := new(MockFileUseCase)
+	server := NewFileServiceServer(mockUC)
+
+	rendition := &entity.Rendition{Filename: "photo.png", Width: 100, Height: 50, ContentType: "image/jpeg", Size: 4, Checksum: "abc"}
+	mockUC.On("TransformImage", mock.Anything, "photo.png",
+		usecase.TransformOptions{Width: 100, AspectWidth: 2, AspectHeight: 1, Format: "jpeg", Quality: 80}).
+		Return(rendition, io.NopCloser(strings.NewReader("jpeg")), nil)
+	mockUC.On("TransformImage", mock.Anything, "photo.png", usecase.TransformOptions{Width: 10000}).
+		Return((*entity.Rendition)(nil), io.NopCloser(nil), usecase.ErrInvalidTransform)
+	mockUC.On("TransformImage", mock.Anything, "bomb.png", usecase.TransformOptions{}).
+		Return((*entity.Rendition)(nil), io.NopCloser(nil), imaging.ErrTooManyPixels)
+
+	mockStream := &mockTransformStream{}
+	err := server.TransformImage(&proto.TransformImageRequest{
+		Filename:     "photo.png",
+		Width:        100,
+		AspectWidth:  2,
+		AspectHeight: 1,
+		Format:       proto.ImageFormat_IMAGE_FORMAT_JPEG,
+		Quality:      80,
+	}, mockStream)
+	require.NoError(t, err)
+	require.Len(t, mockStream.responses, 2)
+
+	metadata := mockStream.responses[0].GetMetadata()
+	require.Equal(t, uint32(100), metadata.GetWidth())
+	require.Equal(t, uint32(50), metadata.GetHeight())
+	require.Equal(t, "image/jpeg", metadata.GetContentType())
+	require.Equal(t, uint64(4), metadata.GetLength())
+	require.Equal(t, []byte("jpeg"), mockStream.responses[1].GetChunk())
+
+	err = server.TransformImage(&proto.TransformImageRequest{Filename: "photo.png", Width: 10000}, &mockTransformStream{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	err = server.TransformImage(&proto.TransformImageRequest{Filename: "bomb.png"}, &mockTransformStream{})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	err = server.TransformImage(&proto.TransformImageRequest{Filename: "photo.png", Format: 42}, &mockTransformStream{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	mockUC.AssertExpectations(t)
+}
+
+type mockTransformStream struct {
+	proto.FileService_TransformImageServer
+	responses []*proto.TransformImageResponse
+}
+
+func (m *mockTransformStream) Context() context.Context {
+	return context.Background()
+}
+
+func (m *mockTransformStream) Send(resp *proto.TransformImageResponse) error {
+	m.responses = append(m.responses, resp)
+	return nil
+}
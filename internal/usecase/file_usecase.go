@@ -2,63 +2,453 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	"github.com/keenoobi/grpc-file-manager/internal/imaging"
 	"github.com/keenoobi/grpc-file-manager/internal/repository"
 )
 
 type FileUseCase interface {
-	UploadFile(ctx context.Context, filename string, data io.Reader) (*entity.File, error)
-	DownloadFile(ctx context.Context, filename string) (*entity.File, io.ReadCloser, error)
-	ListFiles(ctx context.Context) ([]*entity.File, error)
+	UploadFile(ctx context.Context, filename string, data io.Reader, opts UploadOptions) (*entity.File, error)
+	DownloadFile(ctx context.Context, filename string, opts DownloadOptions) (*entity.File, io.ReadCloser, error)
+	ListFiles(ctx context.Context, opts repository.ListOptions) (*repository.ListResult, error)
+	StreamFiles(ctx context.Context, filter repository.FileFilter, batchSize int, fn func([]*entity.File) error) error
+	DeleteFile(ctx context.Context, filename string, cond repository.Precondition) (*entity.TrashItem, error)
+	RenameFile(ctx context.Context, from, to string, overwrite bool) (*entity.File, error)
+	CopyFile(ctx context.Context, from, to string, overwrite bool) (*entity.File, error)
+
+	ListFileVersions(ctx context.Context, filename string) ([]*entity.FileVersion, error)
+	RestoreFileVersion(ctx context.Context, filename string, version int64) (*entity.File, error)
+	PruneFileVersions(ctx context.Context, filename string, keep int) (int, error)
+
+	ListTrash(ctx context.Context) ([]*entity.TrashItem, error)
+	RestoreFile(ctx context.Context, trashID, destination string) (*entity.File, error)
+	EmptyTrash(ctx context.Context) (int, error)
+	// PurgeTrash удаляет из корзины файлы, удаленные раньше, чем olderThan назад
+	PurgeTrash(ctx context.Context, olderThan time.Duration) (int, error)
+
+	CreateUploadSession(ctx context.Context, filename string, size int64, checksum string) (*entity.UploadSession, error)
+	WriteUploadSession(ctx context.Context, id string, offset int64, data io.Reader) (*entity.UploadSession, error)
+	GetUploadSession(ctx context.Context, id string) (*entity.UploadSession, error)
+	FinalizeUploadSession(ctx context.Context, id string) (*entity.File, error)
+	// PurgeUploadSessions удаляет сессии, в которые не писали дольше olderThan
+	PurgeUploadSessions(ctx context.Context, olderThan time.Duration) (int, error)
+
+	// GetThumbnail отдает превью изображения с ограничением большей стороны size
+	GetThumbnail(ctx context.Context, filename string, size int) (*entity.Thumbnail, io.ReadCloser, error)
+	// TransformImage обрезает, уменьшает и перекодирует изображение
+	TransformImage(ctx context.Context, filename string, opts TransformOptions) (*entity.Rendition, io.ReadCloser, error)
+
+	// Close останавливает фоновые задачи и ждет их завершения. Вызывается
+	// перед закрытием хранилища
+	Close() error
+}
+
+type UploadOptions struct {
+	// Ожидаемый SHA-256 содержимого в hex, пустая строка - без проверки
+	Checksum     string
+	Conflict     repository.ConflictPolicy
+	Precondition repository.Precondition
+
+	// Метаданные, заявленные клиентом
+	Size        int64
+	CreatedAt   time.Time
+	Uploader    string
+	ContentType string
+
+	// Какие метаданные удалить из JPEG и PNG. Действует политика строже из
+	// этой и заданной в WithStripPolicy
+	Strip imaging.StripPolicy
 }
 
+type DownloadOptions struct {
+	// Диапазон байт: нулевая длина означает "до конца файла"
+	Offset int64
+	Length int64
+
+	// Номер сохраненной версии, 0 - текущее содержимое
+	Version int64
+
+	// Условия для проверки копии клиента. Если копия актуальна, DownloadFile
+	// возвращает файл и ErrNotModified без открытого содержимого
+	IfNoneMatch     string
+	IfModifiedSince time.Time
+}
+
+var (
+	ErrInvalidFilename  = errors.New("invalid filename")
+	ErrUploadIncomplete = errors.New("upload is incomplete")
+	ErrInvalidRange     = errors.New("invalid range")
+	ErrNotModified      = errors.New("not modified")
+)
+
 type fileUseCase struct {
-	repo repository.FileRepository
+	repo       repository.FileRepository
+	sessions   repository.UploadSessionRepository
+	thumbnails *thumbnailer // nil - превью отключены
+	transforms *transformer // nil - преобразования отключены
+	strip      imaging.StripPolicy
+	content    ContentPolicy
 }
 
-func NewFileUseCase(repo repository.FileRepository) FileUseCase {
-	return &fileUseCase{repo: repo}
+// Option включает необязательные возможности FileUseCase
+type Option func(*fileUseCase)
+
+func NewFileUseCase(repo repository.FileRepository, sessions repository.UploadSessionRepository, opts ...Option) FileUseCase {
+	uc := &fileUseCase{repo: repo, sessions: sessions}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
 }
 
-func (uc *fileUseCase) UploadFile(ctx context.Context, filename string, data io.Reader) (*entity.File, error) {
+func (uc *fileUseCase) UploadFile(ctx context.Context, filename string, data io.Reader, opts UploadOptions) (*entity.File, error) {
 	if !isValidFilename(filename) {
-		return nil, fmt.Errorf("invalid filename")
+		return nil, ErrInvalidFilename
+	}
+	data, err := uc.checkContent(data)
+	if err != nil {
+		return nil, err
 	}
 
+	now := time.Now()
 	file := &entity.File{
-		Name:      filename,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		Name:         filename,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		DeclaredSize: opts.Size,
+		Uploader:     opts.Uploader,
+		ContentType:  opts.ContentType,
+	}
+	if !opts.CreatedAt.IsZero() {
+		file.CreatedAt = opts.CreatedAt
+	}
+	if file.ContentType == "" {
+		file.ContentType = contentTypeByName(filename)
+	}
+
+	saveOpts := repository.SaveOptions{
+		Checksum:     opts.Checksum,
+		Conflict:     opts.Conflict,
+		Precondition: opts.Precondition,
+	}
+	previous := uc.checksumOf(ctx, filename)
+	if err := uc.save(ctx, file, data, saveOpts, max(uc.strip, opts.Strip)); err != nil {
+		return nil, err
+	}
+	// При ConflictRename файл сохранен под другим именем, и прежний остался на месте
+	if file.Name != filename {
+		previous = ""
+	}
+	uc.contentChanged(ctx, previous, file)
+
+	return file, nil
+}
+
+// save сохраняет загруженные данные, по пути удаляя метаданные по политике и
+// извлекая свойства изображения
+func (uc *fileUseCase) save(ctx context.Context, file *entity.File, data io.Reader, opts repository.SaveOptions, policy imaging.StripPolicy) error {
+	if policy == imaging.StripNone {
+		return uc.repo.Save(ctx, file, newImageProbe(file, data), opts)
+	}
+
+	stripper := newMetadataStripper(data, policy, opts.Checksum)
+	opts.Checksum = ""
+	err := uc.repo.Save(ctx, file, newImageProbe(file, stripper), opts)
+	file.StrippedMetadata = stripper.finish()
+	return err
+}
+
+// DownloadFile отдает opts.Length байт файла начиная с opts.Offset. Нулевая
+// длина означает "до конца файла", длина за пределами файла обрезается.
+func (uc *fileUseCase) DownloadFile(ctx context.Context, filename string, opts DownloadOptions) (*entity.File, io.ReadCloser, error) {
+	if !isValidFilename(filename) {
+		return nil, nil, ErrInvalidFilename
+	}
+
+	// Для ответа "не изменился" содержимое не открывается
+	conditional := opts.IfNoneMatch != "" || !opts.IfModifiedSince.IsZero()
+	if conditional && opts.Version == 0 {
+		file, err := uc.repo.Stat(ctx, filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !isModified(file, opts) {
+			return file, nil, ErrNotModified
+		}
+	}
+
+	file, reader, err := uc.open(ctx, filename, opts.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isModified(file, opts) {
+		reader.Close()
+		return file, nil, ErrNotModified
+	}
+
+	offset, length := opts.Offset, opts.Length
+	if offset < 0 || length < 0 || offset > file.Size {
+		reader.Close()
+		return nil, nil, fmt.Errorf("%w: offset %d, size %d", ErrInvalidRange, offset, file.Size)
+	}
+
+	// Пропущенный префикс не читается с диска
+	if offset > 0 {
+		if _, err := reader.Seek(offset, io.SeekStart); err != nil {
+			reader.Close()
+			return nil, nil, fmt.Errorf("seek failed: %w", err)
+		}
+	}
+
+	if length == 0 || length > file.Size-offset {
+		length = file.Size - offset
+	}
+
+	return file, &rangeReader{Reader: io.LimitReader(reader, length), Closer: reader}, nil
+}
+
+// isModified проверяет, отличается ли файл от копии клиента. Как и в HTTP,
+// при заданном ETag время изменения не учитывается
+func isModified(file *entity.File, opts DownloadOptions) bool {
+	if opts.IfNoneMatch != "" {
+		return opts.IfNoneMatch != "*" && opts.IfNoneMatch != file.ETag()
+	}
+	if !opts.IfModifiedSince.IsZero() {
+		return file.UpdatedAt.After(opts.IfModifiedSince)
+	}
+	return true
+}
+
+// open открывает текущее содержимое файла или одну из его версий
+func (uc *fileUseCase) open(ctx context.Context, filename string, version int64) (*entity.File, io.ReadSeekCloser, error) {
+	if version == 0 {
+		return uc.repo.Get(ctx, filename)
+	}
+
+	v, reader, err := uc.repo.GetVersion(ctx, filename, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &entity.File{
+		Name:        v.Filename,
+		Size:        v.Size,
+		CreatedAt:   v.UpdatedAt,
+		UpdatedAt:   v.UpdatedAt,
+		Path:        v.Path,
+		Checksum:    v.Checksum,
+		Uploader:    v.Uploader,
+		ContentType: v.ContentType,
+		Image:       v.Image,
+	}, reader, nil
+}
+
+type rangeReader struct {
+	io.Reader
+	io.Closer
+}
+
+func (uc *fileUseCase) ListFiles(ctx context.Context, opts repository.ListOptions) (*repository.ListResult, error) {
+	if !isValidFolder(opts.Folder) {
+		return nil, ErrInvalidFilename
+	}
+	return uc.repo.List(ctx, opts)
+}
+
+func (uc *fileUseCase) StreamFiles(ctx context.Context, filter repository.FileFilter, batchSize int, fn func([]*entity.File) error) error {
+	if !isValidFolder(filter.Folder) {
+		return ErrInvalidFilename
+	}
+	return uc.repo.Stream(ctx, filter, batchSize, fn)
+}
+
+func (uc *fileUseCase) DeleteFile(ctx context.Context, filename string, cond repository.Precondition) (*entity.TrashItem, error) {
+	if !isValidFilename(filename) {
+		return nil, ErrInvalidFilename
+	}
+
+	item, err := uc.repo.Delete(ctx, filename, cond)
+	if err != nil {
+		return nil, err
+	}
+	if uc.thumbnails != nil {
+		uc.thumbnails.remove(ctx, item.Checksum)
+	}
+	return item, nil
+}
+
+func (uc *fileUseCase) ListTrash(ctx context.Context) ([]*entity.TrashItem, error) {
+	return uc.repo.ListTrash(ctx)
+}
+
+// RestoreFile возвращает файл из корзины. Пустой destination - исходное имя
+func (uc *fileUseCase) RestoreFile(ctx context.Context, trashID, destination string) (*entity.File, error) {
+	if destination != "" && !isValidFilename(destination) {
+		return nil, ErrInvalidFilename
+	}
+
+	file, err := uc.repo.RestoreTrash(ctx, trashID, destination)
+	if err != nil {
+		return nil, err
+	}
+	uc.contentChanged(ctx, "", file)
+	return file, nil
+}
+
+func (uc *fileUseCase) EmptyTrash(ctx context.Context) (int, error) {
+	return uc.repo.PurgeTrash(ctx, time.Now())
+}
+
+func (uc *fileUseCase) PurgeTrash(ctx context.Context, olderThan time.Duration) (int, error) {
+	return uc.repo.PurgeTrash(ctx, time.Now().Add(-olderThan))
+}
+
+func (uc *fileUseCase) RenameFile(ctx context.Context, from, to string, overwrite bool) (*entity.File, error) {
+	if !isValidFilename(from) || !isValidFilename(to) {
+		return nil, ErrInvalidFilename
+	}
+
+	return uc.repo.Rename(ctx, from, to, overwrite)
+}
+
+func (uc *fileUseCase) CopyFile(ctx context.Context, from, to string, overwrite bool) (*entity.File, error) {
+	if !isValidFilename(from) || !isValidFilename(to) {
+		return nil, ErrInvalidFilename
+	}
+
+	return uc.repo.Copy(ctx, from, to, overwrite)
+}
+
+func (uc *fileUseCase) ListFileVersions(ctx context.Context, filename string) ([]*entity.FileVersion, error) {
+	if !isValidFilename(filename) {
+		return nil, ErrInvalidFilename
+	}
+
+	return uc.repo.ListVersions(ctx, filename)
+}
+
+func (uc *fileUseCase) RestoreFileVersion(ctx context.Context, filename string, version int64) (*entity.File, error) {
+	if !isValidFilename(filename) {
+		return nil, ErrInvalidFilename
 	}
 
-	if err := uc.repo.Save(ctx, file, data); err != nil {
+	previous := uc.checksumOf(ctx, filename)
+	file, err := uc.repo.RestoreVersion(ctx, filename, version)
+	if err != nil {
 		return nil, err
 	}
+	uc.contentChanged(ctx, previous, file)
+	return file, nil
+}
+
+func (uc *fileUseCase) PruneFileVersions(ctx context.Context, filename string, keep int) (int, error) {
+	if !isValidFilename(filename) {
+		return 0, ErrInvalidFilename
+	}
+
+	return uc.repo.PruneVersions(ctx, filename, keep)
+}
+
+func (uc *fileUseCase) CreateUploadSession(ctx context.Context, filename string, size int64, checksum string) (*entity.UploadSession, error) {
+	if !isValidFilename(filename) {
+		return nil, ErrInvalidFilename
+	}
+
+	session := &entity.UploadSession{
+		Filename:  filename,
+		Size:      size,
+		Checksum:  checksum,
+		CreatedAt: time.Now(),
+	}
+	if err := uc.sessions.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (uc *fileUseCase) WriteUploadSession(ctx context.Context, id string, offset int64, data io.Reader) (*entity.UploadSession, error) {
+	return uc.sessions.Write(ctx, id, offset, data)
+}
+
+func (uc *fileUseCase) GetUploadSession(ctx context.Context, id string) (*entity.UploadSession, error) {
+	return uc.sessions.Get(ctx, id)
+}
 
+// FinalizeUploadSession сохраняет файл под блокировкой сессии: чанки,
+// пришедшие во время сохранения, и повторное завершение ждут и затем
+// получают NotFound
+func (uc *fileUseCase) FinalizeUploadSession(ctx context.Context, id string) (*entity.File, error) {
+	var file *entity.File
+	err := uc.sessions.Commit(ctx, id, func(session *entity.UploadSession, data io.Reader) error {
+		if session.Size > 0 && session.CommittedSize != session.Size {
+			return fmt.Errorf("%w: committed %d of %d bytes", ErrUploadIncomplete, session.CommittedSize, session.Size)
+		}
+		content, err := uc.checkContent(data)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		file = &entity.File{
+			Name:         session.Filename,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+			DeclaredSize: session.Size,
+			ContentType:  contentTypeByName(session.Filename),
+		}
+		previous := uc.checksumOf(ctx, session.Filename)
+		if err := uc.save(ctx, file, content, repository.SaveOptions{Checksum: session.Checksum}, uc.strip); err != nil {
+			return err
+		}
+		uc.contentChanged(ctx, previous, file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	return file, nil
 }
 
-func (uc *fileUseCase) DownloadFile(ctx context.Context, filename string) (*entity.File, io.ReadCloser, error) {
-	return uc.repo.Get(ctx, filename)
+func (uc *fileUseCase) PurgeUploadSessions(ctx context.Context, olderThan time.Duration) (int, error) {
+	return uc.sessions.DeleteExpired(ctx, time.Now().Add(-olderThan))
 }
 
-func (uc *fileUseCase) ListFiles(ctx context.Context) ([]*entity.File, error) {
-	return uc.repo.List(ctx)
+func contentTypeByName(filename string) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(filename)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
 }
 
+const (
+	maxPathLength    = 1024
+	maxSegmentLength = 255
+)
+
+// isValidFilename проверяет путь файла вида "project/2024/photo.jpg"
 func isValidFilename(filename string) bool {
-	if filename == "" || len(filename) > 255 {
+	if filename == "" || len(filename) > maxPathLength {
 		return false
 	}
-	// Запрещаем: ../, ~/, /, \
-	if strings.Contains(filename, "..") || strings.ContainsAny(filename, `/\~`) {
+	// Запрещаем: ../, ~/, \
+	if strings.Contains(filename, "..") || strings.ContainsAny(filename, `\~`) {
 		return false
 	}
+	// Пустой сегмент означает абсолютный путь, двойной или замыкающий слеш.
+	// Сегменты с точки зарезервированы под служебные каталоги хранилища
+	for _, segment := range strings.Split(filename, "/") {
+		if segment == "" || len(segment) > maxSegmentLength || strings.HasPrefix(segment, ".") {
+			return false
+		}
+	}
 	// Проверяем на недопустимые символы (например, управляющие символы ASCII)
 	for _, r := range filename {
 		if r < 32 || r == 127 {
@@ -67,3 +457,8 @@ func isValidFilename(filename string) bool {
 	}
 	return true
 }
+
+// isValidFolder допускает пустую строку - корень хранилища
+func isValidFolder(folder string) bool {
+	return folder == "" || isValidFilename(folder)
+}
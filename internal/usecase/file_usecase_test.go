@@ -6,9 +6,13 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	"github.com/keenoobi/grpc-file-manager/internal/repository"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -17,37 +21,105 @@ type MockFileRepository struct {
 	mock.Mock
 }
 
-func (m *MockFileRepository) Save(ctx context.Context, file *entity.File, data io.Reader) error {
-	args := m.Called(ctx, file, data)
+func (m *MockFileRepository) Save(ctx context.Context, file *entity.File, data io.Reader, opts repository.SaveOptions) error {
+	args := m.Called(ctx, file, data, opts)
 	return args.Error(0)
 }
 
-func (m *MockFileRepository) Get(ctx context.Context, filename string) (*entity.File, io.ReadCloser, error) {
+func (m *MockFileRepository) Get(ctx context.Context, filename string) (*entity.File, io.ReadSeekCloser, error) {
 	args := m.Called(ctx, filename)
-	return args.Get(0).(*entity.File), args.Get(1).(io.ReadCloser), args.Error(2)
+	return args.Get(0).(*entity.File), args.Get(1).(io.ReadSeekCloser), args.Error(2)
 }
 
-func (m *MockFileRepository) List(ctx context.Context) ([]*entity.File, error) {
+func (m *MockFileRepository) Stat(ctx context.Context, filename string) (*entity.File, error) {
+	args := m.Called(ctx, filename)
+	return args.Get(0).(*entity.File), args.Error(1)
+}
+
+func (m *MockFileRepository) List(ctx context.Context, opts repository.ListOptions) (*repository.ListResult, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).(*repository.ListResult), args.Error(1)
+}
+
+func (m *MockFileRepository) Stream(ctx context.Context, filter repository.FileFilter, batchSize int, fn func([]*entity.File) error) error {
+	args := m.Called(ctx, filter, batchSize, fn)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) Rename(ctx context.Context, from, to string, overwrite bool) (*entity.File, error) {
+	args := m.Called(ctx, from, to, overwrite)
+	return args.Get(0).(*entity.File), args.Error(1)
+}
+
+func (m *MockFileRepository) Copy(ctx context.Context, from, to string, overwrite bool) (*entity.File, error) {
+	args := m.Called(ctx, from, to, overwrite)
+	return args.Get(0).(*entity.File), args.Error(1)
+}
+
+func (m *MockFileRepository) ListVersions(ctx context.Context, filename string) ([]*entity.FileVersion, error) {
+	args := m.Called(ctx, filename)
+	return args.Get(0).([]*entity.FileVersion), args.Error(1)
+}
+
+func (m *MockFileRepository) GetVersion(ctx context.Context, filename string, number int64) (*entity.FileVersion, io.ReadSeekCloser, error) {
+	args := m.Called(ctx, filename, number)
+	return args.Get(0).(*entity.FileVersion), args.Get(1).(io.ReadSeekCloser), args.Error(2)
+}
+
+func (m *MockFileRepository) RestoreVersion(ctx context.Context, filename string, number int64) (*entity.File, error) {
+	args := m.Called(ctx, filename, number)
+	return args.Get(0).(*entity.File), args.Error(1)
+}
+
+func (m *MockFileRepository) PruneVersions(ctx context.Context, filename string, keep int) (int, error) {
+	args := m.Called(ctx, filename, keep)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockFileRepository) Delete(ctx context.Context, filename string, cond repository.Precondition) (*entity.TrashItem, error) {
+	args := m.Called(ctx, filename, cond)
+	return args.Get(0).(*entity.TrashItem), args.Error(1)
+}
+
+func (m *MockFileRepository) ListTrash(ctx context.Context) ([]*entity.TrashItem, error) {
 	args := m.Called(ctx)
-	return args.Get(0).([]*entity.File), args.Error(1)
+	return args.Get(0).([]*entity.TrashItem), args.Error(1)
+}
+
+func (m *MockFileRepository) RestoreTrash(ctx context.Context, id, destination string) (*entity.File, error) {
+	args := m.Called(ctx, id, destination)
+	return args.Get(0).(*entity.File), args.Error(1)
+}
+
+func (m *MockFileRepository) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	args := m.Called(ctx, deletedBefore)
+	return args.Int(0), args.Error(1)
 }
 
 func TestFileUseCase_UploadFile(t *testing.T) {
 	mockRepo := new(MockFileRepository)
-	uc := NewFileUseCase(mockRepo)
+	uc := NewFileUseCase(mockRepo, nil)
 	ctx := context.Background()
 
 	t.Run("valid file", func(t *testing.T) {
-		data := bytes.NewReader([]byte("data"))
-		mockRepo.On("Save", ctx, mock.Anything, data).Return(nil)
+		var saved []byte
+		mockRepo.On("Save", ctx, mock.Anything, mock.Anything, repository.SaveOptions{Checksum: "abc"}).
+			Run(func(args mock.Arguments) {
+				saved, _ = io.ReadAll(args.Get(2).(io.Reader))
+			}).
+			Return(nil)
 
-		file, err := uc.UploadFile(ctx, "valid.txt", data)
+		file, err := uc.UploadFile(ctx, "valid.txt", bytes.NewReader([]byte("data")), UploadOptions{Checksum: "abc", Uploader: "alice"})
 		require.NoError(t, err)
+		require.Equal(t, []byte("data"), saved)
+		require.Nil(t, file.Image)
 		require.Equal(t, "valid.txt", file.Name)
+		require.Equal(t, "alice", file.Uploader)
+		require.Equal(t, "text/plain; charset=utf-8", file.ContentType)
 	})
 
 	t.Run("invalid filename", func(t *testing.T) {
-		_, err := uc.UploadFile(ctx, "../invalid.txt", bytes.NewReader([]byte("data")))
+		_, err := uc.UploadFile(ctx, "../invalid.txt", bytes.NewReader([]byte("data")), UploadOptions{})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "invalid filename")
 	})
@@ -55,13 +127,252 @@ func TestFileUseCase_UploadFile(t *testing.T) {
 
 func TestFileUseCase_UploadError(t *testing.T) {
 	mockRepo := new(MockFileRepository)
-	uc := NewFileUseCase(mockRepo)
+	uc := NewFileUseCase(mockRepo, nil)
 	ctx := context.Background()
 
 	// Репозиторий возвращает ошибку
-	mockRepo.On("Save", ctx, mock.Anything, mock.Anything).Return(fmt.Errorf("disk error"))
+	mockRepo.On("Save", ctx, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("disk error"))
 
-	_, err := uc.UploadFile(ctx, "test.txt", bytes.NewReader([]byte("data")))
+	_, err := uc.UploadFile(ctx, "test.txt", bytes.NewReader([]byte("data")), UploadOptions{})
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "disk error")
 }
+
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+func TestFileUseCase_DownloadRange(t *testing.T) {
+	mockRepo := new(MockFileRepository)
+	uc := NewFileUseCase(mockRepo, nil)
+	ctx := context.Background()
+
+	content := "0123456789"
+	file := &entity.File{Name: "range.txt", Size: int64(len(content))}
+
+	tests := []struct {
+		name           string
+		offset, length int64
+		expected       string
+	}{
+		{"whole file", 0, 0, "0123456789"},
+		{"middle", 2, 3, "234"},
+		{"tail", 7, 0, "789"},
+		{"length past end", 8, 100, "89"},
+		{"offset at end", 10, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo.ExpectedCalls = nil
+			mockRepo.On("Get", ctx, "range.txt").Return(file, nopSeekCloser{strings.NewReader(content)}, nil)
+
+			_, reader, err := uc.DownloadFile(ctx, "range.txt", DownloadOptions{Offset: tt.offset, Length: tt.length})
+			require.NoError(t, err)
+			defer reader.Close()
+
+			data, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(data))
+		})
+	}
+
+	t.Run("offset past end", func(t *testing.T) {
+		mockRepo.ExpectedCalls = nil
+		mockRepo.On("Get", ctx, "range.txt").Return(file, nopSeekCloser{strings.NewReader(content)}, nil)
+
+		_, _, err := uc.DownloadFile(ctx, "range.txt", DownloadOptions{Offset: 11})
+		require.ErrorIs(t, err, ErrInvalidRange)
+	})
+
+	t.Run("range of a version", func(t *testing.T) {
+		mockRepo.ExpectedCalls = nil
+		version := &entity.FileVersion{Filename: "range.txt", Number: 2, Size: 5, Checksum: "abc"}
+		mockRepo.On("GetVersion", ctx, "range.txt", int64(2)).Return(version, nopSeekCloser{strings.NewReader("old-v")}, nil)
+
+		file, reader, err := uc.DownloadFile(ctx, "range.txt", DownloadOptions{Offset: 1, Length: 3, Version: 2})
+		require.NoError(t, err)
+		defer reader.Close()
+
+		require.Equal(t, "abc", file.Checksum)
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.Equal(t, "ld-", string(data))
+	})
+}
+
+func TestFileUseCase_DeleteFile(t *testing.T) {
+	mockRepo := new(MockFileRepository)
+	uc := NewFileUseCase(mockRepo, nil)
+	ctx := context.Background()
+
+	t.Run("valid file", func(t *testing.T) {
+		mockRepo.On("Delete", ctx, "old.txt", repository.Precondition{}).Return(&entity.TrashItem{ID: "id", Filename: "old.txt"}, nil)
+
+		item, err := uc.DeleteFile(ctx, "old.txt", repository.Precondition{})
+		require.NoError(t, err)
+		require.Equal(t, "id", item.ID)
+	})
+
+	t.Run("invalid filename", func(t *testing.T) {
+		_, err := uc.DeleteFile(ctx, "../etc/passwd", repository.Precondition{})
+		require.ErrorIs(t, err, ErrInvalidFilename)
+	})
+
+	mockRepo.AssertNumberOfCalls(t, "Delete", 1)
+}
+
+func TestFileUseCase_UploadSession(t *testing.T) {
+	mockRepo := new(MockFileRepository)
+	uc := NewFileUseCase(mockRepo, repository.NewUploadSessionRepository(t.TempDir()))
+	ctx := context.Background()
+
+	session, err := uc.CreateUploadSession(ctx, "resumed.bin", 6, "")
+	require.NoError(t, err)
+
+	_, err = uc.WriteUploadSession(ctx, session.ID, 0, bytes.NewReader([]byte("abc")))
+	require.NoError(t, err)
+
+	// Файл еще не дописан
+	_, err = uc.FinalizeUploadSession(ctx, session.ID)
+	require.ErrorIs(t, err, ErrUploadIncomplete)
+
+	// Клиент переподключился и узнает, с какого места продолжать
+	session, err = uc.GetUploadSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), session.CommittedSize)
+
+	_, err = uc.WriteUploadSession(ctx, session.ID, session.CommittedSize, bytes.NewReader([]byte("def")))
+	require.NoError(t, err)
+
+	var saved []byte
+	mockRepo.On("Save", ctx, mock.Anything, mock.Anything, repository.SaveOptions{}).
+		Run(func(args mock.Arguments) {
+			saved, _ = io.ReadAll(args.Get(2).(io.Reader))
+		}).
+		Return(nil)
+
+	file, err := uc.FinalizeUploadSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, "resumed.bin", file.Name)
+	require.Equal(t, []byte("abcdef"), saved)
+
+	_, err = uc.GetUploadSession(ctx, session.ID)
+	require.True(t, os.IsNotExist(err))
+
+	// Повторное завершение не сохраняет файл второй раз
+	_, err = uc.FinalizeUploadSession(ctx, session.ID)
+	require.True(t, os.IsNotExist(err))
+	mockRepo.AssertNumberOfCalls(t, "Save", 1)
+}
+
+func TestIsValidFilename(t *testing.T) {
+	valid := []string{
+		"photo.jpg",
+		"image with spaces.jpg",
+		"изображение.jpg",
+		"project/2024/photo.jpg",
+	}
+	for _, name := range valid {
+		require.True(t, isValidFilename(name), name)
+	}
+
+	invalid := []string{
+		"",
+		"../photo.jpg",
+		"project/../../etc/passwd",
+		"/etc/passwd",
+		"project//photo.jpg",
+		"project/",
+		"~/photo.jpg",
+		`project\photo.jpg`,
+		".meta/photo.jpg.json",
+		"project/.hidden",
+		"bad\x00name",
+		strings.Repeat("a", 256),
+	}
+	for _, name := range invalid {
+		require.False(t, isValidFilename(name), name)
+	}
+}
+
+func TestFileUseCase_RenameFile(t *testing.T) {
+	mockRepo := new(MockFileRepository)
+	uc := NewFileUseCase(mockRepo, nil)
+	ctx := context.Background()
+
+	mockRepo.On("Rename", ctx, "inbox/a.jpg", "archive/2024/a.jpg", false).Return(&entity.File{Name: "archive/2024/a.jpg"}, nil)
+
+	file, err := uc.RenameFile(ctx, "inbox/a.jpg", "archive/2024/a.jpg", false)
+	require.NoError(t, err)
+	require.Equal(t, "archive/2024/a.jpg", file.Name)
+
+	_, err = uc.RenameFile(ctx, "inbox/a.jpg", "../a.jpg", false)
+	require.ErrorIs(t, err, ErrInvalidFilename)
+
+	_, err = uc.RenameFile(ctx, "../a.jpg", "a.jpg", true)
+	require.ErrorIs(t, err, ErrInvalidFilename)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileUseCase_PurgeTrash(t *testing.T) {
+	mockRepo := new(MockFileRepository)
+	uc := NewFileUseCase(mockRepo, nil)
+	ctx := context.Background()
+
+	var cutoff time.Time
+	mockRepo.On("PurgeTrash", ctx, mock.AnythingOfType("time.Time")).
+		Run(func(args mock.Arguments) { cutoff = args.Get(1).(time.Time) }).
+		Return(2, nil)
+
+	removed, err := uc.PurgeTrash(ctx, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 2, removed)
+	require.WithinDuration(t, time.Now().Add(-time.Hour), cutoff, time.Minute)
+
+	_, err = uc.RestoreFile(ctx, "id", "../escape.txt")
+	require.ErrorIs(t, err, ErrInvalidFilename)
+}
+
+func TestFileUseCase_ConditionalDownload(t *testing.T) {
+	ctx := context.Background()
+
+	updatedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	file := &entity.File{Name: "a.jpg", Size: 4, Checksum: "abc", UpdatedAt: updatedAt}
+
+	tests := []struct {
+		name     string
+		opts     DownloadOptions
+		modified bool
+	}{
+		{"no conditions", DownloadOptions{}, true},
+		{"same etag", DownloadOptions{IfNoneMatch: "abc"}, false},
+		{"any etag", DownloadOptions{IfNoneMatch: "*"}, false},
+		{"other etag", DownloadOptions{IfNoneMatch: "old"}, true},
+		{"not modified since", DownloadOptions{IfModifiedSince: updatedAt}, false},
+		{"modified since", DownloadOptions{IfModifiedSince: updatedAt.Add(-time.Second)}, true},
+		{"etag wins over date", DownloadOptions{IfNoneMatch: "old", IfModifiedSince: updatedAt}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockFileRepository)
+			uc := NewFileUseCase(mockRepo, nil)
+			mockRepo.On("Stat", ctx, "a.jpg").Return(file, nil)
+			mockRepo.On("Get", ctx, "a.jpg").Return(file, nopSeekCloser{strings.NewReader("data")}, nil)
+
+			got, reader, err := uc.DownloadFile(ctx, "a.jpg", tt.opts)
+			if tt.modified {
+				require.NoError(t, err)
+				reader.Close()
+				return
+			}
+			require.ErrorIs(t, err, ErrNotModified)
+			require.Nil(t, reader)
+			require.Equal(t, "abc", got.ETag())
+			// Неизмененный файл проверяется по метаданным, без открытия
+			mockRepo.AssertNotCalled(t, "Get", ctx, "a.jpg")
+		})
+	}
+}
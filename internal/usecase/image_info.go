@@ -0,0 +1,39 @@
+package usecase
+
+import (
+	"io"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	"github.com/keenoobi/grpc-file-manager/internal/imaging"
+)
+
+// Сколько байт с начала файла хватает для заголовка изображения и EXIF.
+// В JPEG перед размерами могут идти EXIF с миниатюрой и ICC-профиль
+const imageHeaderSize = 1 << 20
+
+// imageProbe запоминает начало загружаемых данных и, когда репозиторий
+// дочитал их до конца, записывает свойства изображения в file.Image.
+// Репозитории строят метаданные после чтения содержимого, поэтому свойства
+// сохраняются вместе с остальными метаданными без второго чтения файла
+type imageProbe struct {
+	r      io.Reader
+	file   *entity.File
+	header []byte
+}
+
+func newImageProbe(file *entity.File, r io.Reader) io.Reader {
+	return &imageProbe{r: r, file: file}
+}
+
+func (p *imageProbe) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if room := imageHeaderSize - len(p.header); room > 0 {
+		p.header = append(p.header, b[:min(n, room)]...)
+	}
+	if err == io.EOF {
+		// Не изображение или заголовок не поместился - файл сохраняется без свойств
+		p.file.Image, _ = imaging.Inspect(p.header)
+		p.header = nil
+	}
+	return n, err
+}
@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/keenoobi/grpc-file-manager/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileUseCase_ImageInfo(t *testing.T) {
+	ctx := context.Background()
+	uc := NewFileUseCase(repository.NewMemoryRepository(repository.VersioningOptions{}),
+		repository.NewUploadSessionRepository(t.TempDir()))
+
+	file, err := uc.UploadFile(ctx, "photo.png", bytes.NewReader(testImage(t, 300, 200)), UploadOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, file.Image)
+	require.Equal(t, 300, file.Image.Width)
+	require.Equal(t, 200, file.Image.Height)
+	require.Equal(t, "png", file.Image.Format)
+
+	// Свойства сохранены и отдаются в списке без чтения содержимого
+	result, err := uc.ListFiles(ctx, repository.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Files, 1)
+	require.Equal(t, file.Image, result.Files[0].Image)
+
+	// Загрузка через сессию извлекает свойства так же
+	data := testImage(t, 30, 20)
+	session, err := uc.CreateUploadSession(ctx, "resumed.png", int64(len(data)), "")
+	require.NoError(t, err)
+	_, err = uc.WriteUploadSession(ctx, session.ID, 0, bytes.NewReader(data))
+	require.NoError(t, err)
+	file, err = uc.FinalizeUploadSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.NotNil(t, file.Image)
+	require.Equal(t, 30, file.Image.Width)
+
+	file, err = uc.UploadFile(ctx, "fake.png", strings.NewReader("not an image"), UploadOptions{})
+	require.NoError(t, err)
+	require.Nil(t, file.Image)
+}
@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/keenoobi/grpc-file-manager/internal/imaging"
+	"github.com/keenoobi/grpc-file-manager/internal/repository"
+)
+
+// WithStripPolicy задает, какие метаданные удаляются из JPEG и PNG при каждой
+// загрузке. Загрузка может попросить более строгую политику, но не более мягкую
+func WithStripPolicy(policy imaging.StripPolicy) Option {
+	return func(uc *fileUseCase) {
+		uc.strip = policy
+	}
+}
+
+// metadataStripper удаляет метаданные на лету: данные проходят через pipe, и
+// репозиторий сохраняет уже очищенное содержимое, не дожидаясь конца загрузки
+type metadataStripper struct {
+	*io.PipeReader
+	done     chan struct{}
+	stripped []string
+}
+
+// newMetadataStripper запускает удаление метаданных из data. Ожидаемый хеш
+// клиента относится к исходным данным, поэтому проверяется здесь, а не в
+// репозитории. Ошибка разбора или несовпадение хеша приходят репозиторию
+// вместо конца данных, и файл не сохраняется
+func newMetadataStripper(data io.Reader, policy imaging.StripPolicy, checksum string) *metadataStripper {
+	pr, pw := io.Pipe()
+	s := &metadataStripper{PipeReader: pr, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		hash := sha256.New()
+		stripped, err := imaging.StripMetadata(pw, io.TeeReader(data, hash), policy)
+		if err == nil && checksum != "" {
+			if actual := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(checksum, actual) {
+				err = fmt.Errorf("%w: expected %s, got %s", repository.ErrChecksumMismatch, checksum, actual)
+			}
+		}
+		s.stripped = stripped
+		pw.CloseWithError(err)
+	}()
+	return s
+}
+
+// finish останавливает разбор, если репозиторий не дочитал данные, и
+// возвращает, что было удалено
+func (s *metadataStripper) finish() []string {
+	s.Close()
+	<-s.done
+	return s.stripped
+}
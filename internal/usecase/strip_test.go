@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"image"
+	"image/jpeg"
+	"io"
+	"testing"
+
+	"github.com/keenoobi/grpc-file-manager/internal/imaging"
+	"github.com/keenoobi/grpc-file-manager/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// testGeotaggedJPEG возвращает JPEG с координатами в XMP и комментарием
+func testGeotaggedJPEG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 30)), nil))
+	segment := func(marker byte, payload string) []byte {
+		b := binary.BigEndian.AppendUint16([]byte{0xFF, marker}, uint16(len(payload)+2))
+		return append(b, payload...)
+	}
+
+	data := buf.Bytes()
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment(0xE1, "http://ns.adobe.com/xap/1.0/\x00<exif:GPSLatitude>55,45N</exif:GPSLatitude>")...)
+	out = append(out, segment(0xFE, "shot on my phone")...)
+	return append(out, data[2:]...)
+}
+
+func TestFileUseCase_StripMetadata(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository(repository.VersioningOptions{})
+	uc := NewFileUseCase(repo, repository.NewUploadSessionRepository(t.TempDir()),
+		WithStripPolicy(imaging.StripLocation))
+
+	data := testGeotaggedJPEG(t)
+	sum := sha256.Sum256(data)
+	original := hex.EncodeToString(sum[:])
+
+	read := func(name string) []byte {
+		_, reader, err := repo.Get(ctx, name)
+		require.NoError(t, err)
+		defer reader.Close()
+		content, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return content
+	}
+
+	t.Run("server policy", func(t *testing.T) {
+		// Хеш клиента относится к исходным данным
+		file, err := uc.UploadFile(ctx, "location.jpg", bytes.NewReader(data), UploadOptions{Checksum: original})
+		require.NoError(t, err)
+		require.Equal(t, []string{imaging.StrippedXMP}, file.StrippedMetadata)
+		require.NotNil(t, file.Image)
+
+		stored := read("location.jpg")
+		require.NotContains(t, string(stored), "GPSLatitude")
+		require.Contains(t, string(stored), "shot on my phone")
+		require.Equal(t, int64(len(stored)), file.Size)
+		storedSum := sha256.Sum256(stored)
+		require.Equal(t, hex.EncodeToString(storedSum[:]), file.Checksum)
+	})
+
+	t.Run("request is stricter", func(t *testing.T) {
+		file, err := uc.UploadFile(ctx, "all.jpg", bytes.NewReader(data), UploadOptions{Strip: imaging.StripAll})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{imaging.StrippedXMP, imaging.StrippedComment}, file.StrippedMetadata)
+		require.NotContains(t, string(read("all.jpg")), "shot on my phone")
+	})
+
+	t.Run("request cannot weaken server policy", func(t *testing.T) {
+		file, err := uc.UploadFile(ctx, "none.jpg", bytes.NewReader(data), UploadOptions{Strip: imaging.StripNone})
+		require.NoError(t, err)
+		require.Equal(t, []string{imaging.StrippedXMP}, file.StrippedMetadata)
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		_, err := uc.UploadFile(ctx, "mismatch.jpg", bytes.NewReader(data), UploadOptions{Checksum: hex.EncodeToString(make([]byte, 32))})
+		require.ErrorIs(t, err, repository.ErrChecksumMismatch)
+		_, _, err = repo.Get(ctx, "mismatch.jpg")
+		require.Error(t, err)
+	})
+
+	t.Run("malformed image", func(t *testing.T) {
+		_, err := uc.UploadFile(ctx, "broken.jpg", bytes.NewReader(data[:len(data)/2]), UploadOptions{})
+		require.ErrorIs(t, err, imaging.ErrMalformedImage)
+		_, _, err = repo.Get(ctx, "broken.jpg")
+		require.Error(t, err)
+	})
+
+	t.Run("upload session", func(t *testing.T) {
+		session, err := uc.CreateUploadSession(ctx, "resumed.jpg", int64(len(data)), original)
+		require.NoError(t, err)
+		_, err = uc.WriteUploadSession(ctx, session.ID, 0, bytes.NewReader(data))
+		require.NoError(t, err)
+		file, err := uc.FinalizeUploadSession(ctx, session.ID)
+		require.NoError(t, err)
+		require.Equal(t, []string{imaging.StrippedXMP}, file.StrippedMetadata)
+		require.NotContains(t, string(read("resumed.jpg")), "GPSLatitude")
+	})
+
+	t.Run("other files are kept as is", func(t *testing.T) {
+		file, err := uc.UploadFile(ctx, "notes.txt", bytes.NewReader([]byte("GPSLatitude")), UploadOptions{})
+		require.NoError(t, err)
+		require.Empty(t, file.StrippedMetadata)
+		require.Equal(t, "GPSLatitude", string(read("notes.txt")))
+	})
+}
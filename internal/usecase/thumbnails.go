@@ -0,0 +1,268 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	"github.com/keenoobi/grpc-file-manager/internal/imaging"
+	"github.com/keenoobi/grpc-file-manager/internal/repository"
+)
+
+var (
+	ErrThumbnailsDisabled   = errors.New("thumbnails are disabled")
+	ErrInvalidThumbnailSize = errors.New("invalid thumbnail size")
+	ErrNotImage             = errors.New("file is not a supported image")
+)
+
+type ThumbnailOptions struct {
+	Sizes     []int // Допустимые размеры: ограничение большей стороны в пикселях
+	Workers   int   // Сколько превью создается одновременно в фоне
+	Queue     int   // Сколько загрузок может ждать создания превью
+	MaxPixels int64 // Изображения больше не декодируются, 0 - без ограничения
+}
+
+// WithThumbnails включает создание превью для загруженных изображений
+func WithThumbnails(store repository.ThumbnailRepository, opts ThumbnailOptions) Option {
+	return func(uc *fileUseCase) {
+		uc.thumbnails = newThumbnailer(uc.repo, store, opts)
+	}
+}
+
+func (uc *fileUseCase) GetThumbnail(ctx context.Context, filename string, size int) (*entity.Thumbnail, io.ReadCloser, error) {
+	if !isValidFilename(filename) {
+		return nil, nil, ErrInvalidFilename
+	}
+	if uc.thumbnails == nil {
+		return nil, nil, ErrThumbnailsDisabled
+	}
+	return uc.thumbnails.open(ctx, filename, size)
+}
+
+// Close останавливает воркеры превью
+func (uc *fileUseCase) Close() error {
+	if uc.thumbnails != nil {
+		uc.thumbnails.close()
+	}
+	return nil
+}
+
+// checksumOf возвращает хеш текущего содержимого файла, чтобы после
+// перезаписи удалить превью старого содержимого
+func (uc *fileUseCase) checksumOf(ctx context.Context, filename string) string {
+	if uc.thumbnails == nil {
+		return ""
+	}
+	file, err := uc.repo.Stat(ctx, filename)
+	if err != nil {
+		return ""
+	}
+	return file.Checksum
+}
+
+// contentChanged удаляет превью замененного содержимого previous и ставит
+// в очередь создание превью для нового
+func (uc *fileUseCase) contentChanged(ctx context.Context, previous string, file *entity.File) {
+	if uc.thumbnails == nil {
+		return
+	}
+	if previous != "" && previous != file.Checksum {
+		uc.thumbnails.remove(ctx, previous)
+	}
+	uc.thumbnails.schedule(file)
+}
+
+// thumbnailer создает превью в фоне после загрузки, а если превью еще нет
+// (очередь переполнена, сервер перезапускался) - при первом запросе
+type thumbnailer struct {
+	repo  repository.FileRepository
+	store repository.ThumbnailRepository
+	opts  ThumbnailOptions
+	jobs  chan *entity.File
+
+	// Отменяется в close, воркеры завершаются
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+}
+
+func newThumbnailer(repo repository.FileRepository, store repository.ThumbnailRepository, opts ThumbnailOptions) *thumbnailer {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &thumbnailer{
+		repo:   repo,
+		store:  store,
+		opts:   opts,
+		jobs:   make(chan *entity.File, max(opts.Queue, 0)),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for range max(opts.Workers, 1) {
+		t.workers.Add(1)
+		go t.work()
+	}
+	return t
+}
+
+func (t *thumbnailer) work() {
+	defer t.workers.Done()
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case file := <-t.jobs:
+			if err := t.generate(t.ctx, file); err != nil && t.ctx.Err() == nil {
+				slog.Warn("Thumbnail generation failed", "filename", file.Name, "error", err)
+			}
+		}
+	}
+}
+
+// close останавливает воркеры и ждет, пока они выйдут. Задачи из очереди
+// отбрасываются, эти превью создадутся по запросу
+func (t *thumbnailer) close() {
+	t.cancel()
+	t.workers.Wait()
+}
+
+// schedule ставит создание превью в очередь. Если очередь заполнена, задача
+// отбрасывается: загрузка не должна ждать, превью создастся по запросу
+func (t *thumbnailer) schedule(file *entity.File) {
+	if file.Checksum == "" || !strings.HasPrefix(file.ContentType, "image/") {
+		return
+	}
+	if t.ctx.Err() != nil {
+		return
+	}
+	select {
+	case t.jobs <- file:
+	default:
+		slog.Warn("Thumbnail queue is full", "filename", file.Name)
+	}
+}
+
+// generate создает превью всех размеров, если файл с момента загрузки
+// не успели изменить
+func (t *thumbnailer) generate(ctx context.Context, file *entity.File) error {
+	current, reader, err := t.repo.Get(ctx, file.Name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if current.Checksum != file.Checksum {
+		return nil
+	}
+	_, err = t.render(ctx, current, reader)
+	if errors.Is(err, ErrNotImage) {
+		return nil // Тип содержимого заявлен клиентом и мог не соответствовать данным
+	}
+	return err
+}
+
+func (t *thumbnailer) open(ctx context.Context, filename string, size int) (*entity.Thumbnail, io.ReadCloser, error) {
+	if !slices.Contains(t.opts.Sizes, size) {
+		return nil, nil, fmt.Errorf("%w: %d, available %v", ErrInvalidThumbnailSize, size, t.opts.Sizes)
+	}
+
+	// Готовое превью находится по хешу из метаданных, содержимое файла
+	// открывается, только если превью придется создать
+	file, err := t.repo.Stat(ctx, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	if file.Checksum != "" {
+		data, length, err := t.store.Open(ctx, file.Checksum, size)
+		if err == nil {
+			thumbnail := thumbnailOf(file, size)
+			thumbnail.Size = length
+			return thumbnail, data, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, nil, err
+		}
+	}
+
+	file, reader, err := t.repo.Get(ctx, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reader.Close()
+
+	rendered, err := t.render(ctx, file, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	data := rendered[size]
+	thumbnail := thumbnailOf(file, size)
+	thumbnail.Size = int64(len(data))
+	return thumbnail, io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func thumbnailOf(file *entity.File, size int) *entity.Thumbnail {
+	return &entity.Thumbnail{
+		Filename:    file.Name,
+		MaxSize:     size,
+		ContentType: imaging.ContentType(thumbnailFormat(file.ContentType)),
+		Checksum:    file.Checksum,
+	}
+}
+
+// render декодирует изображение один раз и создает превью всех размеров.
+// Превью сохраняются, если у файла есть хеш содержимого
+func (t *thumbnailer) render(ctx context.Context, file *entity.File, reader io.ReadSeeker) (map[int][]byte, error) {
+	img, _, err := imaging.Decode(reader, t.opts.MaxPixels)
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotImage, err)
+	}
+
+	format := thumbnailFormat(file.ContentType)
+	rendered := make(map[int][]byte, len(t.opts.Sizes))
+	for _, size := range t.opts.Sizes {
+		data, err := imaging.EncodeBytes(imaging.Fit(img, size), format, 0)
+		if err != nil {
+			return nil, err
+		}
+		if file.Checksum != "" {
+			if err := t.store.Save(ctx, file.Checksum, size, data); err != nil {
+				return nil, fmt.Errorf("save thumbnail failed: %w", err)
+			}
+		}
+		rendered[size] = data
+	}
+	return rendered, nil
+}
+
+// remove удаляет превью содержимого. Такое же содержимое может быть у
+// другого файла - его превью будет создано заново при запросе
+func (t *thumbnailer) remove(ctx context.Context, checksum string) {
+	if checksum == "" {
+		return
+	}
+	if err := t.store.Delete(ctx, checksum); err != nil {
+		slog.Warn("Failed to delete thumbnails", "checksum", checksum, "error", err)
+	}
+}
+
+// thumbnailFormat сохраняет прозрачность PNG и GIF, остальное - в JPEG
+func thumbnailFormat(contentType string) string {
+	switch contentType {
+	case "image/png", "image/gif":
+		return imaging.FormatPNG
+	default:
+		return imaging.FormatJPEG
+	}
+}
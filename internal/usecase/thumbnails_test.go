@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/keenoobi/grpc-file-manager/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func testImage(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func TestFileUseCase_Thumbnails(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewThumbnailRepository(t.TempDir())
+	repo := &countingRepository{FileRepository: repository.NewMemoryRepository(repository.VersioningOptions{})}
+	uc := NewFileUseCase(repo, nil,
+		WithThumbnails(store, ThumbnailOptions{Sizes: []int{16, 64}, Workers: 1, Queue: 10}))
+	t.Cleanup(func() { uc.Close() })
+
+	file, err := uc.UploadFile(ctx, "photo.png", bytes.NewReader(testImage(t, 200, 100)), UploadOptions{})
+	require.NoError(t, err)
+
+	// Превью создаются в фоне после загрузки
+	require.Eventually(t, func() bool {
+		reader, _, err := store.Open(ctx, file.Checksum, 64)
+		if err != nil {
+			return false
+		}
+		reader.Close()
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Готовое превью отдается без открытия самого файла
+	opened := repo.opened.Load()
+	thumbnail, reader, err := uc.GetThumbnail(ctx, "photo.png", 64)
+	require.NoError(t, err)
+	require.Equal(t, opened, repo.opened.Load())
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	require.Equal(t, "image/png", thumbnail.ContentType)
+	require.Equal(t, int64(len(data)), thumbnail.Size)
+	config, err := png.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, 64, config.Width)
+	require.Equal(t, 32, config.Height)
+
+	_, _, err = uc.GetThumbnail(ctx, "photo.png", 100)
+	require.ErrorIs(t, err, ErrInvalidThumbnailSize)
+
+	// После перезаписи превью старого содержимого удаляются
+	updated, err := uc.UploadFile(ctx, "photo.png", bytes.NewReader(testImage(t, 10, 10)), UploadOptions{})
+	require.NoError(t, err)
+	require.NotEqual(t, file.Checksum, updated.Checksum)
+	_, _, err = store.Open(ctx, file.Checksum, 64)
+	require.True(t, os.IsNotExist(err))
+
+	// Превью сохраняются в порядке Sizes, 64 - последним
+	require.Eventually(t, func() bool {
+		reader, _, err := store.Open(ctx, updated.Checksum, 64)
+		if err != nil {
+			return false
+		}
+		reader.Close()
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Превью, которого нет в хранилище, создается по запросу
+	require.NoError(t, store.Delete(ctx, updated.Checksum))
+	opened = repo.opened.Load()
+	thumbnail, reader, err = uc.GetThumbnail(ctx, "photo.png", 16)
+	require.NoError(t, err)
+	reader.Close()
+	require.Equal(t, opened+1, repo.opened.Load())
+	require.Equal(t, updated.Checksum, thumbnail.Checksum)
+	reader, _, err = store.Open(ctx, updated.Checksum, 16)
+	require.NoError(t, err)
+	reader.Close()
+
+	_, err = uc.DeleteFile(ctx, "photo.png", repository.Precondition{})
+	require.NoError(t, err)
+	_, _, err = store.Open(ctx, thumbnail.Checksum, 16)
+	require.True(t, os.IsNotExist(err))
+
+	_, err = uc.UploadFile(ctx, "notes.txt", strings.NewReader("text"), UploadOptions{})
+	require.NoError(t, err)
+	_, _, err = uc.GetThumbnail(ctx, "notes.txt", 16)
+	require.ErrorIs(t, err, ErrNotImage)
+}
+
+// blockingThumbnails задерживает сохранение превью до закрытия release
+type blockingThumbnails struct {
+	repository.ThumbnailRepository
+	started chan struct{}
+	release chan struct{}
+	saves   atomic.Int32
+}
+
+func (s *blockingThumbnails) Save(ctx context.Context, checksum string, size int, data []byte) error {
+	if s.saves.Add(1) == 1 {
+		close(s.started)
+	}
+	<-s.release
+	return s.ThumbnailRepository.Save(ctx, checksum, size, data)
+}
+
+func TestFileUseCase_ThumbnailsClose(t *testing.T) {
+	ctx := context.Background()
+	store := &blockingThumbnails{
+		ThumbnailRepository: repository.NewThumbnailRepository(t.TempDir()),
+		started:             make(chan struct{}),
+		release:             make(chan struct{}),
+	}
+	uc := NewFileUseCase(repository.NewMemoryRepository(repository.VersioningOptions{}), nil,
+		WithThumbnails(store, ThumbnailOptions{Sizes: []int{16}, Workers: 1, Queue: 10}))
+
+	_, err := uc.UploadFile(ctx, "photo.png", bytes.NewReader(testImage(t, 20, 20)), UploadOptions{})
+	require.NoError(t, err)
+	<-store.started
+
+	// Close ждет превью, которое уже создается
+	closed := make(chan struct{})
+	go func() {
+		uc.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returned before the worker finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(store.release)
+	<-closed
+
+	// После Close превью в фоне не создаются
+	_, err = uc.UploadFile(ctx, "other.png", bytes.NewReader(testImage(t, 30, 30)), UploadOptions{})
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, int32(1), store.saves.Load())
+}
+
+func TestFileUseCase_ThumbnailsDisabled(t *testing.T) {
+	uc := NewFileUseCase(new(MockFileRepository), nil)
+
+	_, _, err := uc.GetThumbnail(context.Background(), "photo.png", 128)
+	require.ErrorIs(t, err, ErrThumbnailsDisabled)
+}
@@ -0,0 +1,219 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	"github.com/keenoobi/grpc-file-manager/internal/imaging"
+	"github.com/keenoobi/grpc-file-manager/internal/repository"
+)
+
+var (
+	ErrTransformsDisabled = errors.New("image transformations are disabled")
+	ErrInvalidTransform   = errors.New("invalid image transformation")
+)
+
+type TransformOptions struct {
+	// Ограничение ширины и высоты, 0 - TransformLimits.MaxDimension или без
+	// ограничения. Изображение только уменьшается с сохранением пропорций
+	Width  int
+	Height int
+
+	// Обрезка по центру до соотношения сторон перед уменьшением, 0 - без обрезки
+	AspectWidth  int
+	AspectHeight int
+
+	// Формат результата, пустая строка - JPEG для JPEG, иначе PNG
+	Format  string
+	Quality int // Качество JPEG 1-100, 0 - imaging.DefaultJPEGQuality
+}
+
+// DefaultMaxPixels - ограничение исходного изображения для TransformImage,
+// если в TransformLimits оно не задано. Преобразования доступны любому
+// клиенту, поэтому без ограничения не работают
+const DefaultMaxPixels = 50_000_000
+
+type TransformLimits struct {
+	MaxPixels    int64 // Изображения больше не декодируются, 0 - DefaultMaxPixels
+	MaxDimension int   // Наибольшие Width и Height и стороны результата, 0 - без ограничения
+}
+
+// WithTransforms включает TransformImage. Готовые варианты сохраняются в
+// cache, nil - без кеша
+func WithTransforms(cache repository.RenditionCache, limits TransformLimits) Option {
+	if limits.MaxPixels <= 0 {
+		limits.MaxPixels = DefaultMaxPixels
+	}
+	return func(uc *fileUseCase) {
+		uc.transforms = &transformer{cache: cache, limits: limits}
+	}
+}
+
+type transformer struct {
+	cache  repository.RenditionCache
+	limits TransformLimits
+}
+
+func (uc *fileUseCase) TransformImage(ctx context.Context, filename string, opts TransformOptions) (*entity.Rendition, io.ReadCloser, error) {
+	if !isValidFilename(filename) {
+		return nil, nil, ErrInvalidFilename
+	}
+	if uc.transforms == nil {
+		return nil, nil, ErrTransformsDisabled
+	}
+	if err := uc.transforms.validate(opts); err != nil {
+		return nil, nil, err
+	}
+
+	// Готовый вариант ищется по хешу из метаданных, содержимое открывается
+	// только при промахе кеша
+	file, err := uc.repo.Stat(ctx, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, ok := uc.transforms.cached(ctx, file, opts)
+	if !ok {
+		// Файл могли перезаписать после Stat, поэтому вариант создается и
+		// кешируется по сведениям, полученным при открытии
+		var reader io.ReadSeekCloser
+		if file, reader, err = uc.repo.Get(ctx, filename); err != nil {
+			return nil, nil, err
+		}
+		defer reader.Close()
+		if data, err = uc.transforms.render(ctx, file, reader, opts); err != nil {
+			return nil, nil, err
+		}
+	}
+	rendition, err := newRendition(file, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rendition, io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (t *transformer) validate(opts TransformOptions) error {
+	if opts.Width < 0 || opts.Height < 0 {
+		return fmt.Errorf("%w: negative size %dx%d", ErrInvalidTransform, opts.Width, opts.Height)
+	}
+	if limit := t.limits.MaxDimension; limit > 0 && (opts.Width > limit || opts.Height > limit) {
+		return fmt.Errorf("%w: size %dx%d exceeds %d", ErrInvalidTransform, opts.Width, opts.Height, limit)
+	}
+	if opts.AspectWidth < 0 || opts.AspectHeight < 0 || (opts.AspectWidth == 0) != (opts.AspectHeight == 0) {
+		return fmt.Errorf("%w: aspect ratio %d:%d", ErrInvalidTransform, opts.AspectWidth, opts.AspectHeight)
+	}
+	if opts.Quality < 0 || opts.Quality > 100 {
+		return fmt.Errorf("%w: quality %d", ErrInvalidTransform, opts.Quality)
+	}
+	switch opts.Format {
+	case "", imaging.FormatJPEG, imaging.FormatPNG, imaging.FormatGIF:
+	default:
+		return fmt.Errorf("%w: cannot encode %q", ErrInvalidTransform, opts.Format)
+	}
+	return nil
+}
+
+// cached отдает вариант из кеша. Ошибки кеша не мешают ответить: вариант
+// всегда можно создать заново
+func (t *transformer) cached(ctx context.Context, file *entity.File, opts TransformOptions) ([]byte, bool) {
+	if t.cache == nil || file.Checksum == "" {
+		return nil, false
+	}
+	data, err := t.cache.Get(ctx, t.key(file.Checksum, opts))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("Rendition cache read failed", "filename", file.Name, "error", err)
+		}
+		return nil, false
+	}
+	return data, true
+}
+
+// render создает вариант и сохраняет его в кеш
+func (t *transformer) render(ctx context.Context, file *entity.File, reader io.ReadSeeker, opts TransformOptions) ([]byte, error) {
+	var key string
+	if t.cache != nil && file.Checksum != "" {
+		key = t.key(file.Checksum, opts) // До выбора формата по исходнику
+	}
+
+	img, format, err := imaging.Decode(reader, t.limits.MaxPixels)
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotImage, err)
+	}
+
+	if opts.AspectWidth > 0 {
+		img = imaging.CropToAspect(img, opts.AspectWidth, opts.AspectHeight)
+	}
+	img = imaging.ScaleDown(img, t.bound(opts.Width), t.bound(opts.Height))
+
+	if opts.Format == "" {
+		opts.Format = imaging.FormatPNG
+		if format == imaging.FormatJPEG {
+			opts.Format = imaging.FormatJPEG
+		}
+	}
+	data, err := imaging.EncodeBytes(img, opts.Format, opts.Quality)
+	if err != nil {
+		return nil, err
+	}
+
+	if key != "" {
+		if err := t.cache.Put(ctx, key, data); err != nil {
+			slog.Warn("Rendition cache write failed", "filename", file.Name, "error", err)
+		}
+	}
+	return data, nil
+}
+
+// bound возвращает наибольшую сторону результата: без MaxDimension запрошенный
+// размер 0 не ограничивал бы результат, и большое исходное изображение
+// уходило бы клиенту целиком
+func (t *transformer) bound(size int) int {
+	if limit := t.limits.MaxDimension; limit > 0 && (size == 0 || size > limit) {
+		return limit
+	}
+	return size
+}
+
+// key - ключ варианта в кеше. Размеры учитываются после bound: запросы с
+// нулевым и с наибольшим размером дают один и тот же вариант
+func (t *transformer) key(checksum string, opts TransformOptions) string {
+	opts.Width, opts.Height = t.bound(opts.Width), t.bound(opts.Height)
+	return renditionKey(checksum, opts)
+}
+
+// renditionKey однозначно определяет вариант: содержимое исходного файла и
+// параметры преобразования
+func renditionKey(checksum string, opts TransformOptions) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%d|%d|%d|%d|%s|%d", checksum,
+		opts.Width, opts.Height, opts.AspectWidth, opts.AspectHeight, opts.Format, opts.Quality))
+	return hex.EncodeToString(sum[:])
+}
+
+// newRendition берет размеры и формат из заголовка результата, поэтому
+// одинаково работает для созданных и взятых из кеша вариантов
+func newRendition(file *entity.File, data []byte) (*entity.Rendition, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode rendition failed: %w", err)
+	}
+	return &entity.Rendition{
+		Filename:    file.Name,
+		Width:       config.Width,
+		Height:      config.Height,
+		ContentType: imaging.ContentType(format),
+		Size:        int64(len(data)),
+		Checksum:    file.Checksum,
+	}, nil
+}
@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"image/jpeg"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/keenoobi/grpc-file-manager/internal/entity"
+	"github.com/keenoobi/grpc-file-manager/internal/imaging"
+	"github.com/keenoobi/grpc-file-manager/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// countingCache считает попадания, чтобы проверить, что вариант взят из кеша
+type countingCache struct {
+	repository.RenditionCache
+	hits int
+}
+
+func (c *countingCache) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.RenditionCache.Get(ctx, key)
+	if err == nil {
+		c.hits++
+	}
+	return data, err
+}
+
+// countingRepository считает открытия содержимого
+type countingRepository struct {
+	repository.FileRepository
+	opened atomic.Int32
+}
+
+func (r *countingRepository) Get(ctx context.Context, filename string) (*entity.File, io.ReadSeekCloser, error) {
+	r.opened.Add(1)
+	return r.FileRepository.Get(ctx, filename)
+}
+
+func TestFileUseCase_TransformImage(t *testing.T) {
+	ctx := context.Background()
+	store, err := repository.NewRenditionCache(t.TempDir(), 1<<20)
+	require.NoError(t, err)
+	cache := &countingCache{RenditionCache: store}
+	repo := &countingRepository{FileRepository: repository.NewMemoryRepository(repository.VersioningOptions{})}
+	uc := NewFileUseCase(repo, nil, WithTransforms(cache, TransformLimits{MaxDimension: 1000}))
+
+	_, err = uc.UploadFile(ctx, "photo.png", bytes.NewReader(testImage(t, 400, 200)), UploadOptions{})
+	require.NoError(t, err)
+
+	transform := func(opts TransformOptions) (*imageResult, error) {
+		rendition, reader, err := uc.TransformImage(ctx, "photo.png", opts)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(data)), rendition.Size)
+		return &imageResult{rendition.Width, rendition.Height, rendition.ContentType, data}, nil
+	}
+
+	// Квадрат из центра, уменьшенный до 100 пикселей, в JPEG
+	result, err := transform(TransformOptions{Width: 100, AspectWidth: 1, AspectHeight: 1, Format: imaging.FormatJPEG, Quality: 70})
+	require.NoError(t, err)
+	require.Equal(t, 100, result.width)
+	require.Equal(t, 100, result.height)
+	require.Equal(t, "image/jpeg", result.contentType)
+	_, err = jpeg.Decode(bytes.NewReader(result.data))
+	require.NoError(t, err)
+	require.Equal(t, 0, cache.hits)
+	require.Equal(t, int32(1), repo.opened.Load())
+
+	// Из кеша вариант отдается без открытия исходного файла
+	cached, err := transform(TransformOptions{Width: 100, AspectWidth: 1, AspectHeight: 1, Format: imaging.FormatJPEG, Quality: 70})
+	require.NoError(t, err)
+	require.Equal(t, result, cached)
+	require.Equal(t, 1, cache.hits)
+	require.Equal(t, int32(1), repo.opened.Load())
+
+	// Без формата PNG остается PNG, маленькие изображения не увеличиваются
+	result, err = transform(TransformOptions{Height: 500})
+	require.NoError(t, err)
+	require.Equal(t, "image/png", result.contentType)
+	require.Equal(t, 400, result.width)
+	require.Equal(t, 200, result.height)
+	_, err = transform(TransformOptions{Height: 500})
+	require.NoError(t, err)
+	require.Equal(t, 2, cache.hits)
+
+	// После перезаписи вариант создается заново по новому содержимому
+	_, err = uc.UploadFile(ctx, "photo.png", bytes.NewReader(testImage(t, 200, 400)), UploadOptions{})
+	require.NoError(t, err)
+	_, err = transform(TransformOptions{Width: 100, AspectWidth: 1, AspectHeight: 1, Format: imaging.FormatJPEG, Quality: 70})
+	require.NoError(t, err)
+	require.Equal(t, 2, cache.hits)
+
+	invalid := []TransformOptions{
+		{Width: 2000},
+		{AspectWidth: 16},
+		{Quality: 101},
+		{Format: imaging.FormatWebP},
+	}
+	for _, opts := range invalid {
+		_, err = transform(opts)
+		require.ErrorIs(t, err, ErrInvalidTransform, "%+v", opts)
+	}
+
+	_, err = uc.UploadFile(ctx, "notes.txt", bytes.NewReader([]byte("text")), UploadOptions{})
+	require.NoError(t, err)
+	_, _, err = uc.TransformImage(ctx, "notes.txt", TransformOptions{Width: 100})
+	require.ErrorIs(t, err, ErrNotImage)
+}
+
+func TestFileUseCase_TransformImageLimits(t *testing.T) {
+	ctx := context.Background()
+	uc := NewFileUseCase(repository.NewMemoryRepository(repository.VersioningOptions{}), nil,
+		WithTransforms(nil, TransformLimits{MaxPixels: 100 * 100}))
+
+	_, err := uc.UploadFile(ctx, "bomb.png", bytes.NewReader(testImage(t, 101, 100)), UploadOptions{})
+	require.NoError(t, err)
+	_, _, err = uc.TransformImage(ctx, "bomb.png", TransformOptions{Width: 10})
+	require.ErrorIs(t, err, imaging.ErrTooManyPixels)
+
+	disabled := NewFileUseCase(new(MockFileRepository), nil)
+	_, _, err = disabled.TransformImage(ctx, "bomb.png", TransformOptions{})
+	require.ErrorIs(t, err, ErrTransformsDisabled)
+
+	// Без явного ограничения действует ограничение по умолчанию
+	unlimited := NewFileUseCase(repository.NewMemoryRepository(repository.VersioningOptions{}), nil,
+		WithTransforms(nil, TransformLimits{}))
+	require.Equal(t, int64(DefaultMaxPixels), unlimited.(*fileUseCase).transforms.limits.MaxPixels)
+
+	// Незаданный размер не снимает MaxDimension: результат не больше ограничения
+	bounded := NewFileUseCase(repository.NewMemoryRepository(repository.VersioningOptions{}), nil,
+		WithTransforms(nil, TransformLimits{MaxDimension: 50}))
+	_, err = bounded.UploadFile(ctx, "large.png", bytes.NewReader(testImage(t, 200, 100)), UploadOptions{})
+	require.NoError(t, err)
+	for _, opts := range []TransformOptions{{}, {Height: 40}, {AspectWidth: 1, AspectHeight: 1}} {
+		rendition, reader, err := bounded.TransformImage(ctx, "large.png", opts)
+		require.NoError(t, err)
+		reader.Close()
+		require.LessOrEqual(t, rendition.Width, 50, "%+v", opts)
+		require.LessOrEqual(t, rendition.Height, 50, "%+v", opts)
+	}
+	rendition, reader, err := bounded.TransformImage(ctx, "large.png", TransformOptions{Width: 0})
+	require.NoError(t, err)
+	reader.Close()
+	require.Equal(t, 50, rendition.Width)
+	require.Equal(t, 25, rendition.Height)
+}
+
+type imageResult struct {
+	width, height int
+	contentType   string
+	data          []byte
+}
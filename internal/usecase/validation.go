@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/keenoobi/grpc-file-manager/internal/imaging"
+)
+
+// Сколько байт с начала файла смотрит определение типа по сигнатуре
+const sniffSize = 512
+
+var ErrContentNotAllowed = errors.New("content type not allowed")
+
+// ContentPolicy - проверка содержимого загружаемых файлов. Тип определяется
+// по сигнатуре в начале данных, а не по имени и не по заявленному клиентом
+type ContentPolicy struct {
+	// MIME-типы вида "image/png" или "image/*". Пусто - разрешены любые
+	AllowedTypes []string
+	// Проверять, что заголовок изображения разбирается. Заголовок должен
+	// поместиться в первый мегабайт файла
+	VerifyImages bool
+}
+
+// WithContentPolicy включает проверку содержимого при загрузке
+func WithContentPolicy(policy ContentPolicy) Option {
+	return func(uc *fileUseCase) {
+		uc.content = policy
+	}
+}
+
+func (p ContentPolicy) enabled() bool {
+	return len(p.AllowedTypes) > 0 || p.VerifyImages
+}
+
+func (p ContentPolicy) allows(contentType string) bool {
+	if len(p.AllowedTypes) == 0 {
+		return true
+	}
+	major, _, _ := strings.Cut(contentType, "/")
+	for _, allowed := range p.AllowedTypes {
+		if strings.EqualFold(allowed, contentType) || strings.EqualFold(allowed, major+"/*") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkContent читает начало data и проверяет его по политике до того, как
+// репозиторий начнет запись. Возвращает reader с полными данными
+func (uc *fileUseCase) checkContent(data io.Reader) (io.Reader, error) {
+	if !uc.content.enabled() {
+		return data, nil
+	}
+
+	size := sniffSize
+	if uc.content.VerifyImages {
+		size = imageHeaderSize
+	}
+	header := make([]byte, size)
+	n, err := io.ReadFull(data, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	header = header[:n]
+
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(header))
+	if err != nil {
+		contentType = "application/octet-stream"
+	}
+	if !uc.content.allows(contentType) {
+		return nil, fmt.Errorf("%w: %s", ErrContentNotAllowed, contentType)
+	}
+	if uc.content.VerifyImages && strings.HasPrefix(contentType, "image/") {
+		// Форматы без декодера (BMP, ICO) проверяются только по сигнатуре
+		if _, err := imaging.Inspect(header); err != nil && !errors.Is(err, imaging.ErrUnsupportedFormat) {
+			return nil, fmt.Errorf("%w: %s header: %v", imaging.ErrMalformedImage, contentType, err)
+		}
+	}
+	return io.MultiReader(bytes.NewReader(header), data), nil
+}
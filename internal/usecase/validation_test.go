@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/keenoobi/grpc-file-manager/internal/imaging"
+	"github.com/keenoobi/grpc-file-manager/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileUseCase_ContentPolicy(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository(repository.VersioningOptions{})
+	uc := NewFileUseCase(repo, repository.NewUploadSessionRepository(t.TempDir()),
+		WithContentPolicy(ContentPolicy{AllowedTypes: []string{"image/jpeg", "image/png"}, VerifyImages: true}))
+
+	png := testImage(t, 30, 20)
+	exists := func(name string) bool {
+		_, reader, err := repo.Get(ctx, name)
+		if err == nil {
+			reader.Close()
+		}
+		return err == nil
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		file, err := uc.UploadFile(ctx, "photo.png", bytes.NewReader(png), UploadOptions{})
+		require.NoError(t, err)
+		require.Equal(t, int64(len(png)), file.Size)
+		require.NotNil(t, file.Image)
+	})
+
+	t.Run("name does not matter", func(t *testing.T) {
+		// Исполняемый файл ELF под видом фотографии
+		_, err := uc.UploadFile(ctx, "cat.jpg", bytes.NewReader([]byte("\x7fELF\x02\x01\x01\x00")), UploadOptions{})
+		require.ErrorIs(t, err, ErrContentNotAllowed)
+		require.False(t, exists("cat.jpg"))
+
+		_, err = uc.UploadFile(ctx, "notes.txt", bytes.NewReader(nil), UploadOptions{})
+		require.ErrorIs(t, err, ErrContentNotAllowed)
+	})
+
+	t.Run("malformed header", func(t *testing.T) {
+		// Сигнатура PNG без заголовка IHDR
+		_, err := uc.UploadFile(ctx, "broken.png", bytes.NewReader(png[:12]), UploadOptions{})
+		require.ErrorIs(t, err, imaging.ErrMalformedImage)
+		require.False(t, exists("broken.png"))
+	})
+
+	t.Run("upload session", func(t *testing.T) {
+		data := []byte("GIF89a")
+		session, err := uc.CreateUploadSession(ctx, "anim.gif", int64(len(data)), "")
+		require.NoError(t, err)
+		_, err = uc.WriteUploadSession(ctx, session.ID, 0, bytes.NewReader(data))
+		require.NoError(t, err)
+		_, err = uc.FinalizeUploadSession(ctx, session.ID)
+		require.ErrorIs(t, err, ErrContentNotAllowed)
+		require.False(t, exists("anim.gif"))
+	})
+}
+
+func TestContentPolicy_Allows(t *testing.T) {
+	policy := ContentPolicy{AllowedTypes: []string{"image/*", "application/pdf"}}
+	require.True(t, policy.allows("image/webp"))
+	require.True(t, policy.allows("application/pdf"))
+	require.False(t, policy.allows("application/zip"))
+	require.False(t, policy.allows("text/plain"))
+	require.True(t, ContentPolicy{}.allows("application/octet-stream"))
+}